@@ -15,6 +15,7 @@ import (
 	"product-recommendations-go/internal/config"
 	"product-recommendations-go/internal/container"
 	"product-recommendations-go/internal/delivery/http/middleware"
+	"product-recommendations-go/internal/models"
 )
 
 func main() {
@@ -27,9 +28,45 @@ func main() {
 	// Маршрути для аутентифікації (публічні)
 	r.HandleFunc("/api/v1/auth/register", c.AuthHandler.Register).Methods("POST")
 	r.HandleFunc("/api/v1/auth/login", c.AuthHandler.Login).Methods("POST")
-
-	// Middleware для перевірки JWT токена
-	authMiddleware := middleware.NewAuthMiddleware(c.AuthService)
+	r.HandleFunc("/api/v1/auth/login/2fa", c.AuthHandler.LoginTwoFactor).Methods("POST")
+	r.HandleFunc("/api/v1/auth/refresh", c.AuthHandler.Refresh).Methods("POST")
+	r.HandleFunc("/api/v1/auth/verify-email", c.AuthHandler.VerifyEmail).Methods("POST")
+	r.HandleFunc("/api/v1/auth/forgot-password", c.AuthHandler.ForgotPassword).Methods("POST")
+	r.HandleFunc("/api/v1/auth/reset-password", c.AuthHandler.ResetPassword).Methods("POST")
+	r.HandleFunc("/api/v1/auth/confirm-email-change", c.AuthHandler.ConfirmEmailChange).Methods("POST")
+	r.HandleFunc("/api/v1/auth/oidc/providers", c.OIDCHandler.GetProviders).Methods("GET")
+	r.HandleFunc("/api/v1/auth/oidc/{provider}/login", c.OIDCHandler.Login).Methods("GET")
+	r.HandleFunc("/api/v1/auth/oidc/{provider}/callback", c.OIDCHandler.Callback).Methods("GET")
+
+	// Відкриті ключі перевірки токенів доступу для інших сервісів
+	r.HandleFunc("/.well-known/jwks.json", c.JWKSHandler.GetJWKS).Methods("GET")
+
+	// Middleware для перевірки JWT токена та API ключів
+	authMiddleware := middleware.NewAuthMiddleware(c.AuthService, c.APIKeyService)
+
+	// Маршрути, доступні також за API ключами з відповідною областю доступу
+	recommendations := r.PathPrefix("/api/v1/recommendations").Subrouter()
+	recommendations.Use(authMiddleware.WithAPIKey(models.ScopeRecommendationsRead))
+	recommendations.HandleFunc("", c.RecommendationHandler.GetRecommendations).Methods("GET")
+	recommendations.HandleFunc("/similar/{product_id}", c.RecommendationHandler.GetSimilarProducts).Methods("GET")
+
+	// Адміністративний маршрут вимагає дозволу ролі; сесії користувачів - ще й другого фактора
+	batch := r.PathPrefix("/api/v1/admin/recommendations").Subrouter()
+	batch.Use(authMiddleware.WithAPIKey(models.ScopeRecommendationsBatch), middleware.RequireTwoFactor,
+		middleware.RequirePermission(models.PermissionRecommendationsBatch))
+	batch.HandleFunc("/batch", c.RecommendationHandler.GetBatchRecommendations).Methods("POST")
+
+	// Кошик доступний гостям (за токеном кошика) і користувачам; кошик гостя
+	// переноситься до кошика користувача під час першого запиту після входу
+	cart := r.PathPrefix("/api/v1/cart").Subrouter()
+	cart.Use(authMiddleware.Optional)
+	cart.HandleFunc("", c.CartHandler.Get).Methods("GET")
+	cart.HandleFunc("/items", c.CartHandler.AddItem).Methods("POST")
+	cart.HandleFunc("/items/{product_id}", c.CartHandler.UpdateItem).Methods("PATCH")
+	cart.HandleFunc("/items/{product_id}", c.CartHandler.RemoveItem).Methods("DELETE")
+	cart.HandleFunc("/refresh", c.CartHandler.RefreshPrices).Methods("POST")
+	cart.HandleFunc("/checkout", c.CartHandler.Checkout).Methods("POST")
+	cart.HandleFunc("/recommendations", c.CartHandler.GetRecommendations).Methods("GET")
 
 	// Захищені маршрути (потрібна аутентифікація)
 	api := r.PathPrefix("/api/v1").Subrouter()
@@ -37,6 +74,13 @@ func main() {
 
 	// Маршрут для виходу з системи
 	api.HandleFunc("/auth/logout", c.AuthHandler.Logout).Methods("POST")
+	api.HandleFunc("/auth/verify-email/resend", c.AuthHandler.ResendVerification).Methods("POST")
+
+	// Керування двофакторною автентифікацією
+	api.HandleFunc("/auth/2fa/setup", c.AuthHandler.SetupTwoFactor).Methods("POST")
+	api.HandleFunc("/auth/2fa/enable", c.AuthHandler.EnableTwoFactor).Methods("POST")
+	api.HandleFunc("/auth/2fa/disable", c.AuthHandler.DisableTwoFactor).Methods("POST")
+	api.HandleFunc("/auth/2fa/recovery-codes", c.AuthHandler.RegenerateRecoveryCodes).Methods("POST")
 
 	// Маршрути для товарів
 	api.HandleFunc("/products", c.ProductHandler.GetAll).Methods("GET")
@@ -51,9 +95,40 @@ func main() {
 	api.HandleFunc("/orders", c.OrderHandler.CreateOrder).Methods("POST")
 	api.HandleFunc("/orders", c.OrderHandler.GetUserOrders).Methods("GET")
 	api.HandleFunc("/orders/{id}", c.OrderHandler.GetOrderByID).Methods("GET")
-
-	// Маршрути для рекомендацій
-	api.HandleFunc("/recommendations", c.RecommendationHandler.GetRecommendations).Methods("GET")
+	api.HandleFunc("/orders/{id}/cancel", c.OrderHandler.CancelOrder).Methods("POST")
+
+	// Профіль та обліковий запис поточного користувача
+	api.HandleFunc("/me", c.UserHandler.GetMe).Methods("GET")
+	api.HandleFunc("/me", c.UserHandler.UpdateMe).Methods("PATCH")
+	api.HandleFunc("/me", c.UserHandler.DeleteMe).Methods("DELETE")
+	api.HandleFunc("/me/password", c.UserHandler.ChangePassword).Methods("POST")
+	api.HandleFunc("/me/email", c.UserHandler.ChangeEmail).Methods("POST")
+	api.HandleFunc("/me/export", c.DataExportHandler.Export).Methods("GET")
+
+	// Керування API ключами (лише із сесії користувача, не за ключем)
+	api.HandleFunc("/api-keys", c.APIKeyHandler.Create).Methods("POST")
+	api.HandleFunc("/api-keys", c.APIKeyHandler.List).Methods("GET")
+	api.HandleFunc("/api-keys/{id}", c.APIKeyHandler.Revoke).Methods("DELETE")
+
+	// Адміністративні маршрути: кожен маршрут вимагає окремого дозволу ролі користувача
+	// та сесії, відкритої з підтвердженням другого фактора
+	admin := api.PathPrefix("/admin").Subrouter()
+	admin.Use(middleware.RequireTwoFactor)
+
+	// Керування каталогом товарів
+	catalog := admin.PathPrefix("/products").Subrouter()
+	catalog.Use(middleware.RequirePermission(models.PermissionProductsManage))
+	catalog.HandleFunc("", c.ProductHandler.Create).Methods("POST")
+	catalog.HandleFunc("/low-stock", c.ProductHandler.GetLowStock).Methods("GET")
+	catalog.HandleFunc("/{id}", c.ProductHandler.Update).Methods("PUT")
+	catalog.HandleFunc("/{id}", c.ProductHandler.Delete).Methods("DELETE")
+	catalog.HandleFunc("/{id}/restore", c.ProductHandler.Restore).Methods("POST")
+	catalog.HandleFunc("/{id}/stock", c.ProductHandler.AdjustStock).Methods("PATCH")
+
+	// Керування статусами замовлень
+	orders := admin.PathPrefix("/orders").Subrouter()
+	orders.Use(middleware.RequirePermission(models.PermissionOrdersManage))
+	orders.HandleFunc("/{id}/status", c.OrderHandler.UpdateStatus).Methods("POST")
 
 	// Перевірка стану сервісу (без аутентифікації)
 	r.HandleFunc("/api/v1/health", func(w http.ResponseWriter, _ *http.Request) {
@@ -75,6 +150,14 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Фонове видалення прострочених записів відкликаних і refresh токенів
+	cleanupCtx, stopCleanup := context.WithCancel(context.Background())
+	defer stopCleanup()
+	go runTokenCleanup(cleanupCtx, config.GetEnvDuration("TOKEN_CLEANUP_INTERVAL", time.Hour), c.AuthService, c.OIDCService, c.DataExportService, c.CartService)
+
+	// Фонове формування архівів з даними користувачів
+	go c.DataExportService.Run(cleanupCtx)
+
 	// Запуск сервера в окремій горутині
 	go func() {
 		log.Printf("Server starting on port %s", port)
@@ -96,6 +179,7 @@ func main() {
 
 	// Завершення роботи сервера
 	log.Println("Shutting down server...")
+	stopCleanup()
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatalf("Server shutdown failed: %v", err)
 	}
@@ -106,3 +190,27 @@ func main() {
 	log.Println("Server stopped gracefully")
 
 }
+
+// cleaner сервіс, що зберігає записи з обмеженим терміном дії
+type cleaner interface {
+	Cleanup(ctx context.Context) error
+}
+
+// runTokenCleanup періодично видаляє прострочені записи токенів, доки ctx не скасовано
+func runTokenCleanup(ctx context.Context, interval time.Duration, cleaners ...cleaner) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, c := range cleaners {
+				if err := c.Cleanup(ctx); err != nil {
+					log.Printf("Token cleanup failed: %v", err)
+				}
+			}
+		}
+	}
+}
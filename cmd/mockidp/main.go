@@ -0,0 +1,284 @@
+// Package main локальний постачальник ідентичності OpenID Connect для розробки та
+// перевірки входу через OIDC без зовнішнього сервісу.
+//
+// Використання:
+//
+//	mockidp -addr :9000 -issuer http://localhost:9000 -client-id api -client-secret secret \
+//		-redirect-url http://localhost:8080/api/v1/auth/oidc/mock/callback -email user@example.com
+//
+// Сторінка входу не показується: /authorize одразу схвалює вхід користувача -email
+// (або адреси з параметра login_hint) і повертає код на redirect_uri. Ключ підпису
+// генерується під час запуску. Не використовуйте mockidp у production.
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// codeTTL термін дії коду авторизації
+const codeTTL = time.Minute
+
+// authorization виданий код авторизації та дані, потрібні для його обміну
+type authorization struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+	email       string
+	expiresAt   time.Time
+}
+
+type server struct {
+	issuer        string
+	clientID      string
+	clientSecret  string
+	redirectURL   string
+	emailVerified bool
+	defaultEmail  string
+
+	key *rsa.PrivateKey
+	kid string
+
+	mu    sync.Mutex
+	codes map[string]*authorization
+}
+
+func main() {
+	addr := flag.String("addr", ":9000", "адреса, яку слухає сервер")
+	issuer := flag.String("issuer", "http://localhost:9000", "ідентифікатор постачальника (issuer)")
+	clientID := flag.String("client-id", "api", "ідентифікатор клієнта")
+	clientSecret := flag.String("client-secret", "secret", "секрет клієнта (порожній - публічний клієнт)")
+	redirectURL := flag.String("redirect-url", "", "дозволена адреса зворотного виклику (порожня - будь-яка)")
+	email := flag.String("email", "user@example.com", "адреса користувача, від імені якого схвалюється вхід")
+	emailVerified := flag.Bool("email-verified", true, "значення claim email_verified")
+	flag.Parse()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatalf("Failed to generate signing key: %v", err)
+	}
+
+	s := &server{
+		issuer:        strings.TrimRight(*issuer, "/"),
+		clientID:      *clientID,
+		clientSecret:  *clientSecret,
+		redirectURL:   *redirectURL,
+		emailVerified: *emailVerified,
+		defaultEmail:  *email,
+		key:           key,
+		kid:           randomString(8),
+		codes:         make(map[string]*authorization),
+	}
+
+	log.Printf("Mock identity provider %s listening on %s", s.issuer, *addr)
+	log.Fatal(http.ListenAndServe(*addr, s.routes()))
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("GET /authorize", s.authorize)
+	mux.HandleFunc("POST /token", s.token)
+	mux.HandleFunc("GET /jwks", s.jwks)
+	return mux
+}
+
+func (s *server) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.issuer,
+		"authorization_endpoint":                s.issuer + "/authorize",
+		"token_endpoint":                        s.issuer + "/token",
+		"jwks_uri":                              s.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+	})
+}
+
+// authorize одразу схвалює вхід і перенаправляє на redirect_uri з кодом
+func (s *server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	redirectURI := q.Get("redirect_uri")
+	if q.Get("client_id") != s.clientID {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+	if redirectURI == "" || (s.redirectURL != "" && redirectURI != s.redirectURL) {
+		http.Error(w, "redirect_uri is not allowed", http.StatusBadRequest)
+		return
+	}
+
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	params := target.Query()
+	params.Set("state", q.Get("state"))
+
+	switch {
+	case q.Get("response_type") != "code":
+		params.Set("error", "unsupported_response_type")
+	case q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256":
+		params.Set("error", "invalid_request")
+		params.Set("error_description", "PKCE with S256 is required")
+	default:
+		email := s.defaultEmail
+		if hint := q.Get("login_hint"); hint != "" {
+			email = hint
+		}
+
+		code := randomString(32)
+		s.mu.Lock()
+		s.codes[code] = &authorization{
+			redirectURI: redirectURI,
+			challenge:   q.Get("code_challenge"),
+			nonce:       q.Get("nonce"),
+			email:       email,
+			expiresAt:   time.Now().Add(codeTTL),
+		}
+		s.mu.Unlock()
+
+		params.Set("code", code)
+		log.Printf("Authorized %s", email)
+	}
+
+	target.RawQuery = params.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// token обмінює код авторизації на ID токен
+func (s *server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+	if !s.authenticateClient(r) {
+		tokenError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	// Код видаляється одразу: кожен код обмінюється лише один раз
+	code := r.PostForm.Get("code")
+	s.mu.Lock()
+	auth, ok := s.codes[code]
+	delete(s.codes, code)
+	s.mu.Unlock()
+
+	if !ok || time.Now().After(auth.expiresAt) || auth.redirectURI != r.PostForm.Get("redirect_uri") {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != auth.challenge {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            s.issuer,
+		"sub":            subject(auth.email),
+		"aud":            s.clientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          auth.nonce,
+		"email":          auth.email,
+		"email_verified": s.emailVerified,
+		"name":           strings.SplitN(auth.email, "@", 2)[0],
+	})
+	token.Header["kid"] = s.kid
+
+	idToken, err := token.SignedString(s.key)
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(32),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (s *server) jwks(w http.ResponseWriter, _ *http.Request) {
+	e := big.NewInt(int64(s.key.E)).Bytes()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": s.kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(e),
+		}},
+	})
+}
+
+// authenticateClient перевіряє облікові дані клієнта (client_secret_basic або client_secret_post)
+func (s *server) authenticateClient(r *http.Request) bool {
+	id, secret, ok := r.BasicAuth()
+	if ok {
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	if id != s.clientID {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(s.clientSecret)) == 1
+}
+
+// subject стабільний ідентифікатор користувача, похідний від адреси
+func subject(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(email)))
+	return hex.EncodeToString(sum[:16])
+}
+
+func randomString(size int) string {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatalf("Failed to generate random string: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func tokenError(w http.ResponseWriter, status int, code string) {
+	writeJSON(w, status, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Error JSON: %v", err)
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"product-recommendations-go/internal/delivery/http/handlers"
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/oidc"
+	"product-recommendations-go/internal/repository"
+	"product-recommendations-go/internal/service"
+)
+
+// memoryUsers користувачі в пам'яті; решта методів UserRepository у тесті не викликається
+type memoryUsers struct {
+	repository.UserRepository
+
+	mu    sync.Mutex
+	users []*models.User
+}
+
+func (r *memoryUsers) Create(_ context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user.ID = uint(len(r.users) + 1)
+	r.users = append(r.users, user)
+	return nil
+}
+
+func (r *memoryUsers) GetByID(_ context.Context, id uint) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryUsers) GetByEmail(_ context.Context, email string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+type memoryIdentities struct {
+	repository.UserIdentityRepository
+
+	mu         sync.Mutex
+	identities []*models.UserIdentity
+}
+
+func (r *memoryIdentities) Create(_ context.Context, identity *models.UserIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.identities = append(r.identities, identity)
+	return nil
+}
+
+func (r *memoryIdentities) Get(_ context.Context, provider, subject string) (*models.UserIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryIdentities) Update(context.Context, *models.UserIdentity) error { return nil }
+
+type memoryStates struct {
+	mu     sync.Mutex
+	states map[string]*models.OIDCLoginState
+}
+
+func (r *memoryStates) Create(_ context.Context, state *models.OIDCLoginState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states[state.StateHash] = state
+	return nil
+}
+
+func (r *memoryStates) Consume(_ context.Context, stateHash string) (*models.OIDCLoginState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state := r.states[stateHash]
+	delete(r.states, stateHash)
+	return state, nil
+}
+
+func (r *memoryStates) DeleteExpired(context.Context, time.Time) (int64, error) { return 0, nil }
+
+// tokenIssuer видає замість справжніх токенів ID користувача
+type tokenIssuer struct {
+	service.AuthService
+}
+
+func (tokenIssuer) CompleteExternalLogin(_ context.Context, userID uint) (*service.LoginResult, error) {
+	return &service.LoginResult{Tokens: &service.TokenPair{
+		AccessToken: fmt.Sprintf("user-%d", userID),
+		ExpiresIn:   time.Minute,
+	}}, nil
+}
+
+// startOIDCFlow запускає mockidp та API з постачальником "mock"
+func startOIDCFlow(t *testing.T) (api *httptest.Server) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := httptest.NewUnstartedServer(nil)
+	s := &server{
+		issuer:        "http://" + idp.Listener.Addr().String(),
+		clientID:      "api",
+		clientSecret:  "secret",
+		emailVerified: true,
+		defaultEmail:  "user@example.com",
+		key:           key,
+		kid:           randomString(8),
+		codes:         make(map[string]*authorization),
+	}
+	idp.Config.Handler = s.routes()
+	idp.Start()
+	t.Cleanup(idp.Close)
+
+	router := mux.NewRouter()
+	api = httptest.NewServer(router)
+	t.Cleanup(api.Close)
+
+	provider := oidc.NewProvider(oidc.Config{
+		Name:         "mock",
+		Issuer:       s.issuer,
+		ClientID:     s.clientID,
+		ClientSecret: s.clientSecret,
+		RedirectURL:  api.URL + "/api/v1/auth/oidc/mock/callback",
+	})
+	oidcService := service.NewOIDCService([]*oidc.Provider{provider}, tokenIssuer{}, &memoryUsers{}, &memoryIdentities{},
+		&memoryStates{states: make(map[string]*models.OIDCLoginState)}, 10*time.Minute)
+	handler := handlers.NewOIDCHandler(oidcService)
+	router.HandleFunc("/api/v1/auth/oidc/{provider}/login", handler.Login).Methods("GET")
+	router.HandleFunc("/api/v1/auth/oidc/{provider}/callback", handler.Callback).Methods("GET")
+	return api
+}
+
+// newBrowser повертає клієнт з власними cookie, що не виконує перенаправлення сам
+func newBrowser(t *testing.T) *http.Client {
+	t.Helper()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{
+		Jar: jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// callbackURL починає вхід у браузері та повертає адресу зворотного виклику,
+// на яку mockidp перенаправляє після схвалення входу
+func callbackURL(t *testing.T, browser *http.Client, api *httptest.Server, loginHint string) string {
+	t.Helper()
+
+	resp, err := browser.Get(api.URL + "/api/v1/auth/oidc/mock/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("login: status %d, want 302", resp.StatusCode)
+	}
+
+	authorize, err := resp.Location()
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := authorize.Query()
+	query.Set("login_hint", loginHint)
+	authorize.RawQuery = query.Encode()
+
+	resp, err = browser.Get(authorize.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: status %d, want 302", resp.StatusCode)
+	}
+	return resp.Header.Get("Location")
+}
+
+func getCallback(t *testing.T, browser *http.Client, url string) (int, string) {
+	t.Helper()
+	resp, err := browser.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	api := startOIDCFlow(t)
+	browser := newBrowser(t)
+
+	status, body := getCallback(t, browser, callbackURL(t, browser, api, "user@example.com"))
+	if status != http.StatusOK {
+		t.Fatalf("callback: status %d (%s), want 200", status, body)
+	}
+	var tokens struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal([]byte(body), &tokens); err != nil {
+		t.Fatal(err)
+	}
+	if tokens.Token != "user-1" {
+		t.Errorf("token = %q, want user-1", tokens.Token)
+	}
+}
+
+func TestOIDCCallbackRequiresBrowserThatStartedLogin(t *testing.T) {
+	api := startOIDCFlow(t)
+
+	// Зловмисник починає вхід у своєму браузері та не завершує його
+	attacker := newBrowser(t)
+
+	t.Run("browser without cookie", func(t *testing.T) {
+		forged := callbackURL(t, attacker, api, "attacker@example.com")
+		if status, body := getCallback(t, newBrowser(t), forged); status != http.StatusBadRequest {
+			t.Errorf("status %d (%s), want 400", status, body)
+		}
+	})
+
+	t.Run("browser with its own login in progress", func(t *testing.T) {
+		victim := newBrowser(t)
+		callbackURL(t, victim, api, "user@example.com")
+
+		forged := callbackURL(t, attacker, api, "attacker@example.com")
+		if status, body := getCallback(t, victim, forged); status != http.StatusBadRequest {
+			t.Errorf("status %d (%s), want 400", status, body)
+		}
+	})
+}
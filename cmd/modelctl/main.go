@@ -0,0 +1,203 @@
+// Package main утиліта для експорту, імпорту та перегляду знімків рекомендаційної моделі.
+//
+// Використання:
+//
+//	modelctl export -out model.json.gz [-version v1] [-factors 16] [-neighbors 20]
+//	modelctl import -in model.json.gz [-dest /var/lib/recommendations/model.json.gz]
+//	modelctl inspect -in model.json.gz
+//
+// export навчає модель на даних з бази (параметри підключення як у API сервера)
+// і зберігає знімок. import перевіряє знімок і встановлює його за шляхом, з якого
+// сервер API завантажує модель під час запуску (MODEL_SNAPSHOT).
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"product-recommendations-go/internal/config"
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/repository"
+	"product-recommendations-go/pkg/recommendation/snapshot"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = runExport(os.Args[2:])
+	case "import":
+		err = runImport(os.Args[2:])
+	case "inspect":
+		err = runInspect(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("%s: %v", os.Args[1], err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: modelctl <export|import|inspect> [flags]")
+}
+
+// runExport навчає модель на поточних даних і зберігає знімок
+func runExport(args []string) error {
+	defaults := snapshot.DefaultTrainConfig()
+
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	out := fs.String("out", "model.json.gz", "шлях до файлу знімка (.gz - зі стисненням)")
+	version := fs.String("version", time.Now().UTC().Format("20060102-150405"), "мітка версії моделі")
+	neighbors := fs.Int("neighbors", defaults.Neighbors, "кількість подібних товарів на товар")
+	factors := fs.Int("factors", defaults.Factors, "розмірність векторів факторизації (0 - вимкнути)")
+	epochs := fs.Int("epochs", defaults.Epochs, "кількість епох факторизації")
+	minSupport := fs.Float64("min-support", defaults.MinSupport, "мінімальна підтримка асоціативних правил")
+	minConfidence := fs.Float64("min-confidence", defaults.MinConfidence, "мінімальна достовірність асоціативних правил")
+	seed := fs.Int64("seed", defaults.Seed, "seed для відтворюваного навчання")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg := defaults
+	cfg.ModelVersion = *version
+	cfg.Neighbors = *neighbors
+	cfg.Factors = *factors
+	cfg.Epochs = *epochs
+	cfg.MinSupport = *minSupport
+	cfg.MinConfidence = *minConfidence
+	cfg.Seed = *seed
+
+	db := config.GetDB()
+	defer config.CloseDB()
+
+	ctx := context.Background()
+	likes, err := repository.NewUserLikeRepository(db).GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("load likes: %w", err)
+	}
+	orders, err := repository.NewOrderRepository(db).GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("load orders: %w", err)
+	}
+	// Скасовані та повернуті замовлення не є покупками
+	orders = models.PurchasedOrders(orders)
+
+	log.Printf("Training model %q on %d likes and %d orders", cfg.ModelVersion, len(likes), len(orders))
+	model := snapshot.Train(likes, orders, cfg)
+
+	if err := snapshot.Save(*out, model); err != nil {
+		return err
+	}
+
+	log.Printf("Model snapshot written to %s", *out)
+	printSummary(model)
+	return nil
+}
+
+// runImport перевіряє знімок і встановлює його для сервера API
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	in := fs.String("in", "", "шлях до знімка, що імпортується")
+	dest := fs.String("dest", config.GetEnv("MODEL_SNAPSHOT", ""), "шлях, з якого сервер API завантажує модель")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" || *dest == "" {
+		return fmt.Errorf("both -in and -dest (or MODEL_SNAPSHOT) are required")
+	}
+
+	model, err := snapshot.Load(*in)
+	if err != nil {
+		return err
+	}
+	printSummary(model)
+
+	if err := snapshot.Save(*dest, model); err != nil {
+		return err
+	}
+
+	log.Printf("Model %q installed to %s; restart the API server to apply it", model.ModelVersion, *dest)
+	return nil
+}
+
+// runInspect виводить зведення про знімок для перевірки перед розгортанням
+func runInspect(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	in := fs.String("in", "", "шлях до знімка")
+	top := fs.Int("top", 10, "кількість найпопулярніших товарів та правил у зведенні")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	model, err := snapshot.Load(*in)
+	if err != nil {
+		return err
+	}
+	printSummary(model)
+
+	type popular struct {
+		id    uint
+		score float64
+	}
+	var items []popular
+	for id, score := range model.Popularity {
+		items = append(items, popular{id, score})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].score != items[j].score {
+			return items[i].score > items[j].score
+		}
+		return items[i].id < items[j].id
+	})
+
+	fmt.Println("Top products by popularity:")
+	for i := 0; i < len(items) && i < *top; i++ {
+		fmt.Printf("  %d\t%.2f\n", items[i].id, items[i].score)
+	}
+
+	rules := append([]snapshot.AssociationRule(nil), model.AssociationRules...)
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Lift > rules[j].Lift })
+
+	fmt.Println("Top association rules by lift:")
+	for i := 0; i < len(rules) && i < *top; i++ {
+		r := rules[i]
+		fmt.Printf("  %d => %d\tsupport=%.4f confidence=%.2f lift=%.2f\n",
+			r.Antecedent, r.Consequent, r.Support, r.Confidence, r.Lift)
+	}
+
+	return nil
+}
+
+func printSummary(model *snapshot.Snapshot) {
+	dim := 0
+	for _, vec := range model.ItemFactors {
+		dim = len(vec)
+		break
+	}
+
+	fmt.Printf("Model version:      %s\n", model.ModelVersion)
+	fmt.Printf("Format version:     %d\n", model.FormatVersion)
+	fmt.Printf("Created at:         %s\n", model.CreatedAt.Format(time.RFC3339))
+	fmt.Printf("Item similarity:    %d products\n", len(model.ItemSimilarity))
+	fmt.Printf("Item factors:       %d products x %d\n", len(model.ItemFactors), dim)
+	fmt.Printf("User factors:       %d users\n", len(model.UserFactors))
+	fmt.Printf("Popularity table:   %d products\n", len(model.Popularity))
+	fmt.Printf("Association rules:  %d\n", len(model.AssociationRules))
+	fmt.Printf("Repurchase cycles:  %d products\n", len(model.RepurchaseIntervals))
+}
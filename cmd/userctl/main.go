@@ -0,0 +1,128 @@
+// Package main утиліта для адміністрування користувачів.
+//
+// Використання:
+//
+//	userctl set-role -email admin@example.com -role admin
+//	userctl reset-2fa -email admin@example.com
+//
+// Нова роль потрапляє в токен під час наступного входу користувача.
+// reset-2fa вимикає двофакторну автентифікацію користувача, який втратив і
+// застосунок-автентифікатор, і резервні коди; особу користувача слід перевірити заздалегідь.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"product-recommendations-go/internal/config"
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/repository"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "set-role":
+		err = runSetRole(os.Args[2:])
+	case "reset-2fa":
+		err = runResetTwoFactor(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("%s: %v", os.Args[1], err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  userctl set-role -email <email> -role <customer|admin>")
+	fmt.Fprintln(os.Stderr, "  userctl reset-2fa -email <email>")
+}
+
+// runSetRole призначає роль користувачу
+func runSetRole(args []string) error {
+	fs := flag.NewFlagSet("set-role", flag.ExitOnError)
+	email := fs.String("email", "", "email користувача")
+	role := fs.String("role", "", "нова роль (customer або admin)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return fmt.Errorf("-email is required")
+	}
+	if !models.Role(*role).Valid() {
+		return fmt.Errorf("unknown role %q", *role)
+	}
+
+	db := config.GetDB()
+	defer config.CloseDB()
+
+	ctx := context.Background()
+	users := repository.NewUserRepository(db)
+
+	user, err := users.GetByEmail(ctx, *email)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("user %s not found", *email)
+	}
+
+	user.Role = models.Role(*role)
+	if err := users.UpdateColumns(ctx, user, "role"); err != nil {
+		return err
+	}
+
+	log.Printf("User %d (%s) now has role %q", user.ID, user.Email, user.Role)
+	return nil
+}
+
+// runResetTwoFactor вимикає двофакторну автентифікацію та видаляє резервні коди користувача
+func runResetTwoFactor(args []string) error {
+	fs := flag.NewFlagSet("reset-2fa", flag.ExitOnError)
+	email := fs.String("email", "", "email користувача")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return fmt.Errorf("-email is required")
+	}
+
+	db := config.GetDB()
+	defer config.CloseDB()
+
+	ctx := context.Background()
+	users := repository.NewUserRepository(db)
+
+	user, err := users.GetByEmail(ctx, *email)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("user %s not found", *email)
+	}
+
+	user.TOTPSecret = ""
+	user.TOTPEnabledAt = nil
+	user.TOTPLastStep = 0
+	if err := users.UpdateColumns(ctx, user, "totp_secret", "totp_enabled_at", "totp_last_step"); err != nil {
+		return err
+	}
+	if err := repository.NewRecoveryCodeRepository(db).Replace(ctx, user.ID, nil); err != nil {
+		return err
+	}
+
+	log.Printf("Two-factor authentication reset for user %d (%s)", user.ID, user.Email)
+	return nil
+}
@@ -0,0 +1,37 @@
+// Package cache реалізує кешування результатів рекомендацій
+// з інвалідацією за користувачем.
+//
+// Кожен користувач має версію кешу, яка змінюється при інвалідації.
+// Записи зберігаються під ключем (користувач, версія, ключ запиту), тому
+// результат, обчислений до інвалідації, ніколи не буде прочитаний після неї,
+// навіть якщо його запис завершився пізніше.
+//
+// Версії видаються з одного лічильника для всього кешу. Зміна каталогу
+// (InvalidateAll) піднімає нижню межу версій, тож версії всіх користувачів
+// змінюються одночасно без перебору ключів.
+package cache
+
+import (
+	"context"
+	"product-recommendations-go/internal/models"
+	"strconv"
+)
+
+// RecommendationCache кеш рекомендацій користувачів
+type RecommendationCache interface {
+	// Version повертає поточну версію кешу користувача
+	Version(ctx context.Context, userID uint) (uint64, error)
+	// Get повертає збережені рекомендації для версії та ключа запиту
+	Get(ctx context.Context, userID uint, version uint64, key string) ([]*models.ProductRecommendation, bool, error)
+	// Set зберігає рекомендації для версії та ключа запиту
+	Set(ctx context.Context, userID uint, version uint64, key string, recommendations []*models.ProductRecommendation) error
+	// InvalidateUser робить недійсними всі записи користувача
+	InvalidateUser(ctx context.Context, userID uint) error
+	// InvalidateAll робить недійсними записи всіх користувачів, наприклад після зміни каталогу
+	InvalidateAll(ctx context.Context) error
+}
+
+// userKeyPrefix повертає префікс ключів користувача
+func userKeyPrefix(userID uint) string {
+	return "user:" + strconv.FormatUint(uint64(userID), 10) + ":"
+}
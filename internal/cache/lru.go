@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"product-recommendations-go/internal/models"
+	"sync"
+	"time"
+)
+
+type lruEntry struct {
+	userID          uint
+	key             string
+	recommendations []*models.ProductRecommendation
+	expiresAt       time.Time
+}
+
+type versionEntry struct {
+	userID  uint
+	version uint64
+}
+
+// LRUCache потокобезпечний кеш у пам'яті процесу з витісненням
+// найдавніше використаних записів і обмеженим часом життя
+type LRUCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	order    *list.List
+	entries  map[string]*list.Element
+	byUser   map[uint]map[string]*list.Element
+	// versions власні версії користувачів, чий кеш інвалідовувався, у порядку
+	// інвалідації (найстаріша спереду); їх не більше capacity
+	versions     map[uint]*list.Element
+	versionOrder *list.List
+	// clock останній виданий номер версії
+	clock uint64
+	// floor версія користувачів без власної версії. Піднімається під час
+	// InvalidateAll і під час витіснення власної версії, щоб результат,
+	// обчислений до інвалідації витісненого користувача, не був збережений.
+	floor uint64
+	now   func() time.Time
+}
+
+// NewLRUCache створює новий LRU-кеш на capacity записів з часом життя ttl
+func NewLRUCache(capacity int, ttl time.Duration) *LRUCache {
+	if capacity <= 0 {
+		capacity = 10000
+	}
+
+	return &LRUCache{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		byUser:   make(map[uint]map[string]*list.Element),
+
+		versions:     make(map[uint]*list.Element),
+		versionOrder: list.New(),
+		now:          time.Now,
+	}
+}
+
+// Version повертає поточну версію кешу користувача
+func (c *LRUCache) Version(_ context.Context, userID uint) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.version(userID), nil
+}
+
+// Get повертає збережені рекомендації, якщо запис існує і не застарів
+func (c *LRUCache) Get(_ context.Context, userID uint, version uint64, key string) ([]*models.ProductRecommendation, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.version(userID) != version {
+		return nil, false, nil
+	}
+
+	el, ok := c.entries[entryKey(userID, key)]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := el.Value.(*lruEntry)
+	if c.ttl > 0 && c.now().After(entry.expiresAt) {
+		c.removeElement(el)
+		return nil, false, nil
+	}
+
+	c.order.MoveToFront(el)
+
+	// Повертаємо копію зрізу, щоб виклики не впливали на збережений запис
+	result := make([]*models.ProductRecommendation, len(entry.recommendations))
+	copy(result, entry.recommendations)
+	return result, true, nil
+}
+
+// Set зберігає рекомендації. Записи із застарілою версією ігноруються.
+func (c *LRUCache) Set(_ context.Context, userID uint, version uint64, key string, recommendations []*models.ProductRecommendation) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Поки рекомендації обчислювалися, кеш користувача був інвалідований
+	if c.version(userID) != version {
+		return nil
+	}
+
+	stored := make([]*models.ProductRecommendation, len(recommendations))
+	copy(stored, recommendations)
+
+	k := entryKey(userID, key)
+	if el, ok := c.entries[k]; ok {
+		entry := el.Value.(*lruEntry)
+		entry.recommendations = stored
+		entry.expiresAt = c.now().Add(c.ttl)
+		c.order.MoveToFront(el)
+		return nil
+	}
+
+	el := c.order.PushFront(&lruEntry{
+		userID:          userID,
+		key:             k,
+		recommendations: stored,
+		expiresAt:       c.now().Add(c.ttl),
+	})
+	c.entries[k] = el
+	if c.byUser[userID] == nil {
+		c.byUser[userID] = make(map[string]*list.Element)
+	}
+	c.byUser[userID][k] = el
+
+	for c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back())
+	}
+
+	return nil
+}
+
+// InvalidateUser видаляє всі записи користувача і підвищує його версію
+func (c *LRUCache) InvalidateUser(_ context.Context, userID uint) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clock++
+	if el, ok := c.versions[userID]; ok {
+		el.Value.(*versionEntry).version = c.clock
+		c.versionOrder.MoveToBack(el)
+	} else {
+		c.versions[userID] = c.versionOrder.PushBack(&versionEntry{userID: userID, version: c.clock})
+	}
+
+	// Версії видаються за зростанням, тому найстаріша версія - найменша
+	for c.versionOrder.Len() > c.capacity {
+		oldest := c.versionOrder.Remove(c.versionOrder.Front()).(*versionEntry)
+		delete(c.versions, oldest.userID)
+		c.floor = oldest.version
+	}
+
+	for _, el := range c.byUser[userID] {
+		c.removeElement(el)
+	}
+	delete(c.byUser, userID)
+
+	return nil
+}
+
+// InvalidateAll видаляє записи всіх користувачів і підвищує версії всіх користувачів
+func (c *LRUCache) InvalidateAll(_ context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clock++
+	c.floor = c.clock
+	c.versions = make(map[uint]*list.Element)
+	c.versionOrder.Init()
+
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+	c.byUser = make(map[uint]map[string]*list.Element)
+
+	return nil
+}
+
+// Len повертає кількість записів у кеші
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// version повертає поточну версію користувача; викликається під c.mu
+func (c *LRUCache) version(userID uint) uint64 {
+	if el, ok := c.versions[userID]; ok {
+		return el.Value.(*versionEntry).version
+	}
+	return c.floor
+}
+
+func (c *LRUCache) removeElement(el *list.Element) {
+	entry := el.Value.(*lruEntry)
+	c.order.Remove(el)
+	delete(c.entries, entry.key)
+	if userEntries, ok := c.byUser[entry.userID]; ok {
+		delete(userEntries, entry.key)
+		if len(userEntries) == 0 {
+			delete(c.byUser, entry.userID)
+		}
+	}
+}
+
+func entryKey(userID uint, key string) string {
+	return userKeyPrefix(userID) + key
+}
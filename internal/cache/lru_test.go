@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRUCache(2, time.Minute)
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b"} {
+		if err := c.Set(ctx, 1, 0, key, testRecommendations(1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// "a" використано останнім, тому витісняється "b"
+	if _, found, _ := c.Get(ctx, 1, 0, "a"); !found {
+		t.Fatal("entry a is missing")
+	}
+	if err := c.Set(ctx, 1, 0, "c", testRecommendations(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+	if _, found, _ := c.Get(ctx, 1, 0, "b"); found {
+		t.Error("least recently used entry b was not evicted")
+	}
+	if _, found, _ := c.Get(ctx, 1, 0, "a"); !found {
+		t.Error("recently used entry a was evicted")
+	}
+}
+
+func TestLRUCacheExpires(t *testing.T) {
+	c := NewLRUCache(10, time.Minute)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if err := c.Set(ctx, 1, 0, "k", testRecommendations(1)); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, found, _ := c.Get(ctx, 1, 0, "k"); found {
+		t.Error("expired entry is served")
+	}
+}
+
+func TestLRUCacheInvalidateUser(t *testing.T) {
+	c := NewLRUCache(10, time.Minute)
+	ctx := context.Background()
+
+	before, _ := c.Version(ctx, 1)
+	if err := c.Set(ctx, 1, before, "k", testRecommendations(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(ctx, 2, 0, "k", testRecommendations(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.InvalidateUser(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := c.Version(ctx, 1)
+	if after == before {
+		t.Fatal("InvalidateUser did not change the version")
+	}
+	if _, found, _ := c.Get(ctx, 1, after, "k"); found {
+		t.Error("entry stored before invalidation is served")
+	}
+
+	// Результат, обчислений до інвалідації, не зберігається
+	if err := c.Set(ctx, 1, before, "k", testRecommendations(9)); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := c.Get(ctx, 1, after, "k"); found {
+		t.Error("stale result written after invalidation is served")
+	}
+
+	if _, found, _ := c.Get(ctx, 2, 0, "k"); !found {
+		t.Error("entry of another user was invalidated")
+	}
+}
+
+func TestLRUCacheInvalidateAll(t *testing.T) {
+	c := NewLRUCache(10, time.Minute)
+	ctx := context.Background()
+
+	if err := c.InvalidateUser(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	versions := map[uint]uint64{}
+	for _, userID := range []uint{1, 2} {
+		versions[userID], _ = c.Version(ctx, userID)
+		if err := c.Set(ctx, userID, versions[userID], "k", testRecommendations(userID)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := c.InvalidateAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if c.Len() != 0 {
+		t.Errorf("Len() = %d after InvalidateAll, want 0", c.Len())
+	}
+
+	for userID, before := range versions {
+		after, _ := c.Version(ctx, userID)
+		if after == before {
+			t.Errorf("user %d: version did not change", userID)
+		}
+		// Результат, обчислений до зміни каталогу, не зберігається
+		if err := c.Set(ctx, userID, before, "k", testRecommendations(9)); err != nil {
+			t.Fatal(err)
+		}
+		if _, found, _ := c.Get(ctx, userID, after, "k"); found {
+			t.Errorf("user %d: stale result is served after InvalidateAll", userID)
+		}
+	}
+}
+
+func TestLRUCacheBoundsVersions(t *testing.T) {
+	const capacity = 100
+	c := NewLRUCache(capacity, time.Minute)
+	ctx := context.Background()
+
+	// Версія користувача 1, прочитана до його інвалідації
+	stale, _ := c.Version(ctx, 1)
+
+	for userID := uint(1); userID <= 10*capacity; userID++ {
+		if err := c.InvalidateUser(ctx, userID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(c.versions) > capacity || c.versionOrder.Len() > capacity {
+		t.Fatalf("tracking %d versions, want at most %d", len(c.versions), capacity)
+	}
+
+	// Власна версія користувача 1 витіснена, але застарілий результат
+	// все одно не може бути збережений
+	current, _ := c.Version(ctx, 1)
+	if current == stale {
+		t.Fatalf("version of evicted user fell back to the stale version %d", stale)
+	}
+	if err := c.Set(ctx, 1, stale, "k", testRecommendations(9)); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := c.Get(ctx, 1, current, "k"); found {
+		t.Error("stale result of an evicted user is served")
+	}
+
+	// Останні інвалідовані користувачі зберігають власні версії
+	last := uint(10 * capacity)
+	version, _ := c.Version(ctx, last)
+	if err := c.Set(ctx, last, version, "k", testRecommendations(1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := c.Get(ctx, last, version, "k"); !found {
+		t.Errorf("entry of user %d is missing", last)
+	}
+}
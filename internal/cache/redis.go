@@ -0,0 +1,336 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"product-recommendations-go/internal/models"
+	"strconv"
+	"time"
+)
+
+// RedisConfig налаштування підключення до сервера з протоколом Redis (RESP)
+type RedisConfig struct {
+	Addr        string
+	Password    string
+	DB          int
+	Prefix      string
+	TTL         time.Duration
+	PoolSize    int
+	DialTimeout time.Duration
+}
+
+// RedisCache кеш рекомендацій у сервері, сумісному з протоколом Redis.
+// Використовує лише команди GET, SET та INCR, тому працює також з
+// локальними замінниками Redis для тестування.
+type RedisCache struct {
+	cfg  RedisConfig
+	pool chan *redisConn
+}
+
+// errNil позначає відсутнє значення (nil bulk string)
+var errNil = errors.New("redis: nil")
+
+// NewRedisCache створює кеш поверх сервера Redis. Підключення
+// встановлюються ліниво при першому запиті.
+func NewRedisCache(cfg RedisConfig) *RedisCache {
+	if cfg.Prefix == "" {
+		cfg.Prefix = "recs"
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 10
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+
+	return &RedisCache{
+		cfg:  cfg,
+		pool: make(chan *redisConn, cfg.PoolSize),
+	}
+}
+
+// Version повертає поточну версію кешу користувача: більшу з власної версії
+// користувача та нижньої межі версій, яку піднімає InvalidateAll
+func (c *RedisCache) Version(ctx context.Context, userID uint) (uint64, error) {
+	version, err := c.getUint(ctx, c.versionKey(userID))
+	if err != nil {
+		return 0, err
+	}
+	floor, err := c.getUint(ctx, c.cfg.Prefix+":floor")
+	if err != nil {
+		return 0, err
+	}
+
+	return max(version, floor), nil
+}
+
+// Get повертає збережені рекомендації для версії та ключа запиту
+func (c *RedisCache) Get(ctx context.Context, userID uint, version uint64, key string) ([]*models.ProductRecommendation, bool, error) {
+	reply, err := c.do(ctx, "GET", c.entryKey(userID, version, key))
+	if errors.Is(err, errNil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var recommendations []*models.ProductRecommendation
+	if err := json.Unmarshal(reply.([]byte), &recommendations); err != nil {
+		return nil, false, fmt.Errorf("decode cached recommendations: %w", err)
+	}
+
+	return recommendations, true, nil
+}
+
+// Set зберігає рекомендації з часом життя TTL
+func (c *RedisCache) Set(ctx context.Context, userID uint, version uint64, key string, recommendations []*models.ProductRecommendation) error {
+	payload, err := json.Marshal(recommendations)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"SET", c.entryKey(userID, version, key), string(payload)}
+	if c.cfg.TTL > 0 {
+		args = append(args, "PX", strconv.FormatInt(c.cfg.TTL.Milliseconds(), 10))
+	}
+
+	_, err = c.do(ctx, args...)
+	return err
+}
+
+// InvalidateUser присвоює користувачу нову версію кешу. Записи попередніх
+// версій більше не читаються і видаляються сервером після закінчення TTL.
+// Власна версія користувача живе вдвічі довше за записи: після цього записів
+// старих версій уже немає, і користувачу достатньо нижньої межі версій.
+func (c *RedisCache) InvalidateUser(ctx context.Context, userID uint) error {
+	version, err := c.nextVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"SET", c.versionKey(userID), version}
+	if c.cfg.TTL > 0 {
+		args = append(args, "PX", strconv.FormatInt(2*c.cfg.TTL.Milliseconds(), 10))
+	}
+
+	_, err = c.do(ctx, args...)
+	return err
+}
+
+// InvalidateAll піднімає нижню межу версій вище за версії всіх користувачів
+func (c *RedisCache) InvalidateAll(ctx context.Context) error {
+	version, err := c.nextVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.do(ctx, "SET", c.cfg.Prefix+":floor", version)
+	return err
+}
+
+// nextVersion видає новий номер версії зі спільного для всього кешу лічильника
+func (c *RedisCache) nextVersion(ctx context.Context) (string, error) {
+	reply, err := c.do(ctx, "INCR", c.cfg.Prefix+":clock")
+	if err != nil {
+		return "", err
+	}
+	version, ok := reply.(int64)
+	if !ok {
+		return "", fmt.Errorf("redis: unexpected INCR reply %v", reply)
+	}
+	return strconv.FormatInt(version, 10), nil
+}
+
+// getUint читає числове значення ключа; відсутній ключ означає 0
+func (c *RedisCache) getUint(ctx context.Context, key string) (uint64, error) {
+	reply, err := c.do(ctx, "GET", key)
+	if errors.Is(err, errNil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(string(reply.([]byte)), 10, 64)
+}
+
+// Close закриває всі відкриті підключення
+func (c *RedisCache) Close() error {
+	for {
+		select {
+		case conn := <-c.pool:
+			conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *RedisCache) versionKey(userID uint) string {
+	return c.cfg.Prefix + ":" + userKeyPrefix(userID) + "version"
+}
+
+func (c *RedisCache) entryKey(userID uint, version uint64, key string) string {
+	return c.cfg.Prefix + ":" + userKeyPrefix(userID) + strconv.FormatUint(version, 10) + ":" + key
+}
+
+// do виконує одну команду на підключенні з пулу
+func (c *RedisCache) do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.do(ctx, args...)
+	if err != nil && !errors.Is(err, errNil) && !isServerError(err) {
+		// Після мережевої помилки стан підключення невідомий
+		conn.Close()
+		return nil, err
+	}
+
+	c.release(conn)
+	return reply, err
+}
+
+func (c *RedisCache) acquire(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-c.pool:
+		return conn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: c.cfg.DialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &redisConn{
+		conn:   netConn,
+		reader: bufio.NewReader(netConn),
+		writer: bufio.NewWriter(netConn),
+	}
+
+	if c.cfg.Password != "" {
+		if _, err := conn.do(ctx, "AUTH", c.cfg.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if c.cfg.DB != 0 {
+		if _, err := conn.do(ctx, "SELECT", strconv.Itoa(c.cfg.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+func (c *RedisCache) release(conn *redisConn) {
+	select {
+	case c.pool <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+// serverError помилка, повернута сервером (відповідь типу "-ERR ...")
+type serverError string
+
+func (e serverError) Error() string {
+	return "redis: " + string(e)
+}
+
+func isServerError(err error) bool {
+	var se serverError
+	return errors.As(err, &se)
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+func (c *redisConn) Close() {
+	_ = c.conn.Close()
+}
+
+func (c *redisConn) do(ctx context.Context, args ...string) (interface{}, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(5 * time.Second)
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	// Запит у форматі RESP: масив bulk-рядків
+	fmt.Fprintf(c.writer, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(c.writer, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := c.writer.Flush(); err != nil {
+		return nil, err
+	}
+
+	return c.readReply()
+}
+
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	payload := line[1 : len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, serverError(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, errNil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(c.reader, buf); err != nil {
+			return nil, err
+		}
+		return buf[:size], nil
+	case '*':
+		size, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, errNil
+		}
+		items := make([]interface{}, 0, size)
+		for i := 0; i < size; i++ {
+			item, err := c.readReply()
+			if err != nil && !errors.Is(err, errNil) {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply type %q", line[0])
+	}
+}
@@ -0,0 +1,356 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"product-recommendations-go/internal/models"
+)
+
+// fakeRedis мінімальний замінник сервера Redis: підтримує AUTH, SELECT, GET,
+// SET (з PX) та INCR і записує отримані команди
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mu       sync.Mutex
+	values   map[string]string
+	expires  map[string]time.Time
+	commands []string
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeRedis{
+		listener: listener,
+		password: password,
+		values:   make(map[string]string),
+		expires:  make(map[string]time.Time),
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeRedis) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := s.password == ""
+
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.commands = append(s.commands, strings.ToUpper(args[0]))
+		var reply string
+		switch cmd := strings.ToUpper(args[0]); {
+		case cmd == "AUTH":
+			authenticated = args[1] == s.password
+			reply = "+OK\r\n"
+			if !authenticated {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authenticated:
+			reply = "-NOAUTH Authentication required\r\n"
+		case cmd == "SELECT":
+			reply = "+OK\r\n"
+		case cmd == "GET":
+			if value, ok := s.get(args[1]); ok {
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+			} else {
+				reply = "$-1\r\n"
+			}
+		case cmd == "SET":
+			s.values[args[1]] = args[2]
+			delete(s.expires, args[1])
+			if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+				ms, _ := strconv.Atoi(args[4])
+				s.expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			}
+			reply = "+OK\r\n"
+		case cmd == "INCR":
+			value, _ := s.get(args[1])
+			n, _ := strconv.ParseInt(value, 10, 64)
+			n++
+			s.values[args[1]] = strconv.FormatInt(n, 10)
+			reply = fmt.Sprintf(":%d\r\n", n)
+		default:
+			reply = fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+		}
+		s.mu.Unlock()
+
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// get повертає значення ключа з урахуванням часу життя; викликається під s.mu
+func (s *fakeRedis) get(key string) (string, bool) {
+	if expiresAt, ok := s.expires[key]; ok && time.Now().After(expiresAt) {
+		delete(s.values, key)
+		delete(s.expires, key)
+	}
+	value, ok := s.values[key]
+	return value, ok
+}
+
+func (s *fakeRedis) ttl(key string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiresAt, ok := s.expires[key]
+	if !ok {
+		return 0
+	}
+	return time.Until(expiresAt)
+}
+
+func (s *fakeRedis) commandCount(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int
+	for _, cmd := range s.commands {
+		if cmd == name {
+			n++
+		}
+	}
+	return n
+}
+
+// readCommand читає команду клієнта у форматі RESP (масив bulk-рядків)
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil || line[0] != '*' {
+		return nil, fmt.Errorf("unexpected command %q", line)
+	}
+
+	args := make([]string, count)
+	for i := range args {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(header[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func testRecommendations(ids ...uint) []*models.ProductRecommendation {
+	recommendations := make([]*models.ProductRecommendation, len(ids))
+	for i, id := range ids {
+		recommendations[i] = &models.ProductRecommendation{
+			Product: &models.Product{ID: id, Name: fmt.Sprintf("Product %d", id)},
+			Score:   float64(len(ids) - i),
+		}
+	}
+	return recommendations
+}
+
+func recommendationIDs(recommendations []*models.ProductRecommendation) []uint {
+	ids := make([]uint, len(recommendations))
+	for i, r := range recommendations {
+		ids[i] = r.Product.ID
+	}
+	return ids
+}
+
+func TestRedisCacheGetSet(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+	c := NewRedisCache(RedisConfig{Addr: server.addr(), Password: "secret", DB: 2, TTL: time.Minute})
+	defer c.Close()
+	ctx := context.Background()
+
+	version, err := c.Version(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found, err := c.Get(ctx, 1, version, "k"); err != nil || found {
+		t.Fatalf("Get on empty cache: found=%v err=%v", found, err)
+	}
+
+	if err := c.Set(ctx, 1, version, "k", testRecommendations(3, 1, 2)); err != nil {
+		t.Fatal(err)
+	}
+	got, found, err := c.Get(ctx, 1, version, "k")
+	if err != nil || !found {
+		t.Fatalf("Get after Set: found=%v err=%v", found, err)
+	}
+	if ids := recommendationIDs(got); fmt.Sprint(ids) != "[3 1 2]" {
+		t.Errorf("Get = %v, want [3 1 2]", ids)
+	}
+	if _, found, _ := c.Get(ctx, 2, version, "k"); found {
+		t.Error("entry of user 1 returned for user 2")
+	}
+
+	// Записи зберігаються з часом життя TTL
+	if ttl := server.ttl(c.entryKey(1, version, "k")); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("entry TTL = %v, want (0, 1m]", ttl)
+	}
+	// Підключення повторно використовуються з пулу
+	if n := server.commandCount("AUTH"); n != 1 {
+		t.Errorf("AUTH sent %d times, want 1", n)
+	}
+	if n := server.commandCount("SELECT"); n != 1 {
+		t.Errorf("SELECT sent %d times, want 1", n)
+	}
+}
+
+func TestRedisCacheInvalidateUser(t *testing.T) {
+	server := newFakeRedis(t, "")
+	c := NewRedisCache(RedisConfig{Addr: server.addr(), TTL: time.Minute})
+	defer c.Close()
+	ctx := context.Background()
+
+	before, _ := c.Version(ctx, 1)
+	other, _ := c.Version(ctx, 2)
+	if err := c.Set(ctx, 1, before, "k", testRecommendations(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(ctx, 2, other, "k", testRecommendations(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.InvalidateUser(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	after, err := c.Version(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after == before {
+		t.Fatal("InvalidateUser did not change the user version")
+	}
+	if _, found, _ := c.Get(ctx, 1, after, "k"); found {
+		t.Error("entry stored before invalidation is still served")
+	}
+
+	// Результат, обчислений до інвалідації, записується під старою версією і не читається
+	if err := c.Set(ctx, 1, before, "k", testRecommendations(9)); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := c.Get(ctx, 1, after, "k"); found {
+		t.Error("stale result written after invalidation is served")
+	}
+
+	// Інші користувачі не зачеплені
+	if v, _ := c.Version(ctx, 2); v != other {
+		t.Errorf("version of user 2 changed from %d to %d", other, v)
+	}
+	if _, found, _ := c.Get(ctx, 2, other, "k"); !found {
+		t.Error("entry of user 2 was invalidated")
+	}
+
+	// Власна версія користувача зберігається вдвічі довше за записи
+	if ttl := server.ttl(c.versionKey(1)); ttl <= time.Minute || ttl > 2*time.Minute {
+		t.Errorf("version TTL = %v, want (1m, 2m]", ttl)
+	}
+}
+
+func TestRedisCacheInvalidateAll(t *testing.T) {
+	server := newFakeRedis(t, "")
+	c := NewRedisCache(RedisConfig{Addr: server.addr(), TTL: time.Minute})
+	defer c.Close()
+	ctx := context.Background()
+
+	if err := c.InvalidateUser(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	versions := map[uint]uint64{}
+	for _, userID := range []uint{1, 2} {
+		versions[userID], _ = c.Version(ctx, userID)
+		if err := c.Set(ctx, userID, versions[userID], "k", testRecommendations(userID)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := c.InvalidateAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for userID, before := range versions {
+		after, err := c.Version(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if after <= before {
+			t.Errorf("user %d: version %d did not grow after InvalidateAll (was %d)", userID, after, before)
+		}
+		if _, found, _ := c.Get(ctx, userID, after, "k"); found {
+			t.Errorf("user %d: entry stored before InvalidateAll is served", userID)
+		}
+	}
+
+	// Інвалідація користувача після InvalidateAll знову підвищує його версію
+	floor, _ := c.Version(ctx, 1)
+	if err := c.InvalidateUser(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := c.Version(ctx, 1); v <= floor {
+		t.Errorf("version after InvalidateUser = %d, want above %d", v, floor)
+	}
+}
+
+func TestRedisCacheErrors(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+	ctx := context.Background()
+
+	wrongPassword := NewRedisCache(RedisConfig{Addr: server.addr(), Password: "wrong"})
+	defer wrongPassword.Close()
+	if _, err := wrongPassword.Version(ctx, 1); err == nil {
+		t.Error("Version with a wrong password succeeded")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	unreachable := NewRedisCache(RedisConfig{Addr: addr, DialTimeout: time.Second})
+	defer unreachable.Close()
+	if err := unreachable.InvalidateUser(ctx, 1); err == nil {
+		t.Error("InvalidateUser on an unreachable server succeeded")
+	}
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GetEnvInt отримує цілочисельне значення з змінних середовища або повертає запасне значення
+func GetEnvInt(key string, fallback int) int {
+	value := GetEnv(key, "")
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid value for %s: %q, using %d", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// GetEnvDuration отримує тривалість (наприклад, "10m") з змінних середовища або повертає запасне значення
+func GetEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := GetEnv(key, "")
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid value for %s: %q, using %s", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// GetEnvBool отримує логічне значення ("true", "1", "false", "0" тощо) з змінних середовища або повертає запасне значення
+func GetEnvBool(key string, fallback bool) bool {
+	value := GetEnv(key, "")
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid value for %s: %q, using %t", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// GetEnvList отримує список значень, розділених комами (порожні елементи пропускаються)
+func GetEnvList(key string) []string {
+	var result []string
+	for _, part := range strings.Split(GetEnv(key, ""), ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+// IsDevelopment повідомляє, чи запущено додаток у режимі розробки (APP_ENV=dev, development або local)
+func IsDevelopment() bool {
+	switch strings.ToLower(GetEnv("APP_ENV", "")) {
+	case "dev", "development", "local":
+		return true
+	default:
+		return false
+	}
+}
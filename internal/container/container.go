@@ -2,33 +2,83 @@
 package container
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"gorm.io/gorm"
+	"log"
+	"os"
+	"path/filepath"
+	"product-recommendations-go/internal/cache"
 	"product-recommendations-go/internal/config"
 	"product-recommendations-go/internal/delivery/http/handlers"
+	"product-recommendations-go/internal/jwtkeys"
+	"product-recommendations-go/internal/mail"
+	"product-recommendations-go/internal/oidc"
 	"product-recommendations-go/internal/repository"
+	"product-recommendations-go/internal/secretbox"
 	"product-recommendations-go/internal/service"
+	"product-recommendations-go/pkg/recommendation/snapshot"
+	"strings"
+	"time"
 )
 
 // Container зберігає всі залежності програми
 type Container struct {
 	// Репозиторії
-	UserRepository    repository.UserRepository
-	ProductRepository repository.ProductRepository
-	LikeRepository    repository.UserLikeRepository
-	OrderRepository   repository.OrderRepository
+	UserRepository         repository.UserRepository
+	ProductRepository      repository.ProductRepository
+	LikeRepository         repository.UserLikeRepository
+	OrderRepository        repository.OrderRepository
+	RevokedTokenRepository repository.RevokedTokenRepository
+	RefreshTokenRepository repository.RefreshTokenRepository
+	UserTokenRepository    repository.UserTokenRepository
+	LoginAttemptRepository repository.LoginAttemptRepository
+	RecoveryCodeRepository repository.RecoveryCodeRepository
+	UserIdentityRepository repository.UserIdentityRepository
+	OIDCStateRepository    repository.OIDCStateRepository
+	APIKeyRepository       repository.APIKeyRepository
+	DataExportRepository   repository.DataExportRepository
+	CartRepository         repository.CartRepository
+
+	// Список відкликаних токенів доступу
+	TokenDenylist service.TokenDenylist
+
+	// Ключі підпису токенів доступу
+	SigningKeys *jwtkeys.KeySet
+
+	// Надсилання листів користувачам
+	Mailer mail.Mailer
+
+	// Захист від підбору пароля
+	LoginThrottle service.LoginThrottle
+
+	// Кеш рекомендацій (nil, якщо кешування вимкнене)
+	RecommendationCache cache.RecommendationCache
 
 	// Сервіси
 	AuthService           service.AuthService
+	OIDCService           service.OIDCService
+	APIKeyService         service.APIKeyService
+	UserService           service.UserService
+	DataExportService     service.DataExportService
 	ProductService        service.ProductService
 	LikeService           service.LikeService
 	OrderService          service.OrderService
+	CartService           service.CartService
 	RecommendationService service.RecommendationService
 
 	// Обробники HTTP запитів
 	AuthHandler           *handlers.AuthHandler
+	OIDCHandler           *handlers.OIDCHandler
+	APIKeyHandler         *handlers.APIKeyHandler
+	UserHandler           *handlers.UserHandler
+	DataExportHandler     *handlers.DataExportHandler
 	ProductHandler        *handlers.ProductHandler
 	LikeHandler           *handlers.LikeHandler
 	OrderHandler          *handlers.OrderHandler
+	CartHandler           *handlers.CartHandler
 	RecommendationHandler *handlers.RecommendationHandler
+	JWKSHandler           *handlers.JWKSHandler
 }
 
 // NewContainer створює новий контейнер залежностей
@@ -37,45 +87,325 @@ func NewContainer() *Container {
 	db := config.GetDB()
 
 	// Ініціалізуємо репозиторії
+	transactor := repository.NewTransactor(db)
 	userRepo := repository.NewUserRepository(db)
 	productRepo := repository.NewProductRepository(db)
 	likeRepo := repository.NewUserLikeRepository(db)
 	orderRepo := repository.NewOrderRepository(db)
+	revokedTokenRepo := repository.NewRevokedTokenRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	userTokenRepo := repository.NewUserTokenRepository(db)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(db)
+	recoveryCodeRepo := repository.NewRecoveryCodeRepository(db)
+	userIdentityRepo := repository.NewUserIdentityRepository(db)
+	oidcStateRepo := repository.NewOIDCStateRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	dataExportRepo := repository.NewDataExportRepository(db)
+	cartRepo := repository.NewCartRepository(db)
+
+	// Параметри видачі токенів: короткий токен доступу та довгий refresh токен
+	signingKeys := loadSigningKeys()
+	authConfig := service.AuthConfig{
+		Keys:            signingKeys,
+		AccessTokenTTL:  config.GetEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: config.GetEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+
+		EmailVerificationTTL: config.GetEnvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		PasswordResetTTL:     config.GetEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+		AppURL:               strings.TrimRight(config.GetEnv("APP_URL", "http://localhost:8080"), "/"),
 
-	// Отримуємо JWT секретний ключ
-	jwtSecret := config.GetEnv("JWT_SECRET", "your-secret-key")
+		TOTPIssuer:   config.GetEnv("TOTP_ISSUER", "Product Recommendations"),
+		TOTPSecrets:  loadTOTPSecrets(),
+		ChallengeTTL: config.GetEnvDuration("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute),
+	}
+
+	mailer := newMailer()
+
+	// Захист від підбору пароля: затримка після кількох невдалих спроб, що
+	// подвоюється з кожною наступною, і тимчасове блокування
+	loginThrottle := service.NewLoginThrottle(newLoginFailureStore(db), loginAttemptRepo, service.LoginThrottleConfig{
+		Window:             config.GetEnvDuration("LOGIN_ATTEMPT_WINDOW", 15*time.Minute),
+		FreeAttempts:       config.GetEnvInt("LOGIN_FREE_ATTEMPTS", 3),
+		BaseDelay:          config.GetEnvDuration("LOGIN_BASE_DELAY", time.Second),
+		MaxDelay:           config.GetEnvDuration("LOGIN_MAX_DELAY", 30*time.Second),
+		MaxAccountFailures: config.GetEnvInt("LOGIN_MAX_ACCOUNT_FAILURES", 10),
+		MaxIPFailures:      config.GetEnvInt("LOGIN_MAX_IP_FAILURES", 100),
+		LockoutDuration:    config.GetEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		AuditRetention:     config.GetEnvDuration("LOGIN_AUDIT_RETENTION", 90*24*time.Hour),
+	})
+
+	// Кеш перевірок відкликаних токенів вимкнений за замовчуванням: кожен запит
+	// перевіряється в базі, тому вихід діє одразу на всіх екземплярах
+	tokenDenylist := service.NewTokenDenylist(revokedTokenRepo, config.GetEnvDuration("TOKEN_DENYLIST_CACHE_TTL", 0))
+
+	// Ініціалізуємо кеш рекомендацій
+	recommendationCache := newRecommendationCache()
+
+	// Інвалідатор передається сервісам взаємодій лише якщо кеш увімкнений
+	var invalidator service.RecommendationInvalidator
+	if recommendationCache != nil {
+		invalidator = recommendationCache
+	}
 
 	// Ініціалізуємо сервіси
-	authService := service.NewAuthService(userRepo, jwtSecret)
-	productService := service.NewProductService(productRepo)
-	likeService := service.NewLikeService(likeRepo, productRepo)
-	orderService := service.NewOrderService(orderRepo, productRepo)
-	recommendationService := service.NewRecommendationService(likeRepo, orderRepo, productRepo)
+	authService := service.NewAuthService(userRepo, refreshTokenRepo, userTokenRepo, recoveryCodeRepo, tokenDenylist, loginThrottle, mailer, authConfig)
+	oidcService := service.NewOIDCService(loadOIDCProviders(), authService, userRepo, userIdentityRepo, oidcStateRepo, config.GetEnvDuration("OIDC_LOGIN_TTL", 10*time.Minute))
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, userRepo)
+	userService := service.NewUserService(userRepo)
+	productService := service.NewProductService(productRepo, invalidator, config.GetEnvInt("LOW_STOCK_THRESHOLD", 5))
+	likeService := service.NewLikeService(likeRepo, productRepo, invalidator)
+	orderService := service.NewOrderService(transactor, orderRepo, productRepo, invalidator)
+	// Завантажуємо навчену офлайн модель, якщо вказана
+	model := loadModelSnapshot()
+
+	recommendationService := service.NewRecommendationService(likeRepo, orderRepo, productRepo, model, config.GetEnv("VECTOR_INDEX", ""))
+	if recommendationCache != nil {
+		strategy := "hybrid"
+		if model != nil {
+			strategy += "+model:" + model.ModelVersion + "@" + model.CreatedAt.Format(time.RFC3339)
+		}
+		recommendationService = service.NewCachedRecommendationService(recommendationService, recommendationCache, strategy)
+	}
+
+	// Кошики гостів, що не змінювалися CART_GUEST_TTL, видаляються
+	cartService := service.NewCartService(transactor, cartRepo, productRepo, orderService, recommendationService,
+		config.GetEnvDuration("CART_GUEST_TTL", 30*24*time.Hour))
+
+	// Вивантаження даних користувачів формується у фоні (Run запускається в main)
+	dataExportService := service.NewDataExportService(dataExportRepo, userRepo, likeRepo, orderRepo, loginAttemptRepo,
+		refreshTokenRepo, apiKeyRepo, userIdentityRepo, cartRepo, recommendationService, service.DataExportConfig{
+			Dir:          config.GetEnv("DATA_EXPORT_DIR", filepath.Join(os.TempDir(), "data-exports")),
+			TTL:          config.GetEnvDuration("DATA_EXPORT_TTL", 24*time.Hour),
+			PollInterval: config.GetEnvDuration("DATA_EXPORT_POLL_INTERVAL", 30*time.Second),
+			StaleAfter:   config.GetEnvDuration("DATA_EXPORT_STALE_AFTER", 30*time.Minute),
+		})
 
 	// Ініціалізуємо обробники
-	authHandler := handlers.NewAuthHandler(authService)
+	trustProxy := config.GetEnvBool("TRUST_PROXY_HEADERS", false)
+	authHandler := handlers.NewAuthHandler(authService, trustProxy)
+	oidcHandler := handlers.NewOIDCHandler(oidcService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	userHandler := handlers.NewUserHandler(userService, authService, trustProxy)
+	dataExportHandler := handlers.NewDataExportHandler(dataExportService)
 	productHandler := handlers.NewProductHandler(productService)
 	likeHandler := handlers.NewLikeHandler(likeService)
 	orderHandler := handlers.NewOrderHandler(orderService)
+	cartHandler := handlers.NewCartHandler(cartService)
 	recommendationHandler := handlers.NewRecommendationHandler(recommendationService)
+	jwksHandler := handlers.NewJWKSHandler(signingKeys)
 
 	// Створюємо контейнер
 	return &Container{
-		UserRepository:    userRepo,
-		ProductRepository: productRepo,
-		LikeRepository:    likeRepo,
-		OrderRepository:   orderRepo,
+		UserRepository:         userRepo,
+		ProductRepository:      productRepo,
+		LikeRepository:         likeRepo,
+		OrderRepository:        orderRepo,
+		RevokedTokenRepository: revokedTokenRepo,
+		RefreshTokenRepository: refreshTokenRepo,
+		UserTokenRepository:    userTokenRepo,
+		LoginAttemptRepository: loginAttemptRepo,
+		RecoveryCodeRepository: recoveryCodeRepo,
+		UserIdentityRepository: userIdentityRepo,
+		OIDCStateRepository:    oidcStateRepo,
+		APIKeyRepository:       apiKeyRepo,
+		DataExportRepository:   dataExportRepo,
+		CartRepository:         cartRepo,
+
+		TokenDenylist: tokenDenylist,
+		SigningKeys:   signingKeys,
+		Mailer:        mailer,
+		LoginThrottle: loginThrottle,
+
+		RecommendationCache: recommendationCache,
 
 		AuthService:           authService,
+		OIDCService:           oidcService,
+		APIKeyService:         apiKeyService,
+		UserService:           userService,
+		DataExportService:     dataExportService,
 		ProductService:        productService,
 		LikeService:           likeService,
 		OrderService:          orderService,
+		CartService:           cartService,
 		RecommendationService: recommendationService,
 
 		AuthHandler:           authHandler,
+		OIDCHandler:           oidcHandler,
+		APIKeyHandler:         apiKeyHandler,
+		UserHandler:           userHandler,
+		DataExportHandler:     dataExportHandler,
 		ProductHandler:        productHandler,
 		LikeHandler:           likeHandler,
 		OrderHandler:          orderHandler,
+		CartHandler:           cartHandler,
 		RecommendationHandler: recommendationHandler,
+		JWKSHandler:           jwksHandler,
 	}
 }
+
+// defaultJWTSecret секрет HS256 за замовчуванням; допустимий лише в режимі розробки
+const defaultJWTSecret = "your-secret-key"
+
+// loadSigningKeys завантажує ключі підпису токенів доступу. Якщо задано
+// JWT_SIGNING_KEYS (шляхи до закритих PEM-ключів через кому, перший - активний),
+// токени підписуються асиметрично, а JWT_VERIFY_KEYS додає відкриті ключі
+// виведених з обігу ключів. Інакше використовується HS256 з JWT_SECRET; секрет
+// за замовчуванням зупиняє запуск поза режимом розробки (APP_ENV).
+func loadSigningKeys() *jwtkeys.KeySet {
+	if paths := config.GetEnvList("JWT_SIGNING_KEYS"); len(paths) > 0 {
+		keys, err := jwtkeys.LoadKeySet(paths, config.GetEnvList("JWT_VERIFY_KEYS"))
+		if err != nil {
+			log.Fatalf("Failed to load JWT signing keys: %v", err)
+		}
+		log.Printf("JWT signing keys loaded, active key %s", keys.ActiveKeyID())
+		return keys
+	}
+
+	secret := config.GetEnv("JWT_SECRET", defaultJWTSecret)
+	if secret == defaultJWTSecret {
+		if !config.IsDevelopment() {
+			log.Fatalf("JWT_SECRET is not set: configure JWT_SIGNING_KEYS or JWT_SECRET, or set APP_ENV=development")
+		}
+		log.Printf("Using default JWT secret; do not use it outside development")
+	}
+	return jwtkeys.NewHMACKeySet([]byte(secret))
+}
+
+// loadTOTPSecrets створює ключ шифрування секретів TOTP з TOTP_ENCRYPTION_KEY
+// (32 байти в Base64). Без ключа сервер запускається лише в режимі розробки
+// з відомим усім ключем.
+func loadTOTPSecrets() *secretbox.Box {
+	var key []byte
+	if encoded := config.GetEnv("TOTP_ENCRYPTION_KEY", ""); encoded != "" {
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			log.Fatalf("TOTP_ENCRYPTION_KEY must be base64: %v", err)
+		}
+		key = decoded
+	} else {
+		if !config.IsDevelopment() {
+			log.Fatalf("TOTP_ENCRYPTION_KEY is not set: generate one with `openssl rand -base64 32`, or set APP_ENV=development")
+		}
+		log.Printf("Using default TOTP encryption key; do not use it outside development")
+		sum := sha256.Sum256([]byte(defaultJWTSecret))
+		key = sum[:]
+	}
+
+	box, err := secretbox.New(key)
+	if err != nil {
+		log.Fatalf("Invalid TOTP_ENCRYPTION_KEY: %v", err)
+	}
+	return box
+}
+
+// loadOIDCProviders налаштовує постачальників ідентичності з OIDC_PROVIDERS (назви
+// через кому). Для кожної назви NAME читаються OIDC_NAME_ISSUER, OIDC_NAME_CLIENT_ID,
+// OIDC_NAME_CLIENT_SECRET, OIDC_NAME_REDIRECT_URL та необов'язкова OIDC_NAME_SCOPES.
+func loadOIDCProviders() []*oidc.Provider {
+	names := config.GetEnvList("OIDC_PROVIDERS")
+	providers := make([]*oidc.Provider, 0, len(names))
+
+	for _, name := range names {
+		name = strings.ToLower(name)
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+
+		cfg := oidc.Config{
+			Name:         name,
+			Issuer:       config.GetEnv(prefix+"ISSUER", ""),
+			ClientID:     config.GetEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: config.GetEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  config.GetEnv(prefix+"REDIRECT_URL", ""),
+			Scopes:       config.GetEnvList(prefix + "SCOPES"),
+		}
+		if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+			log.Fatalf("OIDC provider %q requires %sISSUER, %sCLIENT_ID and %sREDIRECT_URL", name, prefix, prefix, prefix)
+		}
+
+		providers = append(providers, oidc.NewProvider(cfg))
+		log.Printf("OIDC provider %q configured (issuer %s)", name, cfg.Issuer)
+	}
+	return providers
+}
+
+// newMailer створює Mailer відповідно до MAILER: "log" (за замовчуванням) записує
+// листи в журнал, "smtp" надсилає їх через SMTP_HOST
+func newMailer() mail.Mailer {
+	switch driver := config.GetEnv("MAILER", "log"); driver {
+	case "smtp":
+		return mail.NewSMTPMailer(mail.SMTPConfig{
+			Host:     config.GetEnv("SMTP_HOST", "localhost"),
+			Port:     config.GetEnvInt("SMTP_PORT", 587),
+			Username: config.GetEnv("SMTP_USERNAME", ""),
+			Password: config.GetEnv("SMTP_PASSWORD", ""),
+			From:     config.GetEnv("MAIL_FROM", "no-reply@localhost"),
+			Timeout:  config.GetEnvDuration("SMTP_TIMEOUT", 30*time.Second),
+		})
+	case "log":
+		if !config.IsDevelopment() {
+			log.Printf("MAILER=log writes password reset links to the log; configure MAILER=smtp outside development")
+		}
+		return mail.NewLogMailer()
+	default:
+		log.Fatalf("Unknown MAILER driver %q", driver)
+		return nil
+	}
+}
+
+// newLoginFailureStore створює сховище лічильників невдалих входів відповідно до
+// LOGIN_ATTEMPT_STORE: "database" (за замовчуванням) - спільне для всіх екземплярів,
+// "memory" - окреме для кожного процесу
+func newLoginFailureStore(db *gorm.DB) repository.LoginFailureStore {
+	switch driver := config.GetEnv("LOGIN_ATTEMPT_STORE", "database"); driver {
+	case "database":
+		return repository.NewDBLoginFailureStore(db)
+	case "memory":
+		return repository.NewMemoryLoginFailureStore()
+	default:
+		log.Fatalf("Unknown LOGIN_ATTEMPT_STORE %q", driver)
+		return nil
+	}
+}
+
+// newRecommendationCache створює кеш рекомендацій відповідно до RECOMMENDATION_CACHE:
+// "memory" (за замовчуванням) - LRU у пам'яті процесу, "redis" - сервер Redis,
+// "off" - кешування вимкнене
+func newRecommendationCache() cache.RecommendationCache {
+	ttl := config.GetEnvDuration("RECOMMENDATION_CACHE_TTL", 10*time.Minute)
+
+	switch driver := config.GetEnv("RECOMMENDATION_CACHE", "memory"); driver {
+	case "off":
+		return nil
+	case "redis":
+		return cache.NewRedisCache(cache.RedisConfig{
+			Addr:     config.GetEnv("REDIS_ADDR", "localhost:6379"),
+			Password: config.GetEnv("REDIS_PASSWORD", ""),
+			DB:       config.GetEnvInt("REDIS_DB", 0),
+			Prefix:   config.GetEnv("RECOMMENDATION_CACHE_PREFIX", "recs"),
+			TTL:      ttl,
+		})
+	case "memory":
+		return cache.NewLRUCache(config.GetEnvInt("RECOMMENDATION_CACHE_SIZE", 10000), ttl)
+	default:
+		log.Printf("Unknown RECOMMENDATION_CACHE driver %q, caching disabled", driver)
+		return nil
+	}
+}
+
+// loadModelSnapshot завантажує знімок моделі з шляху MODEL_SNAPSHOT.
+// Повертає nil, якщо змінна не задана. Пошкоджений знімок зупиняє запуск,
+// щоб екземпляр не працював мовчки без очікуваної моделі.
+func loadModelSnapshot() *snapshot.Snapshot {
+	path := config.GetEnv("MODEL_SNAPSHOT", "")
+	if path == "" {
+		return nil
+	}
+
+	model, err := snapshot.Load(path)
+	if err != nil {
+		log.Fatalf("Failed to load model snapshot %s: %v", path, err)
+	}
+
+	log.Printf("Model snapshot %q loaded from %s (created %s)",
+		model.ModelVersion, path, model.CreatedAt.Format(time.RFC3339))
+	return model
+}
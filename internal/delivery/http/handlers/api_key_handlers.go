@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"log"
+	"net/http"
+	"product-recommendations-go/internal/delivery/http/middleware"
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/service"
+	"strconv"
+	"time"
+)
+
+// APIKeyHandler реалізує керування API ключами користувача
+type APIKeyHandler struct {
+	apiKeyService service.APIKeyService
+}
+
+// NewAPIKeyHandler створює новий обробник API ключів
+func NewAPIKeyHandler(apiKeyService service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+type createAPIKeyRequest struct {
+	Name      string         `json:"name"`
+	Scopes    []models.Scope `json:"scopes"`
+	ExpiresAt *time.Time     `json:"expires_at"`
+}
+
+type apiKeyResponse struct {
+	*models.APIKey
+	Scopes []models.Scope `json:"scopes"`
+}
+
+type createAPIKeyResponse struct {
+	apiKeyResponse
+	// Key повний ключ; показується лише один раз
+	Key string `json:"key"`
+}
+
+func newAPIKeyResponse(key *models.APIKey) apiKeyResponse {
+	return apiKeyResponse{APIKey: key, Scopes: key.ScopeList()}
+}
+
+// Create створює API ключ. Повний ключ повертається лише у цій відповіді.
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req createAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.apiKeyService.Create(r.Context(), userID, service.APIKeyInput{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	}, middleware.TwoFactorFromContext(r.Context()))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidAPIKeyInput):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrScopeNotPermitted), errors.Is(err, service.ErrTwoFactorRequired):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			log.Printf("Failed to create API key: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeNoStoreJSON(w, http.StatusCreated, createAPIKeyResponse{
+		apiKeyResponse: newAPIKeyResponse(created.APIKey),
+		Key:            created.Key,
+	})
+}
+
+// List повертає ключі користувача (без самих ключів)
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keys, err := h.apiKeyService.List(r.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to list API keys: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]apiKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		response = append(response, newAPIKeyResponse(key))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		APIKeys []apiKeyResponse `json:"api_keys"`
+	}{APIKeys: response}); err != nil {
+		http.Error(w, "Error JSON encode", http.StatusInternalServerError)
+		log.Printf("Error JSON: %v", err)
+		return
+	}
+}
+
+// Revoke відкликає ключ користувача; ключ перестає діяти одразу
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.apiKeyService.Revoke(r.Context(), userID, uint(id)); err != nil {
+		if errors.Is(err, service.ErrAPIKeyNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to revoke API key: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
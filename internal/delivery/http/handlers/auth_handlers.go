@@ -3,21 +3,29 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
+	"math"
 	"net/http"
+	"product-recommendations-go/internal/delivery/http/middleware"
 	"product-recommendations-go/internal/models"
 	"product-recommendations-go/internal/service"
+	"strconv"
 )
 
 // AuthHandler реалізує обробку запитів аутентифікації
 type AuthHandler struct {
 	authService service.AuthService
+	// trustProxy визначати адресу клієнта за заголовком X-Forwarded-For
+	trustProxy bool
 }
 
-// NewAuthHandler створює новий обробник для аутентифікації
-func NewAuthHandler(authService service.AuthService) *AuthHandler {
+// NewAuthHandler створює новий обробник для аутентифікації.
+// trustProxy вмикається лише за зворотним проксі, що встановлює X-Forwarded-For.
+func NewAuthHandler(authService service.AuthService, trustProxy bool) *AuthHandler {
 	return &AuthHandler{
 		authService: authService,
+		trustProxy:  trustProxy,
 	}
 }
 
@@ -31,10 +39,40 @@ type loginRequest struct {
 	Password string `json:"password"`
 }
 
-type authResponse struct {
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type verifyEmailRequest struct {
 	Token string `json:"token"`
 }
 
+type forgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type authResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	// ExpiresIn термін дії токена доступу в секундах
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+func newAuthResponse(tokens *service.TokenPair) authResponse {
+	return authResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+	}
+}
+
 // Register обробляє запит на реєстрацію нового користувача
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req registerRequest
@@ -69,18 +107,79 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.authService.Login(r.Context(), req.Email, req.Password)
+	result, err := h.authService.Login(r.Context(), req.Email, req.Password, clientInfo(r, h.trustProxy))
 	if err != nil {
+		writeLoginError(w, err)
+		return
+	}
+
+	// З увімкненою двофакторною автентифікацією вхід завершується на /auth/login/2fa
+	if result.Challenge != nil {
+		writeChallengeResponse(w, result.Challenge)
+		return
+	}
+
+	writeAuthResponse(w, result.Tokens)
+}
+
+// writeLoginError відповідає на помилки входу: 429 з Retry-After під час
+// затримки чи блокування, 401 для неправильних облікових даних або коду
+func writeLoginError(w http.ResponseWriter, err error) {
+	if writeThrottledError(w, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, service.ErrInvalidCredentials),
+		errors.Is(err, service.ErrInvalidTwoFactorCode),
+		errors.Is(err, service.ErrInvalidChallenge):
 		http.Error(w, err.Error(), http.StatusUnauthorized)
+	default:
+		log.Printf("Login failed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// writeThrottledError відповідає 429 з Retry-After, якщо спробу відхилено
+// обмеженнями входу, і повертає false для інших помилок
+func writeThrottledError(w http.ResponseWriter, err error) bool {
+	var throttled *service.LoginThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+	seconds := int(math.Ceil(throttled.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+	http.Error(w, err.Error(), http.StatusTooManyRequests)
+	return true
+}
+
+// Refresh обмінює refresh токен на нову пару токенів. Кожен refresh токен
+// можна використати лише один раз.
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	response := authResponse{
-		Token: token,
+	tokens, err := h.authService.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) || errors.Is(err, service.ErrRefreshTokenReused) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		log.Printf("Token refresh failed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
 
+	writeAuthResponse(w, tokens)
+}
+
+func writeAuthResponse(w http.ResponseWriter, tokens *service.TokenPair) {
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	// Відповідь містить облікові дані, тому не повинна кешуватися
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(newAuthResponse(tokens)); err != nil {
 		http.Error(w, "Error JSON decode", http.StatusInternalServerError)
 		log.Printf("Error JSON: %v", err)
 		return
@@ -113,3 +212,114 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Error in response: %v", err)
 	}
 }
+
+// VerifyEmail підтверджує адресу електронної пошти за токеном з листа
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req verifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.VerifyEmail(r.Context(), req.Token); err != nil {
+		writeUserTokenError(w, err)
+		return
+	}
+
+	writeMessage(w, http.StatusOK, "Email verified successfully")
+}
+
+// ResendVerification повторно надсилає лист підтвердження адреси автентифікованому користувачу
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err := h.authService.ResendVerification(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrEmailAlreadyVerified) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("Resending verification email failed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeMessage(w, http.StatusAccepted, "Verification email sent")
+}
+
+// ForgotPassword надсилає лист для скидання пароля. Відповідь однакова для
+// зареєстрованих і невідомих адрес.
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req forgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.ForgotPassword(r.Context(), req.Email); err != nil {
+		log.Printf("Password reset request failed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeMessage(w, http.StatusAccepted, "If the email is registered, a password reset link has been sent")
+}
+
+// ResetPassword встановлює новий пароль за токеном з листа
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req resetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.ResetPassword(r.Context(), req.Token, req.Password); err != nil {
+		writeUserTokenError(w, err)
+		return
+	}
+
+	writeMessage(w, http.StatusOK, "Password has been reset")
+}
+
+// ConfirmEmailChange замінює адресу електронної пошти на нову за токеном з листа
+func (h *AuthHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var req verifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.ConfirmEmailChange(r.Context(), req.Token); err != nil {
+		if errors.Is(err, service.ErrEmailTaken) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		writeUserTokenError(w, err)
+		return
+	}
+
+	writeMessage(w, http.StatusOK, "Email changed successfully")
+}
+
+// writeUserTokenError відповідає на помилки підтвердження адреси та скидання пароля
+func writeUserTokenError(w http.ResponseWriter, err error) {
+	if errors.Is(err, service.ErrInvalidUserToken) || errors.Is(err, service.ErrWeakPassword) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("User token request failed: %v", err)
+	http.Error(w, "Internal server error", http.StatusInternalServerError)
+}
+
+// writeMessage записує відповідь вигляду {"message": "..."}
+func writeMessage(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]string{"message": message}); err != nil {
+		log.Printf("Error in response: %v", err)
+	}
+}
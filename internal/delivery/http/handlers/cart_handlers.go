@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"log"
+	"net/http"
+	"product-recommendations-go/internal/delivery/http/middleware"
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/service"
+	"strconv"
+	"time"
+)
+
+// CartTokenHeader заголовок з токеном кошика гостя
+const CartTokenHeader = "X-Cart-Token"
+
+// CartHandler реалізує обробку запитів кошика покупок. Кошиком користуються як
+// автентифіковані користувачі, так і гості (за токеном кошика в CartTokenHeader).
+type CartHandler struct {
+	cartService service.CartService
+}
+
+// NewCartHandler створює новий обробник кошика
+func NewCartHandler(cartService service.CartService) *CartHandler {
+	return &CartHandler{
+		cartService: cartService,
+	}
+}
+
+type cartItemRequest struct {
+	ProductID uint `json:"product_id"`
+	Quantity  int  `json:"quantity"`
+}
+
+type cartLineResponse struct {
+	ProductID    uint      `json:"product_id"`
+	Name         string    `json:"name,omitempty"`
+	ImageURL     string    `json:"image_url,omitempty"`
+	Quantity     int       `json:"quantity"`
+	Price        float64   `json:"price"`
+	CurrentPrice float64   `json:"current_price"`
+	Available    bool      `json:"available"`
+	InStock      bool      `json:"in_stock"`
+	PriceChanged bool      `json:"price_changed"`
+	AddedAt      time.Time `json:"added_at"`
+}
+
+type cartResponse struct {
+	// CartToken токен нового кошика гостя; клієнт передає його в наступних запитах
+	CartToken string             `json:"cart_token,omitempty"`
+	Items     []cartLineResponse `json:"items"`
+	Total     float64            `json:"total"`
+	// Changed ціни змінилися або товари недоступні: перед оформленням потрібно оновити ціни
+	Changed   bool       `json:"changed"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// Get повертає вміст кошика
+func (h *CartHandler) Get(w http.ResponseWriter, r *http.Request) {
+	cart, err := h.cartService.Get(r.Context(), cartOwner(r))
+	if err != nil {
+		writeCartError(w, err)
+		return
+	}
+	writeCart(w, http.StatusOK, cart)
+}
+
+// AddItem додає товар до кошика (кількість за замовчуванням 1). Перший товар гостя
+// створює кошик, токен якого повертається у відповіді.
+func (h *CartHandler) AddItem(w http.ResponseWriter, r *http.Request) {
+	var req cartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+
+	cart, err := h.cartService.AddItem(r.Context(), cartOwner(r), req.ProductID, req.Quantity)
+	if err != nil {
+		writeCartError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if cart.Token != "" {
+		status = http.StatusCreated
+	}
+	writeCart(w, status, cart)
+}
+
+// UpdateItem змінює кількість товару в кошику
+func (h *CartHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseUint(mux.Vars(r)["product_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var req cartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	cart, err := h.cartService.UpdateItem(r.Context(), cartOwner(r), uint(productID), req.Quantity)
+	if err != nil {
+		writeCartError(w, err)
+		return
+	}
+	writeCart(w, http.StatusOK, cart)
+}
+
+// RemoveItem видаляє товар з кошика
+func (h *CartHandler) RemoveItem(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseUint(mux.Vars(r)["product_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	cart, err := h.cartService.RemoveItem(r.Context(), cartOwner(r), uint(productID))
+	if err != nil {
+		writeCartError(w, err)
+		return
+	}
+	writeCart(w, http.StatusOK, cart)
+}
+
+// RefreshPrices приймає поточні ціни каталогу і прибирає недоступні товари
+func (h *CartHandler) RefreshPrices(w http.ResponseWriter, r *http.Request) {
+	cart, err := h.cartService.RefreshPrices(r.Context(), cartOwner(r))
+	if err != nil {
+		writeCartError(w, err)
+		return
+	}
+	writeCart(w, http.StatusOK, cart)
+}
+
+// Checkout оформлює замовлення з кошика. Потрібна автентифікація; якщо ціни
+// змінилися, повертається 409 з актуальним кошиком.
+func (h *CartHandler) Checkout(w http.ResponseWriter, r *http.Request) {
+	owner := cartOwner(r)
+	order, err := h.cartService.Checkout(r.Context(), owner)
+	if err != nil {
+		if errors.Is(err, service.ErrCartChanged) {
+			cart, getErr := h.cartService.Get(r.Context(), owner)
+			if getErr == nil {
+				writeCart(w, http.StatusConflict, cart)
+				return
+			}
+		}
+		writeCartError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(order); err != nil {
+		log.Printf("Error JSON: %v", err)
+	}
+}
+
+// GetRecommendations повертає рекомендації до вмісту кошика; параметри ті самі,
+// що й у персональних рекомендацій
+func (h *CartHandler) GetRecommendations(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	filter, err := parseRecommendationFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recommendations, err := h.cartService.Recommendations(r.Context(), cartOwner(r), limit, filter)
+	if err != nil {
+		writeCartError(w, err)
+		return
+	}
+
+	// Переконуємося, що повертаємо порожній масив, а не null
+	if recommendations == nil {
+		recommendations = []*models.ProductRecommendation{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Recommendations []*models.ProductRecommendation `json:"recommendations"`
+	}{
+		Recommendations: recommendations,
+	}); err != nil {
+		log.Printf("Error JSON: %v", err)
+	}
+}
+
+// cartOwner визначає власника кошика: автентифікованого користувача (якщо є) і
+// токен кошика гостя з заголовка запиту
+func cartOwner(r *http.Request) service.CartOwner {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	return service.CartOwner{
+		UserID:     userID,
+		GuestToken: r.Header.Get(CartTokenHeader),
+	}
+}
+
+func writeCartError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrCheckoutUnauthenticated):
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	case errors.Is(err, service.ErrInvalidQuantity),
+		errors.Is(err, service.ErrCartFull),
+		errors.Is(err, service.ErrCartEmpty):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrProductNotFound),
+		errors.Is(err, service.ErrCartItemNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrCartChanged),
+		errors.Is(err, service.ErrInsufficientStock):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Cart request failed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func writeCart(w http.ResponseWriter, status int, cart *service.CartView) {
+	response := cartResponse{
+		CartToken: cart.Token,
+		Items:     make([]cartLineResponse, 0, len(cart.Lines)),
+		Total:     cart.Total,
+		Changed:   cart.Changed,
+		UpdatedAt: cart.UpdatedAt,
+	}
+	for _, line := range cart.Lines {
+		item := cartLineResponse{
+			ProductID:    line.ProductID,
+			Quantity:     line.Quantity,
+			Price:        line.Price,
+			CurrentPrice: line.CurrentPrice,
+			Available:    line.Product != nil,
+			AddedAt:      line.AddedAt,
+		}
+		if line.Product != nil {
+			item.Name = line.Product.Name
+			item.ImageURL = line.Product.ImageURL
+			item.PriceChanged = line.CurrentPrice != line.Price
+			item.InStock = line.Product.Stock >= line.Quantity
+		}
+		response.Items = append(response.Items, item)
+	}
+
+	if cart.Token != "" {
+		w.Header().Set(CartTokenHeader, cart.Token)
+	}
+	writeNoStoreJSON(w, status, response)
+}
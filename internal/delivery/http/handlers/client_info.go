@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"product-recommendations-go/internal/service"
+	"strings"
+)
+
+// clientInfo повертає адресу та User-Agent клієнта. Заголовок X-Forwarded-For
+// враховується лише за trustProxy: без зворотного проксі, що перезаписує його,
+// клієнт може підставити будь-яку адресу і обійти обмеження за IP.
+func clientInfo(r *http.Request, trustProxy bool) service.ClientInfo {
+	return service.ClientInfo{
+		IP:        clientIP(r, trustProxy),
+		UserAgent: r.UserAgent(),
+	}
+}
+
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		// Найближчий до нас проксі додає адресу свого клієнта в кінець списку
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			parts := strings.Split(forwarded, ",")
+			if ip := net.ParseIP(strings.TrimSpace(parts[len(parts)-1])); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"product-recommendations-go/internal/delivery/http/middleware"
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/service"
+	"time"
+)
+
+const (
+	// exportRetryAfter через скільки секунд клієнту варто повторити запит, поки архів формується
+	exportRetryAfter = "10"
+	// exportWriteTimeout час на завантаження архіву
+	exportWriteTimeout = 10 * time.Minute
+)
+
+// DataExportHandler реалізує вивантаження даних користувача
+type DataExportHandler struct {
+	exportService service.DataExportService
+}
+
+// NewDataExportHandler створює новий обробник вивантаження даних
+func NewDataExportHandler(exportService service.DataExportService) *DataExportHandler {
+	return &DataExportHandler{
+		exportService: exportService,
+	}
+}
+
+// Export повертає архів з даними користувача. Архів формується у фоні: перший
+// запит ставить його в чергу і отримує 202, повторні - 202, доки архів не готовий,
+// а потім сам архів.
+func (h *DataExportHandler) Export(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	export, err := h.exportService.Request(r.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to request data export: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if export.Status != models.DataExportReady {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Retry-After", exportRetryAfter)
+		w.WriteHeader(http.StatusAccepted)
+		if err := json.NewEncoder(w).Encode(export); err != nil {
+			log.Printf("Error JSON: %v", err)
+		}
+		return
+	}
+
+	file, err := h.exportService.Open(export)
+	if err != nil {
+		log.Printf("Failed to open data export %d: %v", export.ID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	// Великий архів завантажується довше за WriteTimeout сервера
+	controller := http.NewResponseController(w)
+	if err := controller.SetWriteDeadline(time.Now().Add(exportWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("Failed to extend write deadline: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="data-export-`+export.CreatedAt.Format("2006-01-02")+`.zip"`)
+	w.Header().Set("Cache-Control", "no-store")
+	http.ServeContent(w, r, "", *export.CompletedAt, file)
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"product-recommendations-go/internal/jwtkeys"
+)
+
+// jwksMaxAge час кешування набору ключів клієнтами; новий ключ слід додавати в
+// JWT_VERIFY_KEYS інших екземплярів щонайменше на цей час раніше, ніж він стане активним
+const jwksMaxAge = "300"
+
+// JWKSHandler публікує відкриті ключі перевірки токенів доступу
+type JWKSHandler struct {
+	keys *jwtkeys.KeySet
+}
+
+// NewJWKSHandler створює новий обробник набору відкритих ключів
+func NewJWKSHandler(keys *jwtkeys.KeySet) *JWKSHandler {
+	return &JWKSHandler{
+		keys: keys,
+	}
+}
+
+// GetJWKS повертає відкриті ключі у форматі JWKS (RFC 7517). Ключі HS256 не
+// публікуються, тому при підписі спільним секретом набір порожній.
+func (h *JWKSHandler) GetJWKS(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age="+jwksMaxAge)
+	if err := json.NewEncoder(w).Encode(h.keys.JWKS()); err != nil {
+		http.Error(w, "Error JSON decode", http.StatusInternalServerError)
+		log.Printf("Error JSON: %v", err)
+		return
+	}
+}
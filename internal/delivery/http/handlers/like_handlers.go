@@ -5,6 +5,7 @@ import (
 	"github.com/gorilla/mux"
 	"log"
 	"net/http"
+	"product-recommendations-go/internal/delivery/http/middleware"
 	"product-recommendations-go/internal/service"
 	"strconv"
 )
@@ -32,7 +33,7 @@ func (h *LikeHandler) LikeProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Отримуємо ID користувача з контексту (встановлений middleware аутентифікації)
-	userID, ok := r.Context().Value("user_id").(uint)
+	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -64,7 +65,7 @@ func (h *LikeHandler) UnlikeProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Отримуємо ID користувача з контексту (встановлений middleware аутентифікації)
-	userID, ok := r.Context().Value("user_id").(uint)
+	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -88,7 +89,7 @@ func (h *LikeHandler) UnlikeProduct(w http.ResponseWriter, r *http.Request) {
 // GetUserLikes повертає всі лайкнуті продукти користувача
 func (h *LikeHandler) GetUserLikes(w http.ResponseWriter, r *http.Request) {
 	// Отримуємо ID користувача з контексту (встановлений middleware аутентифікації)
-	userID, ok := r.Context().Value("user_id").(uint)
+	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"log"
+	"net/http"
+	"path"
+	"product-recommendations-go/internal/service"
+)
+
+// OIDCHandler реалізує вхід через зовнішніх постачальників ідентичності
+type OIDCHandler struct {
+	oidcService service.OIDCService
+}
+
+// NewOIDCHandler створює новий обробник входу через постачальників ідентичності
+func NewOIDCHandler(oidcService service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: oidcService,
+	}
+}
+
+type providersResponse struct {
+	Providers []string `json:"providers"`
+}
+
+// GetProviders повертає назви налаштованих постачальників
+func (h *OIDCHandler) GetProviders(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(providersResponse{Providers: h.oidcService.Providers()}); err != nil {
+		http.Error(w, "Error JSON decode", http.StatusInternalServerError)
+		log.Printf("Error JSON: %v", err)
+		return
+	}
+}
+
+// oidcStateCookie cookie, що прив'язує незавершений вхід до браузера
+const oidcStateCookie = "oidc_state"
+
+// Login перенаправляє користувача на сторінку входу постачальника
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	authURL, binding, err := h.oidcService.AuthURL(r.Context(), mux.Vars(r)["provider"])
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownProvider) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to start OIDC login: %v", err)
+		http.Error(w, "Identity provider is unavailable", http.StatusBadGateway)
+		return
+	}
+
+	// Lax: cookie надсилається під час перенаправлення від постачальника назад
+	setOIDCStateCookie(w, r, binding, 0)
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback завершує вхід після повернення від постачальника. Відповідь така сама,
+// як у /auth/login: пара токенів або токен другого кроку входу.
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	// Якщо користувач відмовився від входу, постачальник повертає error замість code;
+	// порожній код завершує вхід помилкою і використовує state
+	code := query.Get("code")
+	if query.Get("error") != "" {
+		code = ""
+	}
+
+	var binding string
+	if cookie, err := r.Cookie(oidcStateCookie); err == nil {
+		binding = cookie.Value
+	}
+	// Стан одноразовий, тому cookie більше не потрібна
+	setOIDCStateCookie(w, r, "", -1)
+
+	result, err := h.oidcService.Callback(r.Context(), mux.Vars(r)["provider"], query.Get("state"), binding, code)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownProvider):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, service.ErrInvalidOIDCState):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrExternalLoginFailed),
+			errors.Is(err, service.ErrExternalEmailNotVerified):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, service.ErrAccountLinkNotAllowed):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			log.Printf("OIDC callback failed: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// З увімкненою двофакторною автентифікацією вхід завершується на /auth/login/2fa
+	if result.Challenge != nil {
+		writeChallengeResponse(w, result.Challenge)
+		return
+	}
+
+	writeAuthResponse(w, result.Tokens)
+}
+
+// setOIDCStateCookie встановлює (або, з maxAge < 0, видаляє) cookie прив'язки входу.
+// Cookie доступна лише маршрутам постачальника: login і callback мають спільний префікс.
+func setOIDCStateCookie(w http.ResponseWriter, r *http.Request, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     path.Dir(r.URL.Path) + "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -2,8 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/gorilla/mux"
+	"io"
+	"log"
 	"net/http"
+	"product-recommendations-go/internal/delivery/http/middleware"
 	"product-recommendations-go/internal/models"
 	"product-recommendations-go/internal/service"
 	"strconv"
@@ -24,7 +28,7 @@ func NewOrderHandler(orderService service.OrderService) *OrderHandler {
 // CreateOrder обробляє створення нового замовлення
 func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	// Отримуємо ID користувача з контексту (встановлений middleware аутентифікації)
-	userID, ok := r.Context().Value("user_id").(uint)
+	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -46,7 +50,6 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	// Створюємо об'єкт замовлення
 	order := &models.Order{
 		UserID: userID,
-		Status: "pending",
 		Items:  make([]models.OrderItem, len(requestOrder.Items)),
 	}
 
@@ -60,7 +63,11 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 
 	// Створюємо замовлення
 	if err := h.orderService.CreateOrder(r.Context(), order); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrInsufficientStock) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
 		return
 	}
 
@@ -83,7 +90,7 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 // GetOrderByID повертає замовлення за ID
 func (h *OrderHandler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 	// Отримуємо ID користувача з контексту (встановлений middleware аутентифікації)
-	userID, ok := r.Context().Value("user_id").(uint)
+	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -116,7 +123,7 @@ func (h *OrderHandler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 // GetUserOrders повертає всі замовлення користувача
 func (h *OrderHandler) GetUserOrders(w http.ResponseWriter, r *http.Request) {
 	// Отримуємо ID користувача з контексту (встановлений middleware аутентифікації)
-	userID, ok := r.Context().Value("user_id").(uint)
+	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -141,3 +148,89 @@ func (h *OrderHandler) GetUserOrders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+type orderStatusRequest struct {
+	Status models.OrderStatus `json:"status"`
+	Reason string             `json:"reason"`
+}
+
+// CancelOrder скасовує неоплачене замовлення користувача. Тіло запиту
+// необов'язкове: {"reason": "..."}
+func (h *OrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	var req orderStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.orderService.CancelOrder(r.Context(), uint(id), userID, req.Reason)
+	if err != nil {
+		writeOrderStatusError(w, err)
+		return
+	}
+	writeOrder(w, order)
+}
+
+// UpdateStatus змінює статус замовлення (адміністративний маршрут):
+// {"status": "paid", "reason": "..."}
+func (h *OrderHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	var req orderStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.orderService.UpdateStatus(r.Context(), uint(id), req.Status, adminID, req.Reason)
+	if err != nil {
+		writeOrderStatusError(w, err)
+		return
+	}
+
+	log.Printf("Order %d status changed to %q by user %d", order.ID, order.Status, adminID)
+	writeOrder(w, order)
+}
+
+func writeOrderStatusError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrOrderNotFound), errors.Is(err, service.ErrOrderAccessDenied):
+		http.Error(w, service.ErrOrderNotFound.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidOrderStatus):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrInvalidStatusTransition):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Order status change failed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func writeOrder(w http.ResponseWriter, order *models.Order) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(order); err != nil {
+		log.Printf("Error JSON: %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/gorilla/mux"
 	"log"
 	"net/http"
@@ -87,3 +88,178 @@ func (h *ProductHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// maxProductBodySize обмеження розміру тіла запиту на створення/оновлення товару
+const maxProductBodySize = 64 << 10
+
+// Create створює новий товар (адміністративний маршрут)
+func (h *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
+	input, ok := decodeProductInput(w, r)
+	if !ok {
+		return
+	}
+
+	product, err := h.productService.Create(r.Context(), input)
+	if err != nil {
+		writeProductError(w, err)
+		return
+	}
+
+	writeProduct(w, http.StatusCreated, product)
+}
+
+// Update повністю оновлює дані товару (адміністративний маршрут)
+func (h *ProductHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := productIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	input, ok := decodeProductInput(w, r)
+	if !ok {
+		return
+	}
+
+	product, err := h.productService.Update(r.Context(), id, input)
+	if err != nil {
+		writeProductError(w, err)
+		return
+	}
+
+	writeProduct(w, http.StatusOK, product)
+}
+
+// Delete м'яко видаляє товар з каталогу (адміністративний маршрут)
+func (h *ProductHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := productIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.productService.Delete(r.Context(), id); err != nil {
+		writeProductError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Restore відновлює м'яко видалений товар (адміністративний маршрут)
+func (h *ProductHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	id, ok := productIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	product, err := h.productService.Restore(r.Context(), id)
+	if err != nil {
+		writeProductError(w, err)
+		return
+	}
+
+	writeProduct(w, http.StatusOK, product)
+}
+
+// AdjustStock змінює залишок товару: {"stock": n} встановлює значення,
+// {"delta": n} додає або списує (адміністративний маршрут)
+func (h *ProductHandler) AdjustStock(w http.ResponseWriter, r *http.Request) {
+	id, ok := productIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var adjustment service.StockAdjustment
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxProductBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&adjustment); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	product, err := h.productService.AdjustStock(r.Context(), id, adjustment)
+	if err != nil {
+		writeProductError(w, err)
+		return
+	}
+
+	writeProduct(w, http.StatusOK, product)
+}
+
+// GetLowStock повертає товари з малим залишком (адміністративний маршрут).
+// Параметр threshold перевизначає поріг за замовчуванням.
+func (h *ProductHandler) GetLowStock(w http.ResponseWriter, r *http.Request) {
+	threshold := -1
+	if value := r.URL.Query().Get("threshold"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, "Invalid threshold", http.StatusBadRequest)
+			return
+		}
+		threshold = parsed
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	products, err := h.productService.GetLowStock(r.Context(), threshold, limit)
+	if err != nil {
+		writeProductError(w, err)
+		return
+	}
+
+	// Переконуємося, що повертаємо порожній масив, а не null
+	if products == nil {
+		products = []*models.Product{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Products []*models.Product `json:"products"`
+	}{
+		Products: products,
+	}); err != nil {
+		log.Println("Error JSON encode:", err)
+	}
+}
+
+func productIDFromPath(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil || id == 0 {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func decodeProductInput(w http.ResponseWriter, r *http.Request) (models.ProductInput, bool) {
+	var input models.ProductInput
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxProductBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return input, false
+	}
+	return input, true
+}
+
+func writeProductError(w http.ResponseWriter, err error) {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrProductNotFound):
+		http.Error(w, "Product not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrInsufficientStock):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Product admin operation failed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func writeProduct(w http.ResponseWriter, status int, product *models.Product) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(product); err != nil {
+		log.Println("Error JSON encode:", err)
+	}
+}
@@ -2,10 +2,31 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"log"
+	"math"
 	"net/http"
+	"net/url"
+	"product-recommendations-go/internal/delivery/http/middleware"
 	"product-recommendations-go/internal/models"
 	"product-recommendations-go/internal/service"
 	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxBatchUsers максимальна кількість користувачів в одному пакетному запиті
+	maxBatchUsers = 10000
+	// maxBatchBodySize обмеження розміру тіла пакетного запиту; вистачає на maxBatchUsers ID та фільтр
+	maxBatchBodySize = 256 << 10
+	// defaultBatchConcurrency кількість користувачів, що обробляються паралельно за замовчуванням
+	defaultBatchConcurrency = 8
+	// maxBatchConcurrency верхня межа паралельності пакетного запиту
+	maxBatchConcurrency = 32
+	// batchWriteTimeout час на запис одного рядка відповіді пакетного запиту
+	batchWriteTimeout = 30 * time.Second
 )
 
 // RecommendationHandler реалізує обробку запитів рекомендацій
@@ -22,7 +43,7 @@ func NewRecommendationHandler(recommendationService service.RecommendationServic
 
 // GetRecommendations повертає рекомендації продуктів для користувача
 func (h *RecommendationHandler) GetRecommendations(w http.ResponseWriter, r *http.Request) {
-	userID, ok := r.Context().Value("user_id").(uint)
+	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -30,7 +51,13 @@ func (h *RecommendationHandler) GetRecommendations(w http.ResponseWriter, r *htt
 
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 
-	recommendations, err := h.recommendationService.GetRecommendations(r.Context(), userID, limit)
+	filter, err := parseRecommendationFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recommendations, err := h.recommendationService.GetRecommendations(r.Context(), userID, limit, filter)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -53,3 +80,186 @@ func (h *RecommendationHandler) GetRecommendations(w http.ResponseWriter, r *htt
 		return
 	}
 }
+
+// GetSimilarProducts повертає товари, схожі на вказаний; параметри фільтрації ті
+// самі, що й у персональних рекомендацій
+func (h *RecommendationHandler) GetSimilarProducts(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseUint(mux.Vars(r)["product_id"], 10, 32)
+	if err != nil || productID == 0 {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	filter, err := parseRecommendationFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recommendations, err := h.recommendationService.GetSimilarProducts(r.Context(), uint(productID), limit, filter)
+	if err != nil {
+		if errors.Is(err, service.ErrProductNotFound) {
+			http.Error(w, "Product not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Similar products request failed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Переконуємося, що повертаємо порожній масив, а не null
+	if recommendations == nil {
+		recommendations = []*models.ProductRecommendation{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Recommendations []*models.ProductRecommendation `json:"recommendations"`
+	}{
+		Recommendations: recommendations,
+	}); err != nil {
+		log.Printf("Error JSON: %v", err)
+	}
+}
+
+type batchRecommendationsRequest struct {
+	UserIDs     []uint                      `json:"user_ids"`
+	Limit       int                         `json:"limit"`
+	Concurrency int                         `json:"concurrency"`
+	Filter      models.RecommendationFilter `json:"filter"`
+}
+
+// GetBatchRecommendations повертає рекомендації для списку користувачів у форматі NDJSON.
+// Кожен рядок відповіді - окремий JSON-об'єкт з результатом одного користувача;
+// рядки надсилаються по мірі обчислення, тому їх порядок не збігається з порядком user_ids.
+func (h *RecommendationHandler) GetBatchRecommendations(w http.ResponseWriter, r *http.Request) {
+	var req batchRecommendationsRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodySize)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.UserIDs) == 0 {
+		http.Error(w, "user_ids must not be empty", http.StatusBadRequest)
+		return
+	}
+	if len(req.UserIDs) > maxBatchUsers {
+		http.Error(w, "too many user_ids", http.StatusBadRequest)
+		return
+	}
+	if err := validateRecommendationFilter(req.Filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	concurrency := req.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	if concurrency > maxBatchConcurrency {
+		concurrency = maxBatchConcurrency
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	// Відповідь може формуватися довше за WriteTimeout сервера,
+	// тому продовжуємо дедлайн перед записом кожного рядка
+	controller := http.NewResponseController(w)
+	encoder := json.NewEncoder(w)
+
+	err := h.recommendationService.GetBatchRecommendations(r.Context(), req.UserIDs, req.Limit, concurrency, req.Filter,
+		func(result *models.BatchRecommendationResult) error {
+			if err := controller.SetWriteDeadline(time.Now().Add(batchWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+			if result.Recommendations == nil && result.Error == "" {
+				result.Recommendations = []*models.ProductRecommendation{}
+			}
+			if err := encoder.Encode(result); err != nil {
+				return err
+			}
+			if err := controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+			return nil
+		})
+	if err != nil {
+		// Заголовки вже надіслані, тому помилку можна лише залогувати
+		log.Printf("Batch recommendations interrupted: %v", err)
+	}
+}
+
+// parseRecommendationFilter розбирає параметри фільтрації рекомендацій з рядка запиту:
+// category, min_price, max_price, exclude_ids (через кому) та include_purchased
+func parseRecommendationFilter(query url.Values) (models.RecommendationFilter, error) {
+	filter := models.RecommendationFilter{
+		Category: query.Get("category"),
+	}
+
+	if value := query.Get("min_price"); value != "" {
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return filter, errors.New("invalid min_price")
+		}
+		filter.MinPrice = &price
+	}
+
+	if value := query.Get("max_price"); value != "" {
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return filter, errors.New("invalid max_price")
+		}
+		filter.MaxPrice = &price
+	}
+
+	if value := query.Get("exclude_ids"); value != "" {
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32)
+			if err != nil {
+				return filter, errors.New("invalid exclude_ids")
+			}
+			filter.ExcludeIDs = append(filter.ExcludeIDs, uint(id))
+		}
+	}
+
+	if value := query.Get("include_purchased"); value != "" {
+		includePurchased, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New("invalid include_purchased")
+		}
+		filter.IncludePurchased = includePurchased
+	}
+
+	if value := query.Get("in_stock_only"); value != "" {
+		inStockOnly, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New("invalid in_stock_only")
+		}
+		filter.InStockOnly = inStockOnly
+	}
+
+	return filter, validateRecommendationFilter(filter)
+}
+
+// validateRecommendationFilter перевіряє межі ціни: скінченні, невід'ємні та узгоджені
+func validateRecommendationFilter(filter models.RecommendationFilter) error {
+	if filter.MinPrice != nil && (math.IsNaN(*filter.MinPrice) || math.IsInf(*filter.MinPrice, 0)) {
+		return errors.New("invalid min_price")
+	}
+	if filter.MaxPrice != nil && (math.IsNaN(*filter.MaxPrice) || math.IsInf(*filter.MaxPrice, 0)) {
+		return errors.New("invalid max_price")
+	}
+	if filter.MinPrice != nil && *filter.MinPrice < 0 {
+		return errors.New("min_price must not be negative")
+	}
+	if filter.MaxPrice != nil && *filter.MaxPrice < 0 {
+		return errors.New("max_price must not be negative")
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return errors.New("min_price must not exceed max_price")
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParseRecommendationFilterPrices(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+	}{
+		{query: "min_price=10&max_price=20"},
+		{query: "min_price=0"},
+		{query: "min_price=15&max_price=15"},
+		{query: "min_price=NaN", wantErr: true},
+		{query: "max_price=nan", wantErr: true},
+		{query: "min_price=Inf", wantErr: true},
+		{query: "max_price=-Inf", wantErr: true},
+		{query: "max_price=1e400", wantErr: true},
+		{query: "min_price=-1", wantErr: true},
+		{query: "max_price=-0.01", wantErr: true},
+		{query: "min_price=20&max_price=10", wantErr: true},
+		{query: "min_price=abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := parseRecommendationFilter(query); (err != nil) != tt.wantErr {
+				t.Errorf("parseRecommendationFilter(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			}
+		})
+	}
+}
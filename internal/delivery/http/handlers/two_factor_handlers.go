@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"product-recommendations-go/internal/delivery/http/middleware"
+	"product-recommendations-go/internal/service"
+)
+
+type twoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+type twoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type twoFactorDisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type challengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	// ExpiresIn час на введення коду в секундах
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+type twoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// LoginTwoFactor завершує вхід кодом з застосунку-автентифікатора або резервним кодом
+func (h *AuthHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req twoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ChallengeToken == "" || req.Code == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.authService.VerifyTwoFactor(r.Context(), req.ChallengeToken, req.Code, clientInfo(r, h.trustProxy))
+	if err != nil {
+		writeLoginError(w, err)
+		return
+	}
+
+	writeAuthResponse(w, tokens)
+}
+
+// SetupTwoFactor генерує секрет TOTP і повертає посилання otpauth:// для застосунку
+func (h *AuthHandler) SetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	setup, err := h.authService.SetupTwoFactor(r.Context(), userID)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	writeNoStoreJSON(w, http.StatusOK, twoFactorSetupResponse{
+		Secret:     setup.Secret,
+		OTPAuthURI: setup.URI,
+	})
+}
+
+// EnableTwoFactor вмикає двофакторну автентифікацію після підтвердження кодом
+// і повертає резервні коди (показуються лише один раз)
+func (h *AuthHandler) EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req twoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.authService.EnableTwoFactor(r.Context(), userID, req.Code, clientInfo(r, h.trustProxy))
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	writeNoStoreJSON(w, http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTwoFactor вимикає двофакторну автентифікацію; потрібні пароль і код
+func (h *AuthHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req twoFactorDisableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" || req.Code == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.DisableTwoFactor(r.Context(), userID, req.Password, req.Code, clientInfo(r, h.trustProxy)); err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes замінює резервні коди новими
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req twoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(r.Context(), userID, req.Code, clientInfo(r, h.trustProxy))
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	writeNoStoreJSON(w, http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// writeTwoFactorError відповідає на помилки керування двофакторною автентифікацією
+func writeTwoFactorError(w http.ResponseWriter, err error) {
+	if writeThrottledError(w, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, service.ErrTwoFactorAlreadyEnabled),
+		errors.Is(err, service.ErrTwoFactorNotEnabled),
+		errors.Is(err, service.ErrTwoFactorNotSetUp):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrInvalidTwoFactorCode),
+		errors.Is(err, service.ErrInvalidCredentials):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		log.Printf("Two-factor request failed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func writeChallengeResponse(w http.ResponseWriter, challenge *service.TwoFactorChallenge) {
+	writeNoStoreJSON(w, http.StatusOK, challengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    challenge.Token,
+		ExpiresIn:         int64(challenge.ExpiresIn.Seconds()),
+	})
+}
+
+// writeNoStoreJSON записує JSON відповідь з секретами, яку не можна кешувати
+func writeNoStoreJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Error JSON: %v", err)
+	}
+}
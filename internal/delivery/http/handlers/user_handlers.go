@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"product-recommendations-go/internal/delivery/http/middleware"
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/service"
+)
+
+// UserHandler реалізує обробку запитів профілю та облікового запису поточного користувача
+type UserHandler struct {
+	userService service.UserService
+	authService service.AuthService
+	// trustProxy визначати адресу клієнта за заголовком X-Forwarded-For
+	trustProxy bool
+}
+
+// NewUserHandler створює новий обробник профілю користувача.
+// trustProxy вмикається лише за зворотним проксі, що встановлює X-Forwarded-For.
+func NewUserHandler(userService service.UserService, authService service.AuthService, trustProxy bool) *UserHandler {
+	return &UserHandler{
+		userService: userService,
+		authService: authService,
+		trustProxy:  trustProxy,
+	}
+}
+
+type updateProfileRequest struct {
+	Name        *string             `json:"name"`
+	Locale      *string             `json:"locale"`
+	Preferences *models.Preferences `json:"preferences"`
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type changeEmailRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type deleteAccountRequest struct {
+	Password string `json:"password"`
+	// Code код TOTP або резервний код, якщо увімкнена двофакторна автентифікація
+	Code string `json:"code"`
+}
+
+// GetMe повертає профіль поточного користувача
+func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.userService.GetProfile(r.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to get profile: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeProfile(w, user)
+}
+
+// UpdateMe змінює поля профілю, передані в запиті
+func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req updateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userService.UpdateProfile(r.Context(), userID, service.ProfileInput{
+		Name:        req.Name,
+		Locale:      req.Locale,
+		Preferences: req.Preferences,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidProfile) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Failed to update profile: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeProfile(w, user)
+}
+
+// ChangePassword змінює пароль; усі сесії завершуються, у відповіді - нова пара токенів
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req changePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CurrentPassword == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.authService.ChangePassword(r.Context(), userID, req.CurrentPassword, req.NewPassword,
+		middleware.TwoFactorFromContext(r.Context()), clientInfo(r, h.trustProxy))
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+
+	writeAuthResponse(w, tokens)
+}
+
+// ChangeEmail надсилає посилання для підтвердження нової адреси; адреса змінюється після підтвердження
+func (h *UserHandler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req changeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" || req.Password == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.RequestEmailChange(r.Context(), userID, req.Email, req.Password, clientInfo(r, h.trustProxy)); err != nil {
+		writeAccountError(w, err)
+		return
+	}
+
+	writeMessage(w, http.StatusAccepted, "Confirmation link has been sent to the new email")
+}
+
+// DeleteMe видаляє обліковий запис поточного користувача
+func (h *UserHandler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req deleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.DeleteAccount(r.Context(), userID, req.Password, req.Code, clientInfo(r, h.trustProxy)); err != nil {
+		writeAccountError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeAccountError відповідає на помилки керування обліковим записом
+func writeAccountError(w http.ResponseWriter, err error) {
+	if writeThrottledError(w, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, service.ErrInvalidCredentials),
+		errors.Is(err, service.ErrInvalidTwoFactorCode):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrWeakPassword),
+		errors.Is(err, service.ErrInvalidEmail),
+		errors.Is(err, service.ErrSameEmail):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrEmailTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Account request failed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func writeProfile(w http.ResponseWriter, user *models.User) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		http.Error(w, "Error JSON encode", http.StatusInternalServerError)
+		log.Printf("Error JSON: %v", err)
+		return
+	}
+}
@@ -3,51 +3,168 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"log"
 	"net/http"
+	"product-recommendations-go/internal/models"
 	"product-recommendations-go/internal/service"
+	"slices"
 	"strings"
 )
 
+// contextKey тип ключів контексту запиту, щоб уникнути колізій з іншими пакетами
+type contextKey string
+
+// Ключі контексту, під якими зберігаються дані автентифікованого користувача
+const (
+	userIDKey    contextKey = "user_id"
+	roleKey      contextKey = "role"
+	twoFactorKey contextKey = "two_factor"
+	apiKeyIDKey  contextKey = "api_key_id"
+)
+
+// UserIDFromContext повертає ID автентифікованого користувача з контексту запиту
+func UserIDFromContext(ctx context.Context) (uint, bool) {
+	userID, ok := ctx.Value(userIDKey).(uint)
+	return userID, ok
+}
+
+// RoleFromContext повертає роль автентифікованого користувача з контексту запиту
+func RoleFromContext(ctx context.Context) (models.Role, bool) {
+	role, ok := ctx.Value(roleKey).(models.Role)
+	return role, ok
+}
+
+// TwoFactorFromContext повідомляє, чи відкрито сесію користувача з підтвердженням другого фактора
+func TwoFactorFromContext(ctx context.Context) bool {
+	twoFactor, _ := ctx.Value(twoFactorKey).(bool)
+	return twoFactor
+}
+
+// APIKeyIDFromContext повертає ID API ключа, яким автентифіковано запит
+func APIKeyIDFromContext(ctx context.Context) (uint, bool) {
+	keyID, ok := ctx.Value(apiKeyIDKey).(uint)
+	return keyID, ok
+}
+
 // AuthMiddleware реалізує middleware для аутентифікації
 type AuthMiddleware struct {
-	authService service.AuthService
+	authService   service.AuthService
+	apiKeyService service.APIKeyService
 }
 
 // NewAuthMiddleware створює новий middleware для аутентифікації
-func NewAuthMiddleware(authService service.AuthService) *AuthMiddleware {
+func NewAuthMiddleware(authService service.AuthService, apiKeyService service.APIKeyService) *AuthMiddleware {
 	return &AuthMiddleware{
-		authService: authService,
+		authService:   authService,
+		apiKeyService: apiKeyService,
 	}
 }
 
-// Middleware виконує перевірку JWT токена
+// Middleware виконує перевірку JWT токена. API ключі тут не приймаються:
+// маршрути, доступні за ключами, підключаються через WithAPIKey.
 func (m *AuthMiddleware) Middleware(next http.Handler) http.Handler {
+	return m.authenticate(next, "")
+}
+
+// Optional автентифікує запит JWT токеном, якщо його передано, а запити без
+// облікових даних пропускає анонімними. Недійсний токен, як і в Middleware, дає 401.
+func (m *AuthMiddleware) Optional(next http.Handler) http.Handler {
+	authenticated := m.authenticate(next, "")
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Отримання токена з заголовка Authorization
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
+		if _, ok := credentials(r); !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		authenticated.ServeHTTP(w, r)
+	})
+}
+
+// WithAPIKey створює middleware, що приймає JWT токен або API ключ з областю
+// доступу scope (у заголовку Authorization: Bearer або X-API-Key)
+func (m *AuthMiddleware) WithAPIKey(scope models.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return m.authenticate(next, scope)
+	}
+}
+
+// authenticate перевіряє облікові дані запиту; порожній scope вимикає API ключі
+func (m *AuthMiddleware) authenticate(next http.Handler, scope models.Scope) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := credentials(r)
+		if !ok {
 			http.Error(w, "Authorization header is required", http.StatusUnauthorized)
 			return
 		}
 
-		// Перевірка формату токена
-		headerParts := strings.Split(authHeader, " ")
-		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
-			http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
+		if service.IsAPIKey(token) {
+			m.serveAPIKey(w, r, next, token, scope)
 			return
 		}
 
 		// Парсинг токена
-		userID, err := m.authService.ParseToken(headerParts[1])
+		claims, err := m.authService.ParseToken(r.Context(), token)
 		if err != nil {
 			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
 			return
 		}
 
-		// Додавання ID користувача до контексту запиту
-		type contextKey string
-		const userIDKey contextKey = "user_id"
-		ctx := context.WithValue(r.Context(), userIDKey, userID)
+		// Додавання ID, ролі користувача та ознаки двофакторної сесії до контексту запиту
+		ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
+		ctx = context.WithValue(ctx, roleKey, claims.Role)
+		ctx = context.WithValue(ctx, twoFactorKey, claims.TwoFactor)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// serveAPIKey автентифікує запит API ключем. Запит діє від імені власника ключа
+// з його поточною роллю, але лише на маршрутах, область яких є в ключі.
+func (m *AuthMiddleware) serveAPIKey(w http.ResponseWriter, r *http.Request, next http.Handler, key string, scope models.Scope) {
+	if scope == "" {
+		http.Error(w, "API keys are not accepted for this endpoint", http.StatusUnauthorized)
+		return
+	}
+
+	principal, err := m.apiKeyService.Authenticate(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAPIKey) {
+			http.Error(w, "Invalid or expired API key", http.StatusUnauthorized)
+			return
+		}
+		log.Printf("API key authentication failed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !slices.Contains(principal.Scopes, scope) {
+		log.Printf("Access denied: API key %d lacks scope %q for %s %s", principal.KeyID, scope, r.Method, r.URL.Path)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), userIDKey, principal.UserID)
+	ctx = context.WithValue(ctx, roleKey, principal.Role)
+	ctx = context.WithValue(ctx, apiKeyIDKey, principal.KeyID)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// credentials повертає токен із заголовка Authorization (схема Bearer) або API ключ
+// із заголовка X-API-Key
+func credentials(r *http.Request) (string, bool) {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key, true
+	}
+
+	// Отримання токена з заголовка Authorization
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return "", false
+	}
+
+	// Перевірка формату токена
+	headerParts := strings.Split(authHeader, " ")
+	if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+		return "", false
+	}
+	return headerParts[1], true
+}
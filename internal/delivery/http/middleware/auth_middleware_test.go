@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/service"
+)
+
+// stubAuthService приймає лише токен "valid-jwt"; решта методів у тестах не викликається
+type stubAuthService struct {
+	service.AuthService
+}
+
+func (stubAuthService) ParseToken(_ context.Context, token string) (*service.TokenClaims, error) {
+	if token != "valid-jwt" {
+		return nil, errors.New("invalid token")
+	}
+	return &service.TokenClaims{UserID: 1, Role: models.RoleCustomer}, nil
+}
+
+// stubAPIKeyService знає ключі з фіксованими областями доступу
+type stubAPIKeyService struct {
+	service.APIKeyService
+}
+
+func (stubAPIKeyService) Authenticate(_ context.Context, key string) (*service.APIKeyPrincipal, error) {
+	switch key {
+	case service.APIKeyPrefix + "read":
+		return &service.APIKeyPrincipal{KeyID: 10, UserID: 2, Role: models.RoleCustomer,
+			Scopes: []models.Scope{models.ScopeRecommendationsRead}}, nil
+	case service.APIKeyPrefix + "batch":
+		return &service.APIKeyPrincipal{KeyID: 11, UserID: 3, Role: models.RoleAdmin,
+			Scopes: []models.Scope{models.ScopeRecommendationsBatch}}, nil
+	case service.APIKeyPrefix + "broken":
+		return nil, errors.New("database is down")
+	default:
+		return nil, service.ErrInvalidAPIKey
+	}
+}
+
+// identify відповідає ID користувача та ключа з контексту запиту
+func identify(t *testing.T, wantUser uint, wantKey bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := UserIDFromContext(r.Context())
+		_, isKey := APIKeyIDFromContext(r.Context())
+		if userID != wantUser || isKey != wantKey {
+			t.Errorf("got user %d and api key %v, want %d and %v", userID, isKey, wantUser, wantKey)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func TestWithAPIKey(t *testing.T) {
+	m := NewAuthMiddleware(stubAuthService{}, stubAPIKeyService{})
+
+	tests := []struct {
+		name       string
+		scope      models.Scope
+		header     string
+		value      string
+		wantStatus int
+		wantUser   uint
+		wantKey    bool
+	}{
+		{name: "jwt", scope: models.ScopeRecommendationsRead, header: "Authorization", value: "Bearer valid-jwt", wantStatus: http.StatusNoContent, wantUser: 1},
+		{name: "key with the scope", scope: models.ScopeRecommendationsRead, header: "X-API-Key", value: service.APIKeyPrefix + "read", wantStatus: http.StatusNoContent, wantUser: 2, wantKey: true},
+		{name: "key as bearer token", scope: models.ScopeRecommendationsBatch, header: "Authorization", value: "Bearer " + service.APIKeyPrefix + "batch", wantStatus: http.StatusNoContent, wantUser: 3, wantKey: true},
+		{name: "key without the scope", scope: models.ScopeRecommendationsBatch, header: "X-API-Key", value: service.APIKeyPrefix + "read", wantStatus: http.StatusForbidden},
+		// Адміністративна роль власника не розширює областей ключа
+		{name: "admin key without the scope", scope: models.ScopeRecommendationsRead, header: "X-API-Key", value: service.APIKeyPrefix + "batch", wantStatus: http.StatusForbidden},
+		{name: "unknown key", scope: models.ScopeRecommendationsRead, header: "X-API-Key", value: service.APIKeyPrefix + "unknown", wantStatus: http.StatusUnauthorized},
+		{name: "key check failure", scope: models.ScopeRecommendationsRead, header: "X-API-Key", value: service.APIKeyPrefix + "broken", wantStatus: http.StatusInternalServerError},
+		{name: "invalid jwt", scope: models.ScopeRecommendationsRead, header: "Authorization", value: "Bearer expired", wantStatus: http.StatusUnauthorized},
+		{name: "no credentials", scope: models.ScopeRecommendationsRead, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/recommendations", nil)
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+
+			m.WithAPIKey(tt.scope)(identify(t, tt.wantUser, tt.wantKey)).ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestMiddlewareRejectsAPIKeys(t *testing.T) {
+	m := NewAuthMiddleware(stubAuthService{}, stubAPIKeyService{})
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/orders", nil)
+	r.Header.Set("X-API-Key", service.APIKeyPrefix+"read")
+	w := httptest.NewRecorder()
+
+	m.Middleware(identify(t, 0, false)).ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"product-recommendations-go/internal/models"
+)
+
+// RequirePermission створює middleware, що пропускає лише користувачів, роль яких
+// має дозвіл permission. Має застосовуватися після AuthMiddleware.
+func RequirePermission(permission models.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := UserIDFromContext(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			role, _ := RoleFromContext(r.Context())
+			if !role.Can(permission) {
+				log.Printf("Access denied: user %d (role %q) lacks permission %q for %s %s",
+					userID, role, permission, r.Method, r.URL.Path)
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
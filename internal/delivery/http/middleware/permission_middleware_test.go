@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"product-recommendations-go/internal/models"
+)
+
+// authenticated повертає запит з даними користувача в контексті, як їх додає AuthMiddleware
+func authenticated(userID uint, role models.Role, twoFactor bool) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/admin/products", nil)
+	ctx := context.WithValue(r.Context(), userIDKey, userID)
+	ctx = context.WithValue(ctx, roleKey, role)
+	ctx = context.WithValue(ctx, twoFactorKey, twoFactor)
+	return r.WithContext(ctx)
+}
+
+// noContent обробник, що відповідає 204
+var noContent = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+})
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		name       string
+		request    *http.Request
+		permission models.Permission
+		wantStatus int
+	}{
+		{name: "admin manages products", request: authenticated(1, models.RoleAdmin, false), permission: models.PermissionProductsManage, wantStatus: http.StatusNoContent},
+		{name: "admin manages orders", request: authenticated(1, models.RoleAdmin, false), permission: models.PermissionOrdersManage, wantStatus: http.StatusNoContent},
+		{name: "customer manages products", request: authenticated(2, models.RoleCustomer, false), permission: models.PermissionProductsManage, wantStatus: http.StatusForbidden},
+		{name: "customer runs batch", request: authenticated(2, models.RoleCustomer, false), permission: models.PermissionRecommendationsBatch, wantStatus: http.StatusForbidden},
+		{name: "unknown role", request: authenticated(3, "owner", false), permission: models.PermissionProductsManage, wantStatus: http.StatusForbidden},
+		{name: "unknown permission", request: authenticated(1, models.RoleAdmin, false), permission: "users:delete", wantStatus: http.StatusForbidden},
+		{name: "not authenticated", request: httptest.NewRequest(http.MethodPost, "/api/v1/admin/products", nil), permission: models.PermissionProductsManage, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			RequirePermission(tt.permission)(noContent).ServeHTTP(w, tt.request)
+			if w.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+)
+
+// RequireTwoFactor пропускає лише запити із сесій, відкритих з підтвердженням
+// другого фактора. Запити з API ключами пропускаються: ключі з адміністративними
+// областями доступу створюються лише із сесії з другим фактором. Має застосовуватися
+// після AuthMiddleware.
+func RequireTwoFactor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if _, isAPIKey := APIKeyIDFromContext(r.Context()); isAPIKey {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !TwoFactorFromContext(r.Context()) {
+			log.Printf("Access denied: user %d has no two-factor session for %s %s", userID, r.Method, r.URL.Path)
+			http.Error(w, "Two-factor authentication required", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
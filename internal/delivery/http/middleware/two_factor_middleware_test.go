@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"product-recommendations-go/internal/models"
+)
+
+func TestRequireTwoFactor(t *testing.T) {
+	withAPIKey := authenticated(1, models.RoleAdmin, false)
+	withAPIKey = withAPIKey.WithContext(context.WithValue(withAPIKey.Context(), apiKeyIDKey, uint(10)))
+
+	tests := []struct {
+		name       string
+		request    *http.Request
+		wantStatus int
+	}{
+		{name: "session with second factor", request: authenticated(1, models.RoleAdmin, true), wantStatus: http.StatusNoContent},
+		{name: "session without second factor", request: authenticated(1, models.RoleAdmin, false), wantStatus: http.StatusForbidden},
+		// Ключі з адміністративними областями створюються лише із сесії з другим фактором
+		{name: "api key", request: withAPIKey, wantStatus: http.StatusNoContent},
+		{name: "not authenticated", request: httptest.NewRequest(http.MethodPost, "/api/v1/admin/products", nil), wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			RequireTwoFactor(noContent).ServeHTTP(w, tt.request)
+			if w.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,324 @@
+// Package jwtkeys керує ключами підпису токенів доступу: завантаженням з PEM-файлів,
+// ротацією за заголовком "kid" та публікацією відкритих ключів у форматі JWKS (RFC 7517).
+//
+// Підтримуються асиметричні ключі RS256 (RSA від 2048 біт) та EdDSA (Ed25519), а
+// також HS256 зі спільним секретом для сумісності. Токени підписуються активним
+// ключем; перевіряються будь-яким ключем набору, тому під час ротації токени,
+// підписані попереднім ключем, діють до закінчення свого терміну.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSABits мінімальна довжина RSA-ключа
+const minRSABits = 2048
+
+// hmacKeyID ідентифікатор ключа HS256
+const hmacKeyID = "hs256"
+
+// Key ключ підпису або перевірки токенів
+type Key struct {
+	// ID ідентифікатор ключа ("kid"); для асиметричних ключів - відбиток JWK (RFC 7638)
+	ID     string
+	Method jwt.SigningMethod
+
+	// signer закритий ключ (nil для ключів, що лише перевіряють підпис)
+	signer interface{}
+	// verifier відкритий ключ або спільний секрет
+	verifier interface{}
+}
+
+// KeySet набір ключів з одним активним ключем підпису
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+	order  []string
+}
+
+// ErrUnknownKey повертається для токенів, підписаних ключем, якого немає в наборі
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// NewHMACKeySet створює набір з одного ключа HS256 зі спільним секретом
+func NewHMACKeySet(secret []byte) *KeySet {
+	key := &Key{
+		ID:       hmacKeyID,
+		Method:   jwt.SigningMethodHS256,
+		signer:   secret,
+		verifier: secret,
+	}
+	return &KeySet{
+		active: key,
+		keys:   map[string]*Key{key.ID: key},
+		order:  []string{key.ID},
+	}
+}
+
+// LoadKeySet завантажує набір ключів. signingKeys - шляхи до закритих ключів (PEM,
+// PKCS#8 або PKCS#1): перший стає активним ключем підпису, решта лише перевіряють
+// підпис. verifyKeys - шляхи до відкритих ключів (PEM, PKIX) виведених з обігу ключів,
+// токени яких ще можуть бути дійсними.
+func LoadKeySet(signingKeys, verifyKeys []string) (*KeySet, error) {
+	if len(signingKeys) == 0 {
+		return nil, errors.New("at least one signing key is required")
+	}
+
+	ks := &KeySet{keys: make(map[string]*Key)}
+
+	for i, path := range signingKeys {
+		key, err := loadPrivateKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("load signing key %s: %w", path, err)
+		}
+		if err := ks.add(key); err != nil {
+			return nil, fmt.Errorf("load signing key %s: %w", path, err)
+		}
+		if i == 0 {
+			ks.active = key
+		}
+	}
+
+	for _, path := range verifyKeys {
+		key, err := loadPublicKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("load verification key %s: %w", path, err)
+		}
+		if err := ks.add(key); err != nil {
+			return nil, fmt.Errorf("load verification key %s: %w", path, err)
+		}
+	}
+
+	return ks, nil
+}
+
+// Sign підписує claims активним ключем і додає заголовок "kid"
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.active.Method, claims)
+	token.Header["kid"] = ks.active.ID
+	return token.SignedString(ks.active.signer)
+}
+
+// Keyfunc повертає ключ перевірки для токена за його заголовком "kid".
+// Використовується як jwt.Keyfunc разом з jwt.WithValidMethods(ks.Methods()).
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	// Sign завжди додає "kid", тож токен без нього підписано не цим набором
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, fmt.Errorf("%w: missing kid", ErrUnknownKey)
+	}
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+
+	// Алгоритм визначається ключем, а не заголовком токена
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+
+	return key.verifier, nil
+}
+
+// Methods повертає алгоритми підпису ключів набору
+func (ks *KeySet) Methods() []string {
+	seen := make(map[string]bool)
+	var methods []string
+	for _, kid := range ks.order {
+		alg := ks.keys[kid].Method.Alg()
+		if !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// ActiveKeyID повертає ідентифікатор активного ключа підпису
+func (ks *KeySet) ActiveKeyID() string {
+	return ks.active.ID
+}
+
+// JWK відкритий ключ у форматі JSON Web Key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP (Ed25519)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS набір відкритих ключів для перевірки токенів іншими сервісами
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS повертає відкриті ключі набору. Ключі HS256 не публікуються.
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, kid := range ks.order {
+		if jwk, ok := publicJWK(ks.keys[kid]); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+func (ks *KeySet) add(key *Key) error {
+	if _, exists := ks.keys[key.ID]; exists {
+		return fmt.Errorf("duplicate key %q", key.ID)
+	}
+	ks.keys[key.ID] = key
+	ks.order = append(ks.order, key.ID)
+	return nil
+}
+
+func loadPrivateKey(path string) (*Key, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", parsed)
+	}
+
+	key, err := newKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	key.signer = signer
+	return key, nil
+}
+
+func loadPublicKey(path string) (*Key, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return newKey(parsed)
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	return block, nil
+}
+
+// newKey визначає алгоритм за типом відкритого ключа та обчислює його "kid"
+func newKey(public crypto.PublicKey) (*Key, error) {
+	key := &Key{verifier: public}
+
+	switch pub := public.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits, got %d", minRSABits, pub.N.BitLen())
+		}
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", public)
+	}
+
+	jwk, _ := publicJWK(key)
+	key.ID = thumbprint(jwk)
+	return key, nil
+}
+
+func publicJWK(key *Key) (JWK, bool) {
+	switch pub := key.verifier.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: key.ID,
+			Use: "sig",
+			Alg: key.Method.Alg(),
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: key.ID,
+			Use: "sig",
+			Alg: key.Method.Alg(),
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+		}, true
+	default:
+		return JWK{}, false
+	}
+}
+
+// thumbprint обчислює відбиток JWK за RFC 7638: SHA-256 від обов'язкових полів
+// ключа в лексикографічному порядку
+func thumbprint(jwk JWK) string {
+	var members interface{}
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,249 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestKeyfuncRejectsMissingKid(t *testing.T) {
+	secret := []byte("test-secret")
+	ks := NewHMACKeySet(secret)
+
+	withoutKid, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "1"}).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwt.Parse(withoutKid, ks.Keyfunc); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("token without kid: got %v, want ErrUnknownKey", err)
+	}
+
+	signed, err := ks.Sign(jwt.MapClaims{"sub": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwt.Parse(signed, ks.Keyfunc); err != nil {
+		t.Errorf("token with kid rejected: %v", err)
+	}
+}
+
+// writeKeys записує закритий ключ (PKCS#8) і відкритий ключ (PKIX) у PEM-файли
+func writeKeys(t *testing.T, name string, private interface{}, public interface{}) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privatePath := filepath.Join(dir, name+".pem")
+	publicPath := filepath.Join(dir, name+".pub.pem")
+	if err := os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return privatePath, publicPath
+}
+
+func TestThumbprint(t *testing.T) {
+	tests := []struct {
+		name string
+		jwk  JWK
+		want string
+	}{
+		{
+			// Приклад з RFC 7638, розділ 3.1
+			name: "RFC 7638 RSA example",
+			jwk: JWK{
+				Kty: "RSA",
+				E:   "AQAB",
+				N: "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECP" +
+					"ebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY" +
+					"368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0f" +
+					"M4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+			},
+			want: "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs",
+		},
+		{
+			// Приклад з RFC 8037, додаток A.3
+			name: "RFC 8037 Ed25519 example",
+			jwk:  JWK{Kty: "OKP", Crv: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},
+			want: "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k",
+		},
+		{
+			// Поля, що не входять до відбитка, на нього не впливають
+			name: "ignores kid, use and alg",
+			jwk:  JWK{Kty: "OKP", Kid: "other", Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},
+			want: "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := thumbprint(tt.jwk); got != tt.want {
+				t.Errorf("thumbprint = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPath, _ := writeKeys(t, "rsa", rsaKey, &rsaKey.PublicKey)
+	_, edPublicPath := writeKeys(t, "ed25519", edPrivate, edPublic)
+
+	ks, err := LoadKeySet([]string{rsaPath}, []string{edPublicPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(ks.JWKS())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var published struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &published); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		kty, alg string
+		members  []string
+	}{
+		{kty: "RSA", alg: "RS256", members: []string{"kty", "kid", "use", "alg", "n", "e"}},
+		{kty: "OKP", alg: "EdDSA", members: []string{"kty", "kid", "use", "alg", "crv", "x"}},
+	}
+	if len(published.Keys) != len(want) {
+		t.Fatalf("got %d keys, want %d: %s", len(published.Keys), len(want), data)
+	}
+	for i, w := range want {
+		key := published.Keys[i]
+		if key["kty"] != w.kty || key["alg"] != w.alg || key["use"] != "sig" {
+			t.Errorf("key %d: %v", i, key)
+		}
+		if len(key) != len(w.members) {
+			t.Errorf("key %d has members %v, want %v", i, key, w.members)
+		}
+		for _, member := range w.members {
+			if key[member] == "" {
+				t.Errorf("key %d lacks %q", i, member)
+			}
+		}
+		// "kid" збігається з відбитком опублікованого ключа
+		jwk := JWK{Kty: key["kty"], N: key["n"], E: key["e"], Crv: key["crv"], X: key["x"]}
+		if key["kid"] != thumbprint(jwk) {
+			t.Errorf("key %d: kid %s is not its thumbprint", i, key["kid"])
+		}
+	}
+
+	// Спільний секрет HS256 не публікується
+	if keys := NewHMACKeySet([]byte("secret")).JWKS().Keys; len(keys) != 0 {
+		t.Errorf("HMAC key published: %v", keys)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, newPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, unknownPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldPath, oldPublicPath := writeKeys(t, "old", oldKey, &oldKey.PublicKey)
+	newPath, _ := writeKeys(t, "new", newPrivate, newPrivate.Public())
+	unknownPath, _ := writeKeys(t, "unknown", unknownPrivate, unknownPrivate.Public())
+
+	before, err := LoadKeySet([]string{oldPath}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Новий ключ стає активним, старий лише перевіряє видані ним токени
+	after, err := LoadKeySet([]string{newPath}, []string{oldPublicPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown, err := LoadKeySet([]string{unknownPath}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if after.ActiveKeyID() == before.ActiveKeyID() {
+		t.Fatal("rotation kept the active key")
+	}
+
+	sign := func(ks *KeySet) string {
+		token, err := ks.Sign(jwt.MapClaims{"sub": "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	hmacToken, err := NewHMACKeySet([]byte("secret")).Sign(jwt.MapClaims{"sub": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Підробка: HS256 з "kid" асиметричного ключа
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "1"})
+	forged.Header["kid"] = after.ActiveKeyID()
+	forgedToken, err := forged.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "signed with the new key", token: sign(after)},
+		{name: "signed with the retired key", token: sign(before)},
+		{name: "signed with an unknown key", token: sign(unknown), wantErr: true},
+		{name: "signed with a key of another set", token: hmacToken, wantErr: true},
+		{name: "algorithm does not match the key", token: forgedToken, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := jwt.Parse(tt.token, after.Keyfunc, jwt.WithValidMethods(after.Methods()))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Ключ, що лише перевіряє підпис, не може стати активним
+	if _, err := LoadKeySet(nil, []string{oldPublicPath}); err == nil {
+		t.Error("key set without signing keys accepted")
+	}
+}
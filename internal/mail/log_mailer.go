@@ -0,0 +1,23 @@
+package mail
+
+import (
+	"context"
+	"log"
+)
+
+type logMailer struct{}
+
+// NewLogMailer створює Mailer, що записує листи в журнал замість надсилання.
+// Призначений для локальної розробки: лист містить одноразові токени, тому
+// в робочому середовищі журнал стає каналом їх витоку.
+func NewLogMailer() Mailer {
+	return logMailer{}
+}
+
+func (logMailer) Send(_ context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	log.Printf("Mail to %s\nSubject: %s\n\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
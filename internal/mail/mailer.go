@@ -0,0 +1,36 @@
+// Package mail надсилає листи користувачам (підтвердження адреси, скидання пароля).
+// Спосіб доставки визначається реалізацією Mailer: SMTP для робочого середовища
+// або журнал для локальної розробки.
+package mail
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// Message текстовий лист одному отримувачу
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer інтерфейс надсилання листів
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// ErrInvalidHeader повертається, якщо адреса або тема листа містить символи нового рядка
+var ErrInvalidHeader = errors.New("mail header contains line breaks")
+
+// validate не допускає підстановки додаткових заголовків через адресу чи тему
+func (m Message) validate() error {
+	if m.To == "" {
+		return errors.New("mail recipient is required")
+	}
+	if strings.ContainsAny(m.To, "\r\n") || strings.ContainsAny(m.Subject, "\r\n") {
+		return ErrInvalidHeader
+	}
+	return nil
+}
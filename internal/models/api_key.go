@@ -0,0 +1,72 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Scope область доступу API ключа
+type Scope string
+
+// Області доступу API ключів
+const (
+	// ScopeRecommendationsRead рекомендації для власника ключа (GET /recommendations)
+	ScopeRecommendationsRead Scope = "recommendations:read"
+	// ScopeRecommendationsBatch пакетне обчислення рекомендацій; власник має мати дозвіл recommendations:batch
+	ScopeRecommendationsBatch Scope = "recommendations:batch"
+)
+
+// scopePermissions дозволи ролі, потрібні власнику для створення ключа з областю доступу.
+// Області без запису доступні будь-якому користувачу.
+var scopePermissions = map[Scope]Permission{
+	ScopeRecommendationsRead:  "",
+	ScopeRecommendationsBatch: PermissionRecommendationsBatch,
+}
+
+// Valid перевіряє, що область доступу відома системі
+func (s Scope) Valid() bool {
+	_, ok := scopePermissions[s]
+	return ok
+}
+
+// Permission повертає дозвіл ролі, потрібний для області доступу (порожній - не потрібен)
+func (s Scope) Permission() Permission {
+	return scopePermissions[s]
+}
+
+// APIKey ключ доступу до API для інтеграцій без входу користувача. Ключ належить
+// користувачу і діє з його роллю, але лише в межах своїх областей доступу. У базі
+// зберігається SHA-256 хеш ключа; Prefix - відкрита частина ключа для ідентифікації.
+type APIKey struct {
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	UserID uint   `gorm:"index;not null" json:"user_id"`
+	Name   string `gorm:"size:100;not null" json:"name"`
+	Prefix string `gorm:"uniqueIndex;size:32;not null" json:"prefix"`
+	// KeyHash хеш повного ключа
+	KeyHash string `gorm:"size:64;not null" json:"-"`
+	// Scopes області доступу через пробіл
+	Scopes     string     `gorm:"size:255;not null" json:"-"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ScopeList повертає області доступу ключа
+func (k *APIKey) ScopeList() []Scope {
+	fields := strings.Fields(k.Scopes)
+	scopes := make([]Scope, 0, len(fields))
+	for _, field := range fields {
+		scopes = append(scopes, Scope(field))
+	}
+	return scopes
+}
+
+// JoinScopes перетворює області доступу на значення поля Scopes
+func JoinScopes(scopes []Scope) string {
+	fields := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		fields = append(fields, string(scope))
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Cart кошик покупок користувача або гостя
+type Cart struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+	// UserID власник кошика; nil - кошик гостя
+	UserID *uint `gorm:"uniqueIndex" json:"-"`
+	// TokenHash SHA-256 токена кошика гостя; у відкритому вигляді токен зберігає лише клієнт
+	TokenHash *string    `gorm:"size:64;uniqueIndex" json:"-"`
+	Items     []CartItem `json:"items"`
+	CreatedAt time.Time  `json:"created_at"`
+	// UpdatedAt час останньої зміни вмісту; кошики гостів без змін видаляються
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CartItem товар у кошику
+type CartItem struct {
+	ID        uint `gorm:"primaryKey" json:"-"`
+	CartID    uint `gorm:"uniqueIndex:idx_cart_items_cart_product;not null" json:"-"`
+	ProductID uint `gorm:"uniqueIndex:idx_cart_items_cart_product;not null" json:"product_id"`
+	Quantity  int  `gorm:"not null" json:"quantity"`
+	// Price ціна товару на момент додавання до кошика (або останнього оновлення цін)
+	Price     float64   `gorm:"not null" json:"price"`
+	CreatedAt time.Time `json:"added_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Product   Product   `gorm:"foreignKey:ProductID" json:"-"`
+}
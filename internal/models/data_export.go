@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// DataExportStatus стан формування архіву даних користувача
+type DataExportStatus string
+
+const (
+	// DataExportPending запит очікує обробки
+	DataExportPending DataExportStatus = "pending"
+	// DataExportProcessing архів формується
+	DataExportProcessing DataExportStatus = "processing"
+	// DataExportReady архів готовий до завантаження
+	DataExportReady DataExportStatus = "ready"
+	// DataExportFailed сформувати архів не вдалося
+	DataExportFailed DataExportStatus = "failed"
+)
+
+// DataExport запит користувача на вивантаження його даних (GDPR, ст. 15 і 20).
+// Архів формується у фоні й зберігається до ExpiresAt.
+type DataExport struct {
+	ID     uint             `gorm:"primaryKey" json:"id"`
+	UserID uint             `gorm:"index;not null" json:"-"`
+	Status DataExportStatus `gorm:"type:varchar(16);index;not null" json:"status"`
+	// FilePath ім'я файлу архіву в каталозі вивантажень
+	FilePath    string     `gorm:"size:255" json:"-"`
+	Size        int64      `json:"size,omitempty"`
+	Error       string     `gorm:"size:255" json:"-"`
+	StartedAt   *time.Time `json:"-"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `gorm:"index" json:"expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"requested_at"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// LoginFailureReason причина невдалої спроби входу
+type LoginFailureReason string
+
+const (
+	// LoginFailureUnknownUser адресу не зареєстровано
+	LoginFailureUnknownUser LoginFailureReason = "unknown_user"
+	// LoginFailureInvalidPassword неправильний пароль
+	LoginFailureInvalidPassword LoginFailureReason = "invalid_password"
+	// LoginFailureInvalidCode неправильний код двофакторної автентифікації
+	LoginFailureInvalidCode LoginFailureReason = "invalid_code"
+	// LoginFailureThrottled спробу відхилено через затримку або блокування
+	LoginFailureThrottled LoginFailureReason = "throttled"
+)
+
+// LoginAttempt запис журналу аудиту невдалих спроб входу
+type LoginAttempt struct {
+	ID        uint               `gorm:"primaryKey" json:"id"`
+	Email     string             `gorm:"index;size:255;not null" json:"email"`
+	UserID    *uint              `gorm:"index" json:"user_id,omitempty"`
+	IP        string             `gorm:"index;size:64;not null" json:"ip"`
+	UserAgent string             `gorm:"size:255" json:"user_agent"`
+	Reason    LoginFailureReason `gorm:"type:varchar(32);not null" json:"reason"`
+	CreatedAt time.Time          `gorm:"index" json:"created_at"`
+}
+
+// LoginFailureCounter лічильник невдалих спроб входу для ключа ("email:<адреса>"
+// або "ip:<адреса>") у сховищі лічильників у базі даних
+type LoginFailureCounter struct {
+	Key      string `gorm:"primaryKey;size:300" json:"key"`
+	Failures int    `gorm:"not null" json:"failures"`
+	// LastAt час останньої врахованої спроби; лічильник починається заново,
+	// якщо спроб не було довше за вікно підрахунку
+	LastAt time.Time `gorm:"index;not null" json:"last_at"`
+	// PreviousAt час спроби, врахованої перед останньою
+	PreviousAt *time.Time `json:"previous_at,omitempty"`
+}
@@ -10,11 +10,89 @@ import (
 type Order struct {
 	ID        uint           `gorm:"primaryKey" json:"id"`
 	UserID    uint           `gorm:"index;not null" json:"user_id"`
-	Status    string         `gorm:"not null" json:"status"`
+	Status    OrderStatus    `gorm:"type:varchar(32);not null" json:"status"`
 	Total     float64        `json:"total"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 	User      User           `gorm:"foreignKey:UserID" json:"-"`
 	Items     []OrderItem    `json:"items"`
+	// History зміни статусу в хронологічному порядку
+	History []OrderStatusChange `json:"history,omitempty"`
+}
+
+// OrderStatus статус замовлення
+type OrderStatus string
+
+// Статуси замовлення
+const (
+	// OrderStatusPending замовлення створене і очікує оплати
+	OrderStatusPending OrderStatus = "pending"
+	// OrderStatusPaid замовлення оплачене
+	OrderStatusPaid OrderStatus = "paid"
+	// OrderStatusShipped замовлення передане на доставку
+	OrderStatusShipped OrderStatus = "shipped"
+	// OrderStatusDelivered замовлення доставлене покупцю
+	OrderStatusDelivered OrderStatus = "delivered"
+	// OrderStatusCancelled замовлення скасоване до оплати
+	OrderStatusCancelled OrderStatus = "cancelled"
+	// OrderStatusRefunded кошти за оплачене замовлення повернуті
+	OrderStatusRefunded OrderStatus = "refunded"
+)
+
+// orderTransitions дозволені переходи між статусами. Неоплачене замовлення
+// скасовується, оплачене - повертається (до відправлення або після доставки).
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending:   {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:      {OrderStatusShipped, OrderStatusRefunded},
+	OrderStatusShipped:   {OrderStatusDelivered},
+	OrderStatusDelivered: {OrderStatusRefunded},
+	OrderStatusCancelled: {},
+	OrderStatusRefunded:  {},
+}
+
+// Valid перевіряє, що статус відомий системі
+func (s OrderStatus) Valid() bool {
+	_, ok := orderTransitions[s]
+	return ok
+}
+
+// CanTransitionTo перевіряє, чи дозволений перехід зі статусу s до next
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPurchase повідомляє, чи вважається замовлення в цьому статусі покупкою
+// (для рекомендацій і навчання моделі): скасовані та повернуті замовлення - ні
+func (s OrderStatus) IsPurchase() bool {
+	return s != OrderStatusCancelled && s != OrderStatusRefunded
+}
+
+// PurchasedOrders повертає замовлення, що вважаються покупками
+func PurchasedOrders(orders []*Order) []*Order {
+	purchased := make([]*Order, 0, len(orders))
+	for _, order := range orders {
+		if order.Status.IsPurchase() {
+			purchased = append(purchased, order)
+		}
+	}
+	return purchased
+}
+
+// OrderStatusChange запис історії статусів замовлення
+type OrderStatusChange struct {
+	ID      uint `gorm:"primaryKey" json:"-"`
+	OrderID uint `gorm:"index;not null" json:"-"`
+	// FromStatus попередній статус (порожній для створення замовлення)
+	FromStatus OrderStatus `gorm:"type:varchar(32);not null;default:''" json:"from_status,omitempty"`
+	ToStatus   OrderStatus `gorm:"type:varchar(32);not null" json:"to_status"`
+	// ChangedBy користувач, що змінив статус (покупець або адміністратор)
+	ChangedBy *uint     `json:"changed_by,omitempty"`
+	Reason    string    `gorm:"size:500;not null;default:''" json:"reason,omitempty"`
+	CreatedAt time.Time `json:"changed_at"`
 }
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // OrderItem представляє товар в замовленні
 type OrderItem struct {
 	ID        uint    `gorm:"primaryKey" json:"id"`
@@ -10,3 +12,11 @@ type OrderItem struct {
 	Order     Order   `gorm:"foreignKey:OrderID" json:"-"`
 	Product   Product `gorm:"foreignKey:ProductID" json:"-"`
 }
+
+// Purchase покупка товару користувачем: позиція замовлення, що вважається покупкою,
+// з часом оформлення замовлення
+type Purchase struct {
+	UserID      uint
+	ProductID   uint
+	PurchasedAt time.Time
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// Preferences довільні налаштування користувача (JSON-об'єкт), що зберігаються у стовпці jsonb
+type Preferences map[string]interface{}
+
+// Value серіалізує налаштування для запису в базу
+func (p Preferences) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan читає налаштування з бази
+func (p *Preferences) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported preferences value")
+	}
+	return json.Unmarshal(data, p)
+}
@@ -7,13 +7,29 @@ import (
 
 // Product представляє продукт в системі
 type Product struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	Name        string         `gorm:"not null" json:"name"`
-	Description string         `json:"description"`
-	Price       float64        `gorm:"not null" json:"price"`
-	Category    string         `json:"category"`
-	ImageURL    string         `json:"image_url"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID           uint    `gorm:"primaryKey" json:"id"`
+	Name         string  `gorm:"not null" json:"name"`
+	Description  string  `json:"description"`
+	Price        float64 `gorm:"not null" json:"price"`
+	Category     string  `json:"category"`
+	ImageURL     string  `json:"image_url"`
+	IsConsumable bool    `gorm:"not null;default:false" json:"is_consumable"`
+	// Stock кількість товару на складі; зменшується під час оформлення замовлення
+	Stock     int            `gorm:"not null;default:0;index" json:"stock"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// ProductInput дані для створення або оновлення товару адміністратором
+type ProductInput struct {
+	Name         string  `json:"name"`
+	Description  string  `json:"description"`
+	Price        float64 `json:"price"`
+	Category     string  `json:"category"`
+	ImageURL     string  `json:"image_url"`
+	IsConsumable bool    `json:"is_consumable"`
+	// Stock початковий залишок; враховується лише під час створення товару,
+	// далі залишок змінюється окремим запитом
+	Stock int `json:"stock"`
 }
@@ -1,7 +1,69 @@
 package models
 
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
 // ProductRecommendation представляє рекомендацію продукту
 type ProductRecommendation struct {
 	Product *Product `json:"product"`
 	Score   float64  `json:"score"`
 }
+
+// BatchRecommendationResult представляє рекомендації одного користувача в пакетному запиті
+type BatchRecommendationResult struct {
+	UserID          uint                     `json:"user_id"`
+	Recommendations []*ProductRecommendation `json:"recommendations"`
+	Error           string                   `json:"error,omitempty"`
+}
+
+// RecommendationFilter обмежує множину товарів, які можуть бути рекомендовані
+type RecommendationFilter struct {
+	Category         string   `json:"category,omitempty"`
+	MinPrice         *float64 `json:"min_price,omitempty"`
+	MaxPrice         *float64 `json:"max_price,omitempty"`
+	ExcludeIDs       []uint   `json:"exclude_ids,omitempty"`
+	IncludePurchased bool     `json:"include_purchased,omitempty"`
+	// InStockOnly виключає товари, яких немає на складі
+	InStockOnly bool `json:"in_stock_only,omitempty"`
+}
+
+// Key повертає канонічне текстове представлення фільтра для ключів кешу.
+// Однакові фільтри дають однаковий ключ незалежно від порядку ExcludeIDs.
+func (f RecommendationFilter) Key() string {
+	var b strings.Builder
+
+	b.WriteString("category=")
+	b.WriteString(strconv.Quote(f.Category))
+	if f.MinPrice != nil {
+		b.WriteString(";min_price=")
+		b.WriteString(strconv.FormatFloat(*f.MinPrice, 'f', -1, 64))
+	}
+	if f.MaxPrice != nil {
+		b.WriteString(";max_price=")
+		b.WriteString(strconv.FormatFloat(*f.MaxPrice, 'f', -1, 64))
+	}
+	if len(f.ExcludeIDs) > 0 {
+		ids := make([]uint, len(f.ExcludeIDs))
+		copy(ids, f.ExcludeIDs)
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		b.WriteString(";exclude=")
+		for i, id := range ids {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(strconv.FormatUint(uint64(id), 10))
+		}
+	}
+	if f.IncludePurchased {
+		b.WriteString(";include_purchased")
+	}
+	if f.InStockOnly {
+		b.WriteString(";in_stock_only")
+	}
+
+	return b.String()
+}
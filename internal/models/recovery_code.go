@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// RecoveryCode резервний код для входу без застосунку-автентифікатора.
+// Зберігається лише хеш коду; кожен код можна використати один раз.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	CodeHash  string     `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// RefreshToken refresh токен, що зберігається у вигляді хешу. Токени однієї сесії
+// (від входу до виходу) утворюють родину FamilyID: кожне оновлення видає новий
+// токен і позначає попередній використаним.
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index;not null" json:"user_id"`
+	FamilyID  string    `gorm:"index;size:64;not null" json:"family_id"`
+	TokenHash string    `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"`
+	// TwoFactor сесію відкрито з підтвердженням другого фактора
+	TwoFactor bool       `gorm:"not null;default:false" json:"two_factor"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// RevokedToken запис списку відкликаних токенів доступу. JTI містить claim "jti"
+// окремого токена або "sid:<ідентифікатор сесії>" для всіх токенів сесії.
+// Запис потрібен лише до закінчення терміну дії токенів, після чого видаляється.
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey;size:64" json:"jti"`
+	UserID    uint      `gorm:"index;not null" json:"user_id"`
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,48 @@
+package models
+
+// Role роль користувача, що визначає набір його дозволів
+type Role string
+
+// Ролі користувачів
+const (
+	// RoleCustomer покупець: каталог, вподобання, замовлення, власні рекомендації
+	RoleCustomer Role = "customer"
+	// RoleAdmin адміністратор магазину
+	RoleAdmin Role = "admin"
+)
+
+// Permission дозвіл на виконання дії; перевіряється на рівні маршрутів
+type Permission string
+
+// Дозволи
+const (
+	// PermissionProductsManage створення, зміна, видалення та відновлення товарів
+	PermissionProductsManage Permission = "products:manage"
+	// PermissionOrdersManage зміна статусів замовлень усіх користувачів
+	PermissionOrdersManage Permission = "orders:manage"
+	// PermissionRecommendationsBatch пакетне обчислення рекомендацій для багатьох користувачів
+	PermissionRecommendationsBatch Permission = "recommendations:batch"
+)
+
+// rolePermissions дозволи кожної ролі. Базові дії покупця (перегляд каталогу,
+// вподобання, замовлення) доступні будь-якому автентифікованому користувачу
+// і окремих дозволів не потребують.
+var rolePermissions = map[Role]map[Permission]bool{
+	RoleCustomer: {},
+	RoleAdmin: {
+		PermissionProductsManage:       true,
+		PermissionOrdersManage:         true,
+		PermissionRecommendationsBatch: true,
+	},
+}
+
+// Valid перевіряє, що роль відома системі
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Can перевіряє, чи має роль дозвіл
+func (r Role) Can(permission Permission) bool {
+	return rolePermissions[r][permission]
+}
@@ -7,10 +7,28 @@ import (
 
 // User представляє користувача в системі
 type User struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Email     string         `gorm:"uniqueIndex;not null" json:"email"`
-	Password  string         `gorm:"not null" json:"-"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	Email    string `gorm:"uniqueIndex;not null" json:"email"`
+	Password string `gorm:"not null" json:"-"`
+	Role     Role   `gorm:"type:varchar(32);not null;default:customer" json:"role"`
+	// Name ім'я для відображення
+	Name string `gorm:"size:100;not null;default:''" json:"name"`
+	// Locale мовний тег інтерфейсу та листів (наприклад, uk-UA)
+	Locale      string      `gorm:"size:16;not null;default:''" json:"locale"`
+	Preferences Preferences `gorm:"type:jsonb" json:"preferences"`
+	// PendingEmail нова адреса, що очікує підтвердження; Email змінюється після переходу за посиланням
+	PendingEmail string `gorm:"size:255;not null;default:''" json:"pending_email,omitempty"`
+	// EmailVerifiedAt час підтвердження адреси (nil - адресу не підтверджено)
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	// TOTPSecret секрет двофакторної автентифікації, зашифрований ключем сервера
+	// (TOTP_ENCRYPTION_KEY); задається під час підключення і діє лише після
+	// підтвердження (TOTPEnabledAt)
+	TOTPSecret string `gorm:"size:128" json:"-"`
+	// TOTPEnabledAt час увімкнення двофакторної автентифікації (nil - вимкнена)
+	TOTPEnabledAt *time.Time `json:"totp_enabled_at,omitempty"`
+	// TOTPLastStep крок останнього прийнятого коду; коди попередніх кроків не приймаються повторно
+	TOTPLastStep int64          `gorm:"not null;default:0" json:"-"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 }
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// UserIdentity обліковий запис користувача в зовнішнього постачальника
+// ідентичності (OpenID Connect). Пара Provider+Subject однозначно визначає
+// особу у постачальника і не змінюється навіть при зміні адреси.
+type UserIdentity struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	UserID   uint   `gorm:"index;not null" json:"user_id"`
+	Provider string `gorm:"size:64;not null;uniqueIndex:idx_user_identities_provider_subject" json:"provider"`
+	Subject  string `gorm:"size:255;not null;uniqueIndex:idx_user_identities_provider_subject" json:"-"`
+	// Email адреса, отримана від постачальника під час останнього входу
+	Email     string    `gorm:"size:255" json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OIDCLoginState стан незавершеного входу через постачальника ідентичності.
+// Запис створюється перед перенаправленням до постачальника і використовується
+// один раз під час зворотного виклику; у базі зберігається лише хеш параметра state.
+type OIDCLoginState struct {
+	ID        uint   `gorm:"primaryKey"`
+	StateHash string `gorm:"uniqueIndex;size:64;not null"`
+	Provider  string `gorm:"size:64;not null"`
+	Nonce     string `gorm:"size:64;not null"`
+	// CodeVerifier PKCE verifier, що передається постачальнику під час обміну коду
+	CodeVerifier string    `gorm:"size:128;not null"`
+	ExpiresAt    time.Time `gorm:"index;not null"`
+	CreatedAt    time.Time
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// TokenPurpose призначення одноразового токена користувача
+type TokenPurpose string
+
+const (
+	// TokenPurposeEmailVerification підтвердження адреси електронної пошти
+	TokenPurposeEmailVerification TokenPurpose = "email_verification"
+	// TokenPurposePasswordReset скидання пароля
+	TokenPurposePasswordReset TokenPurpose = "password_reset"
+	// TokenPurposeEmailChange підтвердження нової адреси (User.PendingEmail)
+	TokenPurposeEmailChange TokenPurpose = "email_change"
+)
+
+// UserToken одноразовий токен, надісланий користувачу листом. У базі зберігається
+// лише хеш токена; після використання токен позначається UsedAt і більше не діє.
+type UserToken struct {
+	ID        uint         `gorm:"primaryKey" json:"id"`
+	UserID    uint         `gorm:"index;not null" json:"user_id"`
+	Purpose   TokenPurpose `gorm:"type:varchar(32);not null" json:"purpose"`
+	TokenHash string       `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	ExpiresAt time.Time    `gorm:"index;not null" json:"expires_at"`
+	UsedAt    *time.Time   `json:"used_at,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+}
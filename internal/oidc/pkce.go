@@ -0,0 +1,28 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// RandomString генерує випадковий рядок base64url з size байт випадковості.
+// Використовується для state, nonce та PKCE verifier.
+func RandomString(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// NewVerifier генерує PKCE code verifier (43 символи, RFC 7636, 4.1)
+func NewVerifier() (string, error) {
+	return RandomString(32)
+}
+
+// Challenge обчислює PKCE code challenge методом S256
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,354 @@
+// Package oidc реалізує вхід через зовнішнього постачальника ідентичності за
+// протоколом OpenID Connect: потік authorization code з PKCE (RFC 7636),
+// автоматичне виявлення налаштувань (/.well-known/openid-configuration) та
+// перевірку ID токена ключами постачальника (JWKS).
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// discoveryTTL як довго використовується завантажений документ виявлення
+	discoveryTTL = time.Hour
+	// jwksMinRefresh мінімальний інтервал між позаплановими оновленнями ключів
+	jwksMinRefresh = time.Minute
+	// maxResponseSize обмеження розміру відповідей постачальника
+	maxResponseSize = 1 << 20
+	// clockSkew допустима розбіжність годинників при перевірці ID токена
+	clockSkew = time.Minute
+)
+
+// ErrInvalidIDToken ID токен не пройшов перевірку
+var ErrInvalidIDToken = errors.New("invalid id token")
+
+// Config параметри клієнта постачальника ідентичності
+type Config struct {
+	// Name коротка назва постачальника в маршрутах (/auth/oidc/{name}/...)
+	Name string
+	// Issuer ідентифікатор постачальника; документ виявлення береться з Issuer + "/.well-known/openid-configuration"
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL адреса зворотного виклику, зареєстрована в постачальника
+	RedirectURL string
+	// Scopes додаткові області доступу; "openid", "email" і "profile" запитуються завжди
+	Scopes []string
+}
+
+// Claims дані користувача з перевіреного ID токена
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Nonce         string
+}
+
+// discoveryDocument потрібні поля документа виявлення OpenID Provider Metadata
+type discoveryDocument struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	SigningAlgs           []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// Provider клієнт одного постачальника ідентичності
+type Provider struct {
+	cfg        Config
+	httpClient *http.Client
+
+	mu           sync.Mutex
+	discovery    *discoveryDocument
+	discoveredAt time.Time
+	keys         map[string]interface{}
+	keysLoadedAt time.Time
+}
+
+// NewProvider створює клієнт постачальника. Налаштування постачальника
+// завантажуються під час першого входу, тому недоступність постачальника
+// не заважає запуску сервера.
+func NewProvider(cfg Config) *Provider {
+	cfg.Issuer = strings.TrimRight(cfg.Issuer, "/")
+	return &Provider{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name повертає назву постачальника
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// AuthCodeURL формує адресу сторінки входу постачальника. state захищає від
+// CSRF, nonce прив'язує ID токен до цього входу, challenge - PKCE challenge (S256).
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, challenge string) (string, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.cfg.ClientID)
+	params.Set("redirect_uri", p.cfg.RedirectURL)
+	params.Set("scope", strings.Join(p.scopes(), " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", challenge)
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return doc.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange обмінює код авторизації на токени та повертає дані перевіреного ID токена.
+// Відповідність nonce перевіряє викликач.
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (*Claims, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", p.cfg.ClientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		// client_secret_basic: облікові дані кодуються як form-urlencoded (RFC 6749, 2.3.1)
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.doJSON(req, &token)
+	if err != nil {
+		return nil, fmt.Errorf("token request: %w", err)
+	}
+	if status != http.StatusOK || token.Error != "" {
+		return nil, fmt.Errorf("token request failed with status %d: %s %s", status, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	return p.verifyIDToken(ctx, doc, token.IDToken)
+}
+
+// verifyIDToken перевіряє підпис, видавця, отримувача та термін дії ID токена
+func (p *Provider) verifyIDToken(ctx context.Context, doc *discoveryDocument, raw string) (*Claims, error) {
+	methods := doc.SigningAlgs
+	if len(methods) == 0 {
+		methods = []string{"RS256"}
+	}
+
+	parsed, err := jwt.Parse(raw, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, doc, kid)
+	},
+		jwt.WithValidMethods(methods),
+		jwt.WithIssuer(p.cfg.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidIDToken
+	}
+
+	// Якщо токен адресовано кільком клієнтам, authorized party має бути нашим клієнтом
+	if aud, _ := claims.GetAudience(); len(aud) > 1 {
+		if azp, _ := claims["azp"].(string); azp != p.cfg.ClientID {
+			return nil, fmt.Errorf("%w: unexpected azp %q", ErrInvalidIDToken, azp)
+		}
+	}
+
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidIDToken)
+	}
+
+	result := &Claims{Subject: subject}
+	result.Email, _ = claims["email"].(string)
+	result.Name, _ = claims["name"].(string)
+	result.Nonce, _ = claims["nonce"].(string)
+
+	// Деякі постачальники передають email_verified рядком
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = verified
+	case string:
+		result.EmailVerified = verified == "true"
+	}
+
+	return result, nil
+}
+
+// key повертає ключ постачальника за kid. Невідомий kid означає ротацію ключів
+// у постачальника, тому набір ключів перезавантажується (не частіше jwksMinRefresh).
+func (p *Provider) key(ctx context.Context, doc *discoveryDocument, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keysLoadedAt) < jwksMinRefresh {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	keys, err := p.fetchKeys(ctx, doc.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	p.keys = keys
+	p.keysLoadedAt = time.Now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey шукає ключ за kid; токен без kid приймається, лише якщо ключ один
+func (p *Provider) lookupKey(kid string) (interface{}, bool) {
+	if kid == "" {
+		if len(p.keys) == 1 {
+			for _, key := range p.keys {
+				return key, true
+			}
+		}
+		return nil, false
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *Provider) fetchKeys(ctx context.Context, jwksURI string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURI, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var set jwkSet
+	status, err := p.doJSON(req, &set)
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks: status %d", status)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			// Ключі непідтримуваних типів пропускаються: ними можуть бути підписані інші токени
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+// discover завантажує документ виявлення постачальника
+func (p *Provider) discover(ctx context.Context) (*discoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil && time.Since(p.discoveredAt) < discoveryTTL {
+		return p.discovery, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc discoveryDocument
+	status, err := p.doJSON(req, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("oidc discovery: status %d", status)
+	}
+
+	// Видавець у документі має збігатися з налаштованим (OpenID Connect Discovery, 4.3)
+	if strings.TrimRight(doc.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer %q does not match %q", doc.Issuer, p.cfg.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: incomplete provider metadata")
+	}
+
+	p.discovery = &doc
+	p.discoveredAt = time.Now()
+	return p.discovery, nil
+}
+
+func (p *Provider) doJSON(req *http.Request, out interface{}) (int, error) {
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, out); err != nil && resp.StatusCode == http.StatusOK {
+			return resp.StatusCode, err
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+func (p *Provider) scopes() []string {
+	scopes := []string{"openid", "email", "profile"}
+	for _, scope := range p.cfg.Scopes {
+		if scope != "openid" && scope != "email" && scope != "profile" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"product-recommendations-go/internal/models"
+	"time"
+)
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository створює новий екземпляр репозиторію API ключів
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{
+		db: db,
+	}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *apiKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+
+	if err := r.db.WithContext(ctx).Where("prefix = ?", prefix).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil // Ключ не знайдено
+		}
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (r *apiKeyRepository) GetByUserID(ctx context.Context, userID uint) ([]*models.APIKey, error) {
+	var keys []*models.APIKey
+
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&keys).Error
+
+	return keys, err
+}
+
+// Revoke відкликає ключ користувача. Повертає false, якщо ключ не знайдено,
+// він належить іншому користувачу або вже відкликаний.
+func (r *apiKeyRepository) Revoke(ctx context.Context, id, userID uint, revokedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", revokedAt)
+	return result.RowsAffected == 1, result.Error
+}
+
+// TouchLastUsed оновлює час останнього використання ключа, якщо з попереднього
+// оновлення минуло більше interval: запис у базу не робиться на кожен запит
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id uint, usedAt time.Time, interval time.Duration) error {
+	return r.db.WithContext(ctx).
+		Model(&models.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, usedAt.Add(-interval)).
+		Update("last_used_at", usedAt).Error
+}
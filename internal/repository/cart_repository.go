@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"product-recommendations-go/internal/models"
+	"time"
+)
+
+type cartRepository struct {
+	db *gorm.DB
+}
+
+// NewCartRepository створює новий екземпляр репозиторію кошиків
+func NewCartRepository(db *gorm.DB) CartRepository {
+	return &cartRepository{
+		db: db,
+	}
+}
+
+func (r *cartRepository) Create(ctx context.Context, cart *models.Cart) error {
+	return dbFrom(ctx, r.db).Create(cart).Error
+}
+
+func (r *cartRepository) GetByUserID(ctx context.Context, userID uint) (*models.Cart, error) {
+	return r.get(dbFrom(ctx, r.db), "user_id = ?", userID)
+}
+
+func (r *cartRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.Cart, error) {
+	return r.get(dbFrom(ctx, r.db), "token_hash = ? AND user_id IS NULL", tokenHash)
+}
+
+// GetByUserIDForUpdate завантажує кошик користувача з блокуванням його рядка
+// (SELECT ... FOR UPDATE) до кінця транзакції. Має викликатися всередині
+// Transactor.WithinTransaction.
+func (r *cartRepository) GetByUserIDForUpdate(ctx context.Context, userID uint) (*models.Cart, error) {
+	return r.get(dbFrom(ctx, r.db).Clauses(clause.Locking{Strength: "UPDATE"}), "user_id = ?", userID)
+}
+
+// GetByTokenHashForUpdate завантажує кошик гостя з блокуванням його рядка до кінця
+// транзакції. Якщо паралельна транзакція вже перенесла кошик до користувача,
+// після її завершення повертається nil.
+func (r *cartRepository) GetByTokenHashForUpdate(ctx context.Context, tokenHash string) (*models.Cart, error) {
+	return r.get(dbFrom(ctx, r.db).Clauses(clause.Locking{Strength: "UPDATE"}), "token_hash = ? AND user_id IS NULL", tokenHash)
+}
+
+// get завантажує кошик з товарами в порядку додавання. Товари, видалені з
+// каталогу, залишаються в кошику з порожнім Product.
+func (r *cartRepository) get(db *gorm.DB, query string, args ...interface{}) (*models.Cart, error) {
+	var cart models.Cart
+
+	err := db.
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("cart_items.id") }).
+		Preload("Items.Product").
+		Where(query, args...).
+		First(&cart).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil // Кошик не знайдено
+		}
+		return nil, err
+	}
+
+	return &cart, nil
+}
+
+// AddItem додає товар до кошика або, якщо він уже є, збільшує кількість (не більше
+// maxQuantity). Ціна товару, що вже є в кошику, не змінюється.
+func (r *cartRepository) AddItem(ctx context.Context, item *models.CartItem, maxQuantity int) error {
+	return dbFrom(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`INSERT INTO cart_items (cart_id, product_id, quantity, price, created_at, updated_at)
+			VALUES (?, ?, LEAST(?, ?), ?, NOW(), NOW())
+			ON CONFLICT (cart_id, product_id) DO UPDATE
+			SET quantity = LEAST(cart_items.quantity + EXCLUDED.quantity, ?), updated_at = EXCLUDED.updated_at`,
+			item.CartID, item.ProductID, item.Quantity, maxQuantity, item.Price, maxQuantity).Error
+		if err != nil {
+			return err
+		}
+		return touchCart(tx, item.CartID)
+	})
+}
+
+// SetItemQuantity змінює кількість товару в кошику. Повертає false, якщо товару немає в кошику.
+func (r *cartRepository) SetItemQuantity(ctx context.Context, cartID, productID uint, quantity int) (bool, error) {
+	return r.updateItem(ctx, cartID, productID, map[string]interface{}{"quantity": quantity})
+}
+
+// SetItemPrice замінює збережену ціну товару в кошику поточною
+func (r *cartRepository) SetItemPrice(ctx context.Context, cartID, productID uint, price float64) error {
+	_, err := r.updateItem(ctx, cartID, productID, map[string]interface{}{"price": price})
+	return err
+}
+
+func (r *cartRepository) updateItem(ctx context.Context, cartID, productID uint, values map[string]interface{}) (bool, error) {
+	var updated bool
+
+	err := dbFrom(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.CartItem{}).
+			Where("cart_id = ? AND product_id = ?", cartID, productID).
+			Updates(values)
+		if result.Error != nil {
+			return result.Error
+		}
+		updated = result.RowsAffected > 0
+		return touchCart(tx, cartID)
+	})
+
+	return updated, err
+}
+
+// RemoveItem видаляє товар з кошика. Повертає false, якщо товару немає в кошику.
+func (r *cartRepository) RemoveItem(ctx context.Context, cartID, productID uint) (bool, error) {
+	var removed bool
+
+	err := dbFrom(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("cart_id = ? AND product_id = ?", cartID, productID).Delete(&models.CartItem{})
+		if result.Error != nil {
+			return result.Error
+		}
+		removed = result.RowsAffected > 0
+		return touchCart(tx, cartID)
+	})
+
+	return removed, err
+}
+
+// Clear видаляє всі товари з кошика
+func (r *cartRepository) Clear(ctx context.Context, cartID uint) error {
+	return dbFrom(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("cart_id = ?", cartID).Delete(&models.CartItem{}).Error; err != nil {
+			return err
+		}
+		return touchCart(tx, cartID)
+	})
+}
+
+// AssignToUser перетворює кошик гостя на кошик користувача
+func (r *cartRepository) AssignToUser(ctx context.Context, cartID, userID uint) error {
+	return dbFrom(ctx, r.db).
+		Model(&models.Cart{}).
+		Where("id = ? AND user_id IS NULL", cartID).
+		Updates(map[string]interface{}{
+			"user_id":    userID,
+			"token_hash": nil,
+			"updated_at": time.Now(),
+		}).Error
+}
+
+// Merge переносить товари кошика fromID до кошика toID і видаляє кошик fromID.
+// Кількості однакових товарів додаються (не більше maxQuantity), ціна товару,
+// що вже є в кошику toID, зберігається.
+func (r *cartRepository) Merge(ctx context.Context, fromID, toID uint, maxQuantity int) error {
+	return dbFrom(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`INSERT INTO cart_items (cart_id, product_id, quantity, price, created_at, updated_at)
+			SELECT ?, product_id, LEAST(quantity, ?), price, created_at, NOW() FROM cart_items WHERE cart_id = ?
+			ON CONFLICT (cart_id, product_id) DO UPDATE
+			SET quantity = LEAST(cart_items.quantity + EXCLUDED.quantity, ?), updated_at = EXCLUDED.updated_at`,
+			toID, maxQuantity, fromID, maxQuantity).Error
+		if err != nil {
+			return err
+		}
+		if _, err := deleteCarts(tx, "id = ?", fromID); err != nil {
+			return err
+		}
+		return touchCart(tx, toID)
+	})
+}
+
+// DeleteGuestBefore видаляє кошики гостів, які не змінювалися з before
+func (r *cartRepository) DeleteGuestBefore(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+
+	err := dbFrom(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var err error
+		deleted, err = deleteCarts(tx, "user_id IS NULL AND updated_at < ?", before)
+		return err
+	})
+
+	return deleted, err
+}
+
+// deleteCarts видаляє кошики, що відповідають умові query, разом з їхніми товарами
+func deleteCarts(tx *gorm.DB, query string, args ...interface{}) (int64, error) {
+	ids := tx.Model(&models.Cart{}).Select("id").Where(query, args...)
+	if err := tx.Where("cart_id IN (?)", ids).Delete(&models.CartItem{}).Error; err != nil {
+		return 0, err
+	}
+
+	result := tx.Where(query, args...).Delete(&models.Cart{})
+	return result.RowsAffected, result.Error
+}
+
+// touchCart оновлює час останньої зміни кошика
+func touchCart(tx *gorm.DB, cartID uint) error {
+	return tx.Model(&models.Cart{}).Where("id = ?", cartID).Update("updated_at", time.Now()).Error
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"product-recommendations-go/internal/models"
+	"time"
+)
+
+type dataExportRepository struct {
+	db *gorm.DB
+}
+
+// NewDataExportRepository створює новий екземпляр репозиторію вивантажень даних
+func NewDataExportRepository(db *gorm.DB) DataExportRepository {
+	return &dataExportRepository{
+		db: db,
+	}
+}
+
+func (r *dataExportRepository) Create(ctx context.Context, export *models.DataExport) error {
+	return r.db.WithContext(ctx).Create(export).Error
+}
+
+// GetLatestByUserID повертає останній запит користувача або nil, якщо запитів не було
+func (r *dataExportRepository) GetLatestByUserID(ctx context.Context, userID uint) (*models.DataExport, error) {
+	var export models.DataExport
+
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").First(&export).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil // Запитів не було
+		}
+		return nil, err
+	}
+
+	return &export, nil
+}
+
+// ClaimNext бере в обробку найстаріший запит, що очікує, або запит, обробка якого
+// почалася до staleBefore (екземпляр, що його обробляв, зупинився). Рядки, які
+// вже обробляє інший екземпляр, пропускаються. Повертає nil, якщо черга порожня.
+func (r *dataExportRepository) ClaimNext(ctx context.Context, now, staleBefore time.Time) (*models.DataExport, error) {
+	var claimed *models.DataExport
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var export models.DataExport
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? OR (status = ? AND started_at < ?)",
+				models.DataExportPending, models.DataExportProcessing, staleBefore).
+			Order("id").
+			First(&export).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		export.Status = models.DataExportProcessing
+		export.StartedAt = &now
+		if err := tx.Save(&export).Error; err != nil {
+			return err
+		}
+		claimed = &export
+		return nil
+	})
+
+	return claimed, err
+}
+
+func (r *dataExportRepository) Update(ctx context.Context, export *models.DataExport) error {
+	return r.db.WithContext(ctx).Save(export).Error
+}
+
+// GetExpired повертає запити, термін зберігання яких закінчився до before
+func (r *dataExportRepository) GetExpired(ctx context.Context, before time.Time) ([]*models.DataExport, error) {
+	var exports []*models.DataExport
+
+	err := r.db.WithContext(ctx).
+		Where("expires_at < ?", before).
+		Find(&exports).Error
+
+	return exports, err
+}
+
+func (r *dataExportRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.DataExport{}, id).Error
+}
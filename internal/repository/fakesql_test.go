@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// stockDB база даних у пам'яті, що розуміє лише оновлення залишків товарів,
+// транзакції та точки збереження - достатньо для перевірки транзакційної
+// поведінки репозиторіїв без PostgreSQL
+type stockDB struct {
+	mu    sync.Mutex
+	stock map[uint]int
+	// snapshots стан на початку транзакції та кожної точки збереження
+	snapshots  []map[uint]int
+	savepoints map[string]int
+
+	begins, commits, rollbacks int
+}
+
+var (
+	stockDBs      sync.Map
+	stockDBNumber atomic.Int64
+)
+
+func init() {
+	sql.Register("stockdb", stockDriver{})
+}
+
+// newStockDB відкриває GORM з postgres-діалектом поверх бази з залишками stock
+func newStockDB(t *testing.T, stock map[uint]int) (*gorm.DB, *stockDB) {
+	t.Helper()
+	fake := &stockDB{stock: stock, savepoints: make(map[string]int)}
+	name := strconv.FormatInt(stockDBNumber.Add(1), 10)
+	stockDBs.Store(name, fake)
+
+	conn, err := sql.Open("stockdb", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Одне з'єднання, як у транзакції PostgreSQL
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, fake
+}
+
+// snapshot повертає копію залишків
+func (d *stockDB) snapshot() map[uint]int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return maps.Clone(d.stock)
+}
+
+var (
+	stockChange = regexp.MustCompile(`stock ([-+]) \$(\d+)`)
+	idFilter    = regexp.MustCompile(`id = \$(\d+)`)
+	stockFilter = regexp.MustCompile(`stock >= \$(\d+)`)
+)
+
+func (d *stockDB) exec(query string, args []driver.Value) (driver.Result, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(query, "SAVEPOINT "):
+		d.savepoints[strings.TrimPrefix(query, "SAVEPOINT ")] = len(d.snapshots)
+		d.snapshots = append(d.snapshots, maps.Clone(d.stock))
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(query, "ROLLBACK TO SAVEPOINT "):
+		i, ok := d.savepoints[strings.TrimPrefix(query, "ROLLBACK TO SAVEPOINT ")]
+		if !ok {
+			return nil, fmt.Errorf("unknown savepoint in %q", query)
+		}
+		d.stock = maps.Clone(d.snapshots[i])
+		d.snapshots = d.snapshots[:i+1]
+		return driver.RowsAffected(0), nil
+	}
+
+	change := stockChange.FindStringSubmatch(query)
+	id := idFilter.FindStringSubmatch(query)
+	if !strings.HasPrefix(query, `UPDATE "products"`) || change == nil || id == nil {
+		return nil, fmt.Errorf("unexpected statement %q", query)
+	}
+	arg := func(number string) int {
+		n, _ := strconv.Atoi(number)
+		v, _ := args[n-1].(int64)
+		return int(v)
+	}
+
+	productID := uint(arg(id[1]))
+	stock, exists := d.stock[productID]
+	if !exists {
+		return driver.RowsAffected(0), nil
+	}
+	if min := stockFilter.FindStringSubmatch(query); min != nil && stock < arg(min[1]) {
+		return driver.RowsAffected(0), nil
+	}
+
+	quantity := arg(change[2])
+	if change[1] == "-" {
+		quantity = -quantity
+	}
+	d.stock[productID] = stock + quantity
+	return driver.RowsAffected(1), nil
+}
+
+func (d *stockDB) begin() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.begins++
+	d.snapshots = []map[uint]int{maps.Clone(d.stock)}
+	clear(d.savepoints)
+}
+
+func (d *stockDB) end(commit bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if commit {
+		d.commits++
+	} else {
+		d.rollbacks++
+		d.stock = d.snapshots[0]
+	}
+	d.snapshots = nil
+}
+
+type stockDriver struct{}
+
+func (stockDriver) Open(name string) (driver.Conn, error) {
+	db, ok := stockDBs.Load(name)
+	if !ok {
+		return nil, errors.New("unknown stock database")
+	}
+	return &stockConn{db: db.(*stockDB)}, nil
+}
+
+type stockConn struct {
+	db *stockDB
+}
+
+func (c *stockConn) Prepare(query string) (driver.Stmt, error) {
+	return &stockStmt{db: c.db, query: query}, nil
+}
+
+func (c *stockConn) Close() error {
+	return nil
+}
+
+func (c *stockConn) Begin() (driver.Tx, error) {
+	c.db.begin()
+	return &stockTx{db: c.db}, nil
+}
+
+type stockTx struct {
+	db *stockDB
+}
+
+func (t *stockTx) Commit() error {
+	t.db.end(true)
+	return nil
+}
+
+func (t *stockTx) Rollback() error {
+	t.db.end(false)
+	return nil
+}
+
+type stockStmt struct {
+	db    *stockDB
+	query string
+}
+
+func (s *stockStmt) Close() error {
+	return nil
+}
+
+func (s *stockStmt) NumInput() int {
+	return -1
+}
+
+func (s *stockStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.db.exec(s.query, args)
+}
+
+func (s *stockStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, fmt.Errorf("unexpected query %q", s.query)
+}
@@ -4,15 +4,26 @@ package repository
 import (
 	"context"
 	"product-recommendations-go/internal/models"
+	"time"
 )
 
+// Transactor виконує операції кількох репозиторіїв в одній транзакції
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	// AfterCommit відкладає fn до фіксації зовнішньої транзакції
+	AfterCommit(ctx context.Context, fn func(ctx context.Context))
+}
+
 // UserRepository інтерфейс для роботи з користувачами
 type UserRepository interface {
 	Create(ctx context.Context, user *models.User) error
 	GetByID(ctx context.Context, id uint) (*models.User, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
-	Update(ctx context.Context, user *models.User) error
+	UpdateColumns(ctx context.Context, user *models.User, columns ...string) error
 	Delete(ctx context.Context, id uint) error
+	AdvanceTOTPStep(ctx context.Context, id uint, step int64) (bool, error)
+	EnableTOTP(ctx context.Context, id uint, secret string, enabledAt time.Time) (bool, error)
+	Anonymize(ctx context.Context, id uint, placeholderEmail string, deletedAt time.Time) error
 }
 
 // ProductRepository інтерфейс для роботи з товарами
@@ -22,6 +33,13 @@ type ProductRepository interface {
 	GetAll(ctx context.Context, page, limit int) ([]*models.Product, int64, error)
 	Update(ctx context.Context, product *models.Product) error
 	Delete(ctx context.Context, id uint) error
+	Restore(ctx context.Context, id uint) (*models.Product, error)
+	GetForUpdate(ctx context.Context, ids []uint) ([]*models.Product, error)
+	ReserveStock(ctx context.Context, quantities map[uint]int) (uint, error)
+	ReleaseStock(ctx context.Context, quantities map[uint]int) error
+	AdjustStock(ctx context.Context, id uint, delta int) (bool, error)
+	SetStock(ctx context.Context, id uint, stock int) (bool, error)
+	GetLowStock(ctx context.Context, threshold, limit int) ([]*models.Product, error)
 }
 
 // UserLikeRepository інтерфейс для роботи з вподобаннями
@@ -29,6 +47,7 @@ type UserLikeRepository interface {
 	Create(ctx context.Context, like *models.UserLike) error
 	Delete(ctx context.Context, userID, productID uint) error
 	GetByUserID(ctx context.Context, userID uint) ([]*models.UserLike, error)
+	GetAll(ctx context.Context) ([]*models.UserLike, error)
 	Exists(ctx context.Context, userID, productID uint) (bool, error)
 }
 
@@ -37,5 +56,120 @@ type OrderRepository interface {
 	Create(ctx context.Context, order *models.Order) error
 	GetByID(ctx context.Context, id uint) (*models.Order, error)
 	GetByUserID(ctx context.Context, userID uint) ([]*models.Order, error)
+	GetAll(ctx context.Context) ([]*models.Order, error)
 	AddItem(ctx context.Context, orderItem *models.OrderItem) error
+	UpdateStatus(ctx context.Context, change *models.OrderStatusChange) (bool, error)
+	// GetCoPurchaseCounts повертає для товарів, які купували разом з productIDs, кількість
+	// таких замовлень (до limit товарів з найбільшою кількістю)
+	GetCoPurchaseCounts(ctx context.Context, productIDs []uint, limit int) (map[uint]int, error)
+	// ForEachPurchase передає fn покупки всіх користувачів у порядку користувача, товару
+	// і часу, читаючи їх курсором. Помилка fn зупиняє читання і повертається.
+	ForEachPurchase(ctx context.Context, fn func(purchase *models.Purchase) error) error
+}
+
+// RevokedTokenRepository інтерфейс для роботи зі списком відкликаних токенів
+type RevokedTokenRepository interface {
+	Create(ctx context.Context, token *models.RevokedToken) error
+	FindRevoked(ctx context.Context, ids []string) ([]string, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// RefreshTokenRepository інтерфейс для роботи з refresh токенами
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *models.RefreshToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string, revokedAt time.Time) error
+	ActiveFamilies(ctx context.Context, userID uint, now time.Time) ([]string, error)
+	GetByUserID(ctx context.Context, userID uint) ([]*models.RefreshToken, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// UserTokenRepository інтерфейс для роботи з одноразовими токенами користувачів
+type UserTokenRepository interface {
+	Create(ctx context.Context, token *models.UserToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*models.UserToken, error)
+	MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error)
+	InvalidateUser(ctx context.Context, userID uint, purpose models.TokenPurpose, usedAt time.Time) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// LoginAttemptRepository інтерфейс для роботи з журналом невдалих спроб входу
+type LoginAttemptRepository interface {
+	Create(ctx context.Context, attempt *models.LoginAttempt) error
+	GetByUserID(ctx context.Context, userID uint) ([]*models.LoginAttempt, error)
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// LoginFailureStore сховище лічильників невдалих спроб входу за ключем
+type LoginFailureStore interface {
+	// Reserve атомарно враховує спробу для ключа в момент at і повертає кількість
+	// спроб, врахованих до неї, та час попередньої з них. Якщо попередня спроба була
+	// раніше since, лічильник починається заново.
+	Reserve(ctx context.Context, key string, at, since time.Time) (int, time.Time, error)
+	// Release скасовує спробу, враховану Reserve в момент at; previous - час
+	// попередньої спроби, повернутий Reserve
+	Release(ctx context.Context, key string, at, previous time.Time) error
+	// Reset скидає лічильник ключа
+	Reset(ctx context.Context, key string) error
+	// DeleteBefore видаляє спроби, старші за before
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// RecoveryCodeRepository інтерфейс для роботи з резервними кодами двофакторної автентифікації
+type RecoveryCodeRepository interface {
+	Replace(ctx context.Context, userID uint, codes []*models.RecoveryCode) error
+	Use(ctx context.Context, userID uint, codeHash string, usedAt time.Time) (bool, error)
+	CountUnused(ctx context.Context, userID uint) (int64, error)
+}
+
+// UserIdentityRepository інтерфейс для роботи з обліковими записами у зовнішніх постачальників ідентичності
+type UserIdentityRepository interface {
+	Create(ctx context.Context, identity *models.UserIdentity) error
+	Get(ctx context.Context, provider, subject string) (*models.UserIdentity, error)
+	GetByUserID(ctx context.Context, userID uint) ([]*models.UserIdentity, error)
+	Update(ctx context.Context, identity *models.UserIdentity) error
+}
+
+// OIDCStateRepository інтерфейс для роботи зі станами незавершених входів через OpenID Connect
+type OIDCStateRepository interface {
+	Create(ctx context.Context, state *models.OIDCLoginState) error
+	Consume(ctx context.Context, stateHash string) (*models.OIDCLoginState, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// APIKeyRepository інтерфейс для роботи з API ключами
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	GetByUserID(ctx context.Context, userID uint) ([]*models.APIKey, error)
+	Revoke(ctx context.Context, id, userID uint, revokedAt time.Time) (bool, error)
+	TouchLastUsed(ctx context.Context, id uint, usedAt time.Time, interval time.Duration) error
+}
+
+// DataExportRepository інтерфейс для роботи з запитами на вивантаження даних користувачів
+type DataExportRepository interface {
+	Create(ctx context.Context, export *models.DataExport) error
+	GetLatestByUserID(ctx context.Context, userID uint) (*models.DataExport, error)
+	ClaimNext(ctx context.Context, now, staleBefore time.Time) (*models.DataExport, error)
+	Update(ctx context.Context, export *models.DataExport) error
+	GetExpired(ctx context.Context, before time.Time) ([]*models.DataExport, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+// CartRepository інтерфейс для роботи з кошиками покупок
+type CartRepository interface {
+	Create(ctx context.Context, cart *models.Cart) error
+	GetByUserID(ctx context.Context, userID uint) (*models.Cart, error)
+	GetByTokenHash(ctx context.Context, tokenHash string) (*models.Cart, error)
+	GetByUserIDForUpdate(ctx context.Context, userID uint) (*models.Cart, error)
+	GetByTokenHashForUpdate(ctx context.Context, tokenHash string) (*models.Cart, error)
+	AddItem(ctx context.Context, item *models.CartItem, maxQuantity int) error
+	SetItemQuantity(ctx context.Context, cartID, productID uint, quantity int) (bool, error)
+	SetItemPrice(ctx context.Context, cartID, productID uint, price float64) error
+	RemoveItem(ctx context.Context, cartID, productID uint) (bool, error)
+	Clear(ctx context.Context, cartID uint) error
+	AssignToUser(ctx context.Context, cartID, userID uint) error
+	Merge(ctx context.Context, fromID, toID uint, maxQuantity int) error
+	DeleteGuestBefore(ctx context.Context, before time.Time) (int64, error)
 }
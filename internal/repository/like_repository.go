@@ -50,6 +50,16 @@ func (r *userLikeRepository) GetByUserID(ctx context.Context, userID uint) ([]*m
 	return likes, nil
 }
 
+func (r *userLikeRepository) GetAll(ctx context.Context) ([]*models.UserLike, error) {
+	var likes []*models.UserLike
+
+	if err := r.db.WithContext(ctx).Find(&likes).Error; err != nil {
+		return nil, err
+	}
+
+	return likes, nil
+}
+
 func (r *userLikeRepository) Exists(ctx context.Context, userID, productID uint) (bool, error) {
 	var count int64
 
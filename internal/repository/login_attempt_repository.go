@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"product-recommendations-go/internal/models"
+	"time"
+)
+
+type loginAttemptRepository struct {
+	db *gorm.DB
+}
+
+// NewLoginAttemptRepository створює новий екземпляр репозиторію журналу невдалих входів
+func NewLoginAttemptRepository(db *gorm.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{
+		db: db,
+	}
+}
+
+func (r *loginAttemptRepository) Create(ctx context.Context, attempt *models.LoginAttempt) error {
+	return r.db.WithContext(ctx).Create(attempt).Error
+}
+
+// GetByUserID повертає записи журналу, пов'язані з користувачем, від найновіших
+func (r *loginAttemptRepository) GetByUserID(ctx context.Context, userID uint) ([]*models.LoginAttempt, error) {
+	var attempts []*models.LoginAttempt
+
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&attempts).Error
+
+	return attempts, err
+}
+
+// DeleteBefore видаляє записи, створені до before
+func (r *loginAttemptRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("created_at < ?", before).
+		Delete(&models.LoginAttempt{})
+	return result.RowsAffected, result.Error
+}
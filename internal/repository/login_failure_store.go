@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"gorm.io/gorm"
+	"product-recommendations-go/internal/models"
+	"sync"
+	"time"
+)
+
+type dbLoginFailureStore struct {
+	db *gorm.DB
+}
+
+// NewDBLoginFailureStore створює сховище лічильників невдалих входів у базі даних,
+// спільне для всіх екземплярів API
+func NewDBLoginFailureStore(db *gorm.DB) LoginFailureStore {
+	return &dbLoginFailureStore{
+		db: db,
+	}
+}
+
+func (s *dbLoginFailureStore) Reserve(ctx context.Context, key string, at, since time.Time) (int, time.Time, error) {
+	// Підрахунок і читання виконуються одним запитом, тому паралельні спроби
+	// отримують різні значення лічильника
+	var row struct {
+		Failures   int
+		PreviousAt sql.NullTime
+	}
+	err := s.db.WithContext(ctx).Raw(`INSERT INTO login_failure_counters (key, failures, last_at, previous_at)
+		VALUES (?, 1, ?, NULL)
+		ON CONFLICT (key) DO UPDATE
+		SET failures = CASE WHEN login_failure_counters.last_at < ? THEN 1 ELSE login_failure_counters.failures + 1 END,
+			previous_at = CASE WHEN login_failure_counters.last_at < ? THEN NULL ELSE login_failure_counters.last_at END,
+			last_at = GREATEST(login_failure_counters.last_at, EXCLUDED.last_at)
+		RETURNING failures, previous_at`,
+		key, at, since, since).Scan(&row).Error
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return row.Failures - 1, row.PreviousAt.Time, nil
+}
+
+func (s *dbLoginFailureStore) Release(ctx context.Context, key string, at, previous time.Time) error {
+	// Час останньої спроби відновлюється, лише якщо після at інших спроб не було
+	return s.db.WithContext(ctx).Exec(`UPDATE login_failure_counters
+		SET failures = GREATEST(failures - 1, 0),
+			last_at = CASE WHEN last_at = ? THEN COALESCE(?, last_at) ELSE last_at END
+		WHERE key = ?`,
+		at, sql.NullTime{Time: previous, Valid: !previous.IsZero()}, key).Error
+}
+
+func (s *dbLoginFailureStore) Reset(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).Where("key = ?", key).Delete(&models.LoginFailureCounter{}).Error
+}
+
+func (s *dbLoginFailureStore) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).
+		Where("last_at < ?", before).
+		Delete(&models.LoginFailureCounter{})
+	return result.RowsAffected, result.Error
+}
+
+// memoryLoginFailureCounter лічильник ключа у сховищі в пам'яті
+type memoryLoginFailureCounter struct {
+	failures int
+	last     time.Time
+}
+
+type memoryLoginFailureStore struct {
+	mu       sync.Mutex
+	counters map[string]*memoryLoginFailureCounter
+}
+
+// NewMemoryLoginFailureStore створює сховище лічильників невдалих входів у пам'яті
+// процесу. Кожен екземпляр API рахує спроби окремо, тому при кількох репліках
+// зловмисник отримує відповідно більше спроб.
+func NewMemoryLoginFailureStore() LoginFailureStore {
+	return &memoryLoginFailureStore{
+		counters: make(map[string]*memoryLoginFailureCounter),
+	}
+}
+
+func (s *memoryLoginFailureStore) Reserve(_ context.Context, key string, at, since time.Time) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter := s.counters[key]
+	if counter == nil || counter.last.Before(since) {
+		counter = &memoryLoginFailureCounter{}
+		s.counters[key] = counter
+	}
+
+	failures, previous := counter.failures, counter.last
+	counter.failures++
+	if at.After(counter.last) {
+		counter.last = at
+	}
+	return failures, previous, nil
+}
+
+func (s *memoryLoginFailureStore) Release(_ context.Context, key string, at, previous time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter := s.counters[key]
+	if counter == nil {
+		return nil
+	}
+	if counter.failures > 0 {
+		counter.failures--
+	}
+	if counter.last.Equal(at) && !previous.IsZero() {
+		counter.last = previous
+	}
+	return nil
+}
+
+func (s *memoryLoginFailureStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.counters, key)
+	return nil
+}
+
+func (s *memoryLoginFailureStore) DeleteBefore(_ context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for key, counter := range s.counters {
+		if counter.last.Before(before) {
+			delete(s.counters, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
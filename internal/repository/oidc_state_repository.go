@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"product-recommendations-go/internal/models"
+	"time"
+)
+
+type oidcStateRepository struct {
+	db *gorm.DB
+}
+
+// NewOIDCStateRepository створює новий екземпляр репозиторію станів входу через OpenID Connect
+func NewOIDCStateRepository(db *gorm.DB) OIDCStateRepository {
+	return &oidcStateRepository{
+		db: db,
+	}
+}
+
+func (r *oidcStateRepository) Create(ctx context.Context, state *models.OIDCLoginState) error {
+	return r.db.WithContext(ctx).Create(state).Error
+}
+
+// Consume видаляє стан і повертає його. Видалення атомарне: з двох паралельних
+// зворотних викликів з тим самим state стан отримує лише один. Повертає nil,
+// якщо стан не знайдено.
+func (r *oidcStateRepository) Consume(ctx context.Context, stateHash string) (*models.OIDCLoginState, error) {
+	var states []models.OIDCLoginState
+
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Returning{}).
+		Where("state_hash = ?", stateHash).
+		Delete(&states).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, nil // Стан не знайдено або вже використано
+	}
+
+	return &states[0], nil
+}
+
+// DeleteExpired видаляє стани входів, не завершених до before
+func (r *oidcStateRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("expires_at < ?", before).
+		Delete(&models.OIDCLoginState{})
+	return result.RowsAffected, result.Error
+}
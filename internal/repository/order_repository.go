@@ -18,16 +18,28 @@ func NewOrderRepository(db *gorm.DB) OrderRepository {
 	}
 }
 
+// Create зберігає замовлення з товарами і перший запис історії статусів
 func (r *orderRepository) Create(ctx context.Context, order *models.Order) error {
-	return r.db.WithContext(ctx).Create(order).Error
+	return dbFrom(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("History").Create(order).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.OrderStatusChange{
+			OrderID:   order.ID,
+			ToStatus:  order.Status,
+			ChangedBy: &order.UserID,
+			CreatedAt: order.CreatedAt,
+		}).Error
+	})
 }
 
 func (r *orderRepository) GetByID(ctx context.Context, id uint) (*models.Order, error) {
 	var order models.Order
 
-	if err := r.db.WithContext(ctx).
+	if err := dbFrom(ctx, r.db).
 		Preload("Items").
 		Preload("Items.Product").
+		Preload("History", func(db *gorm.DB) *gorm.DB { return db.Order("order_status_changes.id") }).
 		First(&order, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil // Замовлення не знайдено
@@ -41,7 +53,7 @@ func (r *orderRepository) GetByID(ctx context.Context, id uint) (*models.Order,
 func (r *orderRepository) GetByUserID(ctx context.Context, userID uint) ([]*models.Order, error) {
 	var orders []*models.Order
 
-	if err := r.db.WithContext(ctx).
+	if err := dbFrom(ctx, r.db).
 		Preload("Items").
 		Preload("Items.Product").
 		Where("user_id = ?", userID).
@@ -52,6 +64,92 @@ func (r *orderRepository) GetByUserID(ctx context.Context, userID uint) ([]*mode
 	return orders, nil
 }
 
+func (r *orderRepository) GetAll(ctx context.Context) ([]*models.Order, error) {
+	var orders []*models.Order
+
+	if err := dbFrom(ctx, r.db).
+		Preload("Items").
+		Find(&orders).Error; err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
 func (r *orderRepository) AddItem(ctx context.Context, orderItem *models.OrderItem) error {
-	return r.db.WithContext(ctx).Create(orderItem).Error
+	return dbFrom(ctx, r.db).Create(orderItem).Error
+}
+
+// UpdateStatus змінює статус замовлення з change.FromStatus на change.ToStatus і
+// додає запис історії. Повертає false, якщо статус замовлення вже не FromStatus
+// (наприклад, його змінив паралельний запит).
+func (r *orderRepository) UpdateStatus(ctx context.Context, change *models.OrderStatusChange) (bool, error) {
+	var updated bool
+
+	err := dbFrom(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND status = ?", change.OrderID, change.FromStatus).
+			Update("status", change.ToStatus)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		updated = true
+		return tx.Create(change).Error
+	})
+
+	return updated, err
+}
+
+func (r *orderRepository) GetCoPurchaseCounts(ctx context.Context, productIDs []uint, limit int) (map[uint]int, error) {
+	var rows []struct {
+		ProductID  uint
+		OrderCount int
+	}
+
+	err := dbFrom(ctx, r.db).
+		Table("order_items AS other").
+		Select("other.product_id, COUNT(DISTINCT other.order_id) AS order_count").
+		Joins("JOIN order_items AS seed ON seed.order_id = other.order_id").
+		Joins("JOIN orders ON orders.id = other.order_id AND orders.deleted_at IS NULL AND orders.status NOT IN ?",
+			[]models.OrderStatus{models.OrderStatusCancelled, models.OrderStatusRefunded}).
+		Where("seed.product_id IN ? AND other.product_id NOT IN ?", productIDs, productIDs).
+		Group("other.product_id").
+		Order("order_count DESC").
+		Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		counts[row.ProductID] = row.OrderCount
+	}
+	return counts, nil
+}
+
+func (r *orderRepository) ForEachPurchase(ctx context.Context, fn func(purchase *models.Purchase) error) error {
+	db := dbFrom(ctx, r.db)
+	rows, err := db.
+		Table("order_items").
+		Select("orders.user_id, order_items.product_id, orders.created_at AS purchased_at").
+		Joins("JOIN orders ON orders.id = order_items.order_id AND orders.deleted_at IS NULL AND orders.status NOT IN ?",
+			[]models.OrderStatus{models.OrderStatusCancelled, models.OrderStatusRefunded}).
+		Order("orders.user_id, order_items.product_id, orders.created_at").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var purchase models.Purchase
+		if err := db.ScanRows(rows, &purchase); err != nil {
+			return err
+		}
+		if err := fn(&purchase); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
 }
@@ -4,9 +4,14 @@ import (
 	"context"
 	"errors"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"product-recommendations-go/internal/models"
+	"sort"
 )
 
+// errStockShortage відкочує транзакцію ReserveStock, якщо товару не вистачає
+var errStockShortage = errors.New("stock shortage")
+
 type productRepository struct {
 	db *gorm.DB
 }
@@ -19,13 +24,13 @@ func NewProductRepository(db *gorm.DB) ProductRepository {
 }
 
 func (r *productRepository) Create(ctx context.Context, product *models.Product) error {
-	return r.db.WithContext(ctx).Create(product).Error
+	return dbFrom(ctx, r.db).Create(product).Error
 }
 
 func (r *productRepository) GetByID(ctx context.Context, id uint) (*models.Product, error) {
 	var product models.Product
 
-	if err := r.db.WithContext(ctx).First(&product, id).Error; err != nil {
+	if err := dbFrom(ctx, r.db).First(&product, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil // Продукт не знайдено
 		}
@@ -40,7 +45,7 @@ func (r *productRepository) GetAll(ctx context.Context, page, limit int) ([]*mod
 	var totalCount int64
 
 	// Рахуємо загальну кількість продуктів
-	if err := r.db.WithContext(ctx).Model(&models.Product{}).Count(&totalCount).Error; err != nil {
+	if err := dbFrom(ctx, r.db).Model(&models.Product{}).Count(&totalCount).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -56,8 +61,9 @@ func (r *productRepository) GetAll(ctx context.Context, page, limit int) ([]*mod
 	// Обчислюємо зміщення
 	offset := (page - 1) * limit
 
-	// Отримуємо продукти з пагінацією
-	if err := r.db.WithContext(ctx).
+	// Отримуємо продукти з пагінацією; сталий порядок потрібен, щоб сторінки не перетиналися
+	if err := dbFrom(ctx, r.db).
+		Order("id").
 		Offset(offset).
 		Limit(limit).
 		Find(&products).Error; err != nil {
@@ -67,10 +73,137 @@ func (r *productRepository) GetAll(ctx context.Context, page, limit int) ([]*mod
 	return products, totalCount, nil
 }
 
+// Update зберігає дані товару. Залишок не перезаписується: він змінюється лише
+// атомарними операціями нижче, щоб не втратити паралельні резервування.
 func (r *productRepository) Update(ctx context.Context, product *models.Product) error {
-	return r.db.WithContext(ctx).Save(product).Error
+	return dbFrom(ctx, r.db).Omit("stock").Save(product).Error
 }
 
 func (r *productRepository) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&models.Product{}, id).Error
+	return dbFrom(ctx, r.db).Delete(&models.Product{}, id).Error
+}
+
+// Restore відновлює м'яко видалений товар. Повертає nil, якщо видаленого товару з таким ID немає.
+func (r *productRepository) Restore(ctx context.Context, id uint) (*models.Product, error) {
+	result := dbFrom(ctx, r.db).
+		Unscoped().
+		Model(&models.Product{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil // Видалений продукт не знайдено
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// GetForUpdate завантажує товари каталогу з блокуванням рядків (SELECT ... FOR UPDATE)
+// до кінця транзакції, тож їхні ціни та залишки не зміняться, доки вона не
+// завершиться. Рядки блокуються за зростанням ID. Має викликатися всередині
+// Transactor.WithinTransaction; товари, яких немає, пропускаються.
+func (r *productRepository) GetForUpdate(ctx context.Context, ids []uint) ([]*models.Product, error) {
+	var products []*models.Product
+
+	err := dbFrom(ctx, r.db).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", ids).
+		Order("id").
+		Find(&products).Error
+
+	return products, err
+}
+
+// ReserveStock списує зі складу кількості quantities (ID товару -> кількість) в
+// одній транзакції. Кожне списання - умовний UPDATE, що блокує рядок товару;
+// товари обробляються за зростанням ID, тому паралельні резервування не
+// взаємоблокуються. Повертає ID першого товару, якого не вистачає (або який
+// видалено), і 0, якщо все зарезервовано.
+func (r *productRepository) ReserveStock(ctx context.Context, quantities map[uint]int) (uint, error) {
+	productIDs := make([]uint, 0, len(quantities))
+	for id := range quantities {
+		productIDs = append(productIDs, id)
+	}
+	sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+
+	var shortID uint
+	err := dbFrom(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		for _, id := range productIDs {
+			quantity := quantities[id]
+			result := tx.Model(&models.Product{}).
+				Where("id = ? AND stock >= ?", id, quantity).
+				Update("stock", gorm.Expr("stock - ?", quantity))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				shortID = id
+				return errStockShortage
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errStockShortage) {
+		return shortID, nil
+	}
+
+	return 0, err
+}
+
+// ReleaseStock повертає на склад кількості quantities, зокрема для товарів,
+// видалених з каталогу після оформлення замовлення
+func (r *productRepository) ReleaseStock(ctx context.Context, quantities map[uint]int) error {
+	productIDs := make([]uint, 0, len(quantities))
+	for id := range quantities {
+		productIDs = append(productIDs, id)
+	}
+	sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+
+	return dbFrom(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		for _, id := range productIDs {
+			err := tx.Unscoped().
+				Model(&models.Product{}).
+				Where("id = ?", id).
+				Update("stock", gorm.Expr("stock + ?", quantities[id])).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AdjustStock змінює залишок товару на delta. Повертає false, якщо товару немає
+// або залишок став би від'ємним.
+func (r *productRepository) AdjustStock(ctx context.Context, id uint, delta int) (bool, error) {
+	result := dbFrom(ctx, r.db).
+		Model(&models.Product{}).
+		Where("id = ? AND stock + ? >= 0", id, delta).
+		Update("stock", gorm.Expr("stock + ?", delta))
+	return result.RowsAffected > 0, result.Error
+}
+
+// SetStock встановлює залишок товару. Повертає false, якщо товару немає.
+func (r *productRepository) SetStock(ctx context.Context, id uint, stock int) (bool, error) {
+	result := dbFrom(ctx, r.db).
+		Model(&models.Product{}).
+		Where("id = ?", id).
+		Update("stock", stock)
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetLowStock повертає товари каталогу із залишком не більше threshold,
+// починаючи з найменшого залишку
+func (r *productRepository) GetLowStock(ctx context.Context, threshold, limit int) ([]*models.Product, error) {
+	var products []*models.Product
+
+	err := dbFrom(ctx, r.db).
+		Where("stock <= ?", threshold).
+		Order("stock, id").
+		Limit(limit).
+		Find(&products).Error
+
+	return products, err
 }
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReserveStock(t *testing.T) {
+	tests := []struct {
+		name       string
+		quantities map[uint]int
+		wantShort  uint
+		wantStock  map[uint]int
+	}{
+		{
+			name:       "enough stock",
+			quantities: map[uint]int{1: 2, 2: 1},
+			wantStock:  map[uint]int{1: 3, 2: 0, 3: 1},
+		},
+		{
+			name:       "last units",
+			quantities: map[uint]int{1: 5, 2: 1, 3: 1},
+			wantStock:  map[uint]int{1: 0, 2: 0, 3: 0},
+		},
+		{
+			// Товар 1 списується першим і повертається відкатом
+			name:       "insufficient stock rolls back earlier items",
+			quantities: map[uint]int{1: 2, 3: 2},
+			wantShort:  3,
+			wantStock:  map[uint]int{1: 5, 2: 1, 3: 1},
+		},
+		{
+			name:       "sold out",
+			quantities: map[uint]int{1: 1, 2: 2},
+			wantShort:  2,
+			wantStock:  map[uint]int{1: 5, 2: 1, 3: 1},
+		},
+		{
+			name:       "unknown product",
+			quantities: map[uint]int{1: 1, 9: 1},
+			wantShort:  9,
+			wantStock:  map[uint]int{1: 5, 2: 1, 3: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := newStockDB(t, map[uint]int{1: 5, 2: 1, 3: 1})
+			r := NewProductRepository(db)
+
+			short, err := r.ReserveStock(context.Background(), tt.quantities)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if short != tt.wantShort {
+				t.Errorf("got short product %d, want %d", short, tt.wantShort)
+			}
+			if got := fake.snapshot(); !reflect.DeepEqual(got, tt.wantStock) {
+				t.Errorf("got stock %v, want %v", got, tt.wantStock)
+			}
+		})
+	}
+}
+
+func TestReleaseStock(t *testing.T) {
+	db, fake := newStockDB(t, map[uint]int{1: 0, 2: 3})
+	r := NewProductRepository(db)
+
+	if err := r.ReleaseStock(context.Background(), map[uint]int{1: 2, 2: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fake.snapshot(), map[uint]int{1: 2, 2: 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got stock %v, want %v", got, want)
+	}
+}
+
+func TestReserveStockInsideTransaction(t *testing.T) {
+	db, fake := newStockDB(t, map[uint]int{1: 0, 2: 1})
+	r := NewProductRepository(db)
+	transactor := NewTransactor(db)
+	errCheckout := errors.New("checkout failed")
+
+	// Нестача відкочує лише резервування; решта зовнішньої транзакції зберігається
+	err := transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		if err := r.ReleaseStock(ctx, map[uint]int{1: 3}); err != nil {
+			return err
+		}
+		short, err := r.ReserveStock(ctx, map[uint]int{1: 1, 2: 2})
+		if err != nil {
+			return err
+		}
+		if short != 2 {
+			t.Errorf("got short product %d, want 2", short)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fake.snapshot(), map[uint]int{1: 3, 2: 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after commit: got stock %v, want %v", got, want)
+	}
+
+	// Помилка зовнішньої транзакції відкочує і успішне резервування
+	err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		if short, err := r.ReserveStock(ctx, map[uint]int{1: 1, 2: 1}); err != nil || short != 0 {
+			t.Fatalf("reserve: short %d, %v", short, err)
+		}
+		return errCheckout
+	})
+	if !errors.Is(err, errCheckout) {
+		t.Fatalf("got %v, want the checkout error", err)
+	}
+	if got, want := fake.snapshot(), map[uint]int{1: 3, 2: 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after rollback: got stock %v, want %v", got, want)
+	}
+}
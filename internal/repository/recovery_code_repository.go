@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"product-recommendations-go/internal/models"
+	"time"
+)
+
+type recoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+// NewRecoveryCodeRepository створює новий екземпляр репозиторію резервних кодів
+func NewRecoveryCodeRepository(db *gorm.DB) RecoveryCodeRepository {
+	return &recoveryCodeRepository{
+		db: db,
+	}
+}
+
+// Replace замінює всі резервні коди користувача новими
+func (r *recoveryCodeRepository) Replace(ctx context.Context, userID uint, codes []*models.RecoveryCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(codes).Error
+	})
+}
+
+// Use позначає невикористаний код користувача використаним. Повертає false,
+// якщо такого коду немає або його вже використано.
+func (r *recoveryCodeRepository) Use(ctx context.Context, userID uint, codeHash string, usedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", usedAt)
+	return result.RowsAffected == 1, result.Error
+}
+
+// CountUnused повертає кількість невикористаних кодів користувача
+func (r *recoveryCodeRepository) CountUnused(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"product-recommendations-go/internal/models"
+	"time"
+)
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewRefreshTokenRepository створює новий екземпляр репозиторію refresh токенів
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{
+		db: db,
+	}
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *refreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil // Токен не знайдено
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// MarkUsed позначає токен використаним. Повертає false, якщо токен уже був
+// використаний або відкликаний (зокрема паралельним запитом).
+func (r *refreshTokenRepository) MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", usedAt)
+	return result.RowsAffected == 1, result.Error
+}
+
+// RevokeFamily відкликає всі ще не відкликані токени родини
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID string, revokedAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", revokedAt).Error
+}
+
+// ActiveFamilies повертає сесії користувача, що мають невідкликані й не прострочені токени
+func (r *refreshTokenRepository) ActiveFamilies(ctx context.Context, userID uint, now time.Time) ([]string, error) {
+	var families []string
+	err := r.db.WithContext(ctx).
+		Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Distinct().
+		Pluck("family_id", &families).Error
+	return families, err
+}
+
+// GetByUserID повертає refresh токени користувача від найновіших
+func (r *refreshTokenRepository) GetByUserID(ctx context.Context, userID uint) ([]*models.RefreshToken, error) {
+	var tokens []*models.RefreshToken
+
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&tokens).Error
+
+	return tokens, err
+}
+
+// DeleteExpired видаляє токени, термін дії яких закінчився до before
+func (r *refreshTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("expires_at < ?", before).
+		Delete(&models.RefreshToken{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"product-recommendations-go/internal/models"
+	"time"
+)
+
+type revokedTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewRevokedTokenRepository створює новий екземпляр репозиторію відкликаних токенів
+func NewRevokedTokenRepository(db *gorm.DB) RevokedTokenRepository {
+	return &revokedTokenRepository{
+		db: db,
+	}
+}
+
+// Create додає токен до списку відкликаних; повторне відкликання не є помилкою
+func (r *revokedTokenRepository) Create(ctx context.Context, token *models.RevokedToken) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(token).Error
+}
+
+// FindRevoked повертає ті з ids, що є у списку відкликаних
+func (r *revokedTokenRepository) FindRevoked(ctx context.Context, ids []string) ([]string, error) {
+	var revoked []string
+	if err := r.db.WithContext(ctx).
+		Model(&models.RevokedToken{}).
+		Where("jti IN ?", ids).
+		Pluck("jti", &revoked).Error; err != nil {
+		return nil, err
+	}
+	return revoked, nil
+}
+
+// DeleteExpired видаляє записи токенів, термін дії яких закінчився до before
+func (r *revokedTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("expires_at < ?", before).
+		Delete(&models.RevokedToken{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+)
+
+// txContextKey ключ контексту, під яким зберігається відкрита транзакція
+type txContextKey struct{}
+
+// txState відкрита транзакція та дії, відкладені до її фіксації
+type txState struct {
+	tx          *gorm.DB
+	afterCommit []func(ctx context.Context)
+}
+
+type transactor struct {
+	db *gorm.DB
+}
+
+// NewTransactor створює новий екземпляр менеджера транзакцій
+func NewTransactor(db *gorm.DB) Transactor {
+	return &transactor{
+		db: db,
+	}
+}
+
+// WithinTransaction виконує fn в одній транзакції: репозиторії, викликані з
+// переданим у fn контекстом, працюють у цій транзакції. Транзакція фіксується,
+// якщо fn повертає nil, і відкочується в іншому разі. Виклик усередині вже
+// відкритої транзакції приєднується до неї.
+func (t *transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txContextKey{}).(*txState); ok {
+		return fn(ctx)
+	}
+
+	state := &txState{}
+	err := t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		state.tx = tx
+		return fn(context.WithValue(ctx, txContextKey{}, state))
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range state.afterCommit {
+		hook(ctx)
+	}
+	return nil
+}
+
+// AfterCommit виконує fn після фіксації зовнішньої транзакції, відкритої через
+// WithinTransaction, або одразу, якщо транзакція не відкрита. Якщо транзакцію
+// відкочено, fn не виконується.
+func (t *transactor) AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	if state, ok := ctx.Value(txContextKey{}).(*txState); ok {
+		state.afterCommit = append(state.afterCommit, fn)
+		return
+	}
+	fn(ctx)
+}
+
+// dbFrom повертає транзакцію з контексту, якщо вона відкрита через
+// WithinTransaction, або db
+func dbFrom(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if state, ok := ctx.Value(txContextKey{}).(*txState); ok {
+		return state.tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestWithinTransaction(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name string
+		// innerErr, outerErr помилки вкладеного і зовнішнього виклику
+		innerErr, outerErr error
+		wantErr            error
+		wantStock          map[uint]int
+		wantHooks          []string
+		wantCommits        int
+	}{
+		{
+			name:        "commit",
+			wantStock:   map[uint]int{1: 3},
+			wantHooks:   []string{"outer before", "inner", "outer after"},
+			wantCommits: 1,
+		},
+		{
+			name:      "outer error rolls back the joined call",
+			outerErr:  errFailed,
+			wantErr:   errFailed,
+			wantStock: map[uint]int{1: 5},
+		},
+		{
+			name:      "inner error rolls back the outer call",
+			innerErr:  errFailed,
+			wantErr:   errFailed,
+			wantStock: map[uint]int{1: 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := newStockDB(t, map[uint]int{1: 5})
+			r := NewProductRepository(db)
+			transactor := NewTransactor(db)
+
+			var hooks []string
+			hook := func(name string) func(ctx context.Context) {
+				return func(context.Context) {
+					// Дії виконуються лише після фіксації
+					if fake.commits != 1 {
+						t.Errorf("hook %q ran before commit", name)
+					}
+					hooks = append(hooks, name)
+				}
+			}
+
+			err := transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+				transactor.AfterCommit(ctx, hook("outer before"))
+				if short, err := r.ReserveStock(ctx, map[uint]int{1: 1}); err != nil || short != 0 {
+					t.Fatalf("reserve: short %d, %v", short, err)
+				}
+
+				err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					transactor.AfterCommit(ctx, hook("inner"))
+					if short, err := r.ReserveStock(ctx, map[uint]int{1: 1}); err != nil || short != 0 {
+						t.Fatalf("reserve: short %d, %v", short, err)
+					}
+					return tt.innerErr
+				})
+				if err != nil {
+					return err
+				}
+
+				transactor.AfterCommit(ctx, hook("outer after"))
+				return tt.outerErr
+			})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			// Вкладений виклик приєднується до зовнішньої транзакції
+			if fake.begins != 1 || fake.commits != tt.wantCommits {
+				t.Errorf("got %d transactions and %d commits, want 1 and %d", fake.begins, fake.commits, tt.wantCommits)
+			}
+			if got := fake.snapshot(); !reflect.DeepEqual(got, tt.wantStock) {
+				t.Errorf("got stock %v, want %v", got, tt.wantStock)
+			}
+			if !reflect.DeepEqual(hooks, tt.wantHooks) {
+				t.Errorf("got hooks %v, want %v", hooks, tt.wantHooks)
+			}
+		})
+	}
+}
+
+func TestAfterCommitWithoutTransaction(t *testing.T) {
+	db, fake := newStockDB(t, map[uint]int{})
+	transactor := NewTransactor(db)
+
+	ran := false
+	transactor.AfterCommit(context.Background(), func(context.Context) { ran = true })
+	if !ran || fake.begins != 0 {
+		t.Errorf("hook ran %v with %d transactions, want immediately without one", ran, fake.begins)
+	}
+}
+
+func TestAfterCommitHookSeesCommittedContext(t *testing.T) {
+	db, fake := newStockDB(t, map[uint]int{1: 5})
+	r := NewProductRepository(db)
+	transactor := NewTransactor(db)
+
+	// Контекст дії не несе завершеної транзакції: її запити виконуються поза нею
+	err := transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		transactor.AfterCommit(ctx, func(ctx context.Context) {
+			if err := r.ReleaseStock(ctx, map[uint]int{1: 1}); err != nil {
+				t.Error(err)
+			}
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.begins != 2 || fake.commits != 2 || fake.snapshot()[1] != 6 {
+		t.Errorf("got %d transactions, %d commits and stock %d", fake.begins, fake.commits, fake.snapshot()[1])
+	}
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"product-recommendations-go/internal/models"
+)
+
+type userIdentityRepository struct {
+	db *gorm.DB
+}
+
+// NewUserIdentityRepository створює новий екземпляр репозиторію зовнішніх облікових записів
+func NewUserIdentityRepository(db *gorm.DB) UserIdentityRepository {
+	return &userIdentityRepository{
+		db: db,
+	}
+}
+
+func (r *userIdentityRepository) Create(ctx context.Context, identity *models.UserIdentity) error {
+	return r.db.WithContext(ctx).Create(identity).Error
+}
+
+func (r *userIdentityRepository) Get(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+
+	if err := r.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil // Обліковий запис не прив'язано
+		}
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+func (r *userIdentityRepository) GetByUserID(ctx context.Context, userID uint) ([]*models.UserIdentity, error) {
+	var identities []*models.UserIdentity
+
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&identities).Error
+
+	return identities, err
+}
+
+func (r *userIdentityRepository) Update(ctx context.Context, identity *models.UserIdentity) error {
+	return r.db.WithContext(ctx).Save(identity).Error
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"gorm.io/gorm"
 	"product-recommendations-go/internal/models"
+	"time"
 )
 
 type userRepository struct {
@@ -44,10 +45,102 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.
 	return &user, nil
 }
 
-func (r *userRepository) Update(ctx context.Context, user *models.User) error {
-	return r.db.WithContext(ctx).Save(user).Error
+// UpdateColumns зберігає лише перелічені поля користувача (імена колонок). Запис
+// цілком не перезаписується, тож паралельні запити, що змінюють інші поля, не
+// втрачають змін одне одного.
+func (r *userRepository) UpdateColumns(ctx context.Context, user *models.User, columns ...string) error {
+	if len(columns) == 0 {
+		return errors.New("no columns to update")
+	}
+	return r.db.WithContext(ctx).Model(user).Select(columns).Updates(user).Error
 }
 
 func (r *userRepository) Delete(ctx context.Context, id uint) error {
 	return r.db.WithContext(ctx).Delete(&models.User{}, id).Error
 }
+
+// AdvanceTOTPStep запам'ятовує крок прийнятого коду TOTP. Повертає false, якщо
+// код цього або пізнішого кроку вже прийнято (зокрема паралельним запитом).
+func (r *userRepository) AdvanceTOTPStep(ctx context.Context, id uint, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+// EnableTOTP вмикає двофакторну автентифікацію з секретом secret. Повертає false,
+// якщо її вже увімкнено або секрет тим часом замінено новим підключенням: тоді
+// підтверджений код належить не тому секрету, що збережений.
+func (r *userRepository) EnableTOTP(ctx context.Context, id uint, secret string, enabledAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ? AND totp_secret = ? AND totp_enabled_at IS NULL", id, secret).
+		Update("totp_enabled_at", enabledAt)
+	return result.RowsAffected == 1, result.Error
+}
+
+// Anonymize видаляє обліковий запис користувача: вподобання, кошик, зовнішні облікові
+// записи, резервні коди, одноразові токени та журнал спроб входу (з адресами і IP)
+// видаляються, API ключі відкликаються, архіви вивантажених даних стають
+// простроченими, а в записі користувача персональні дані замінюються заглушкою
+// і запис позначається видаленим.
+// Замовлення залишаються прив'язаними до знеособленого запису, щоб не
+// спотворювати звітність і статистику покупок.
+func (r *userRepository) Anonymize(ctx context.Context, id uint, placeholderEmail string, deletedAt time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{
+			&models.UserLike{},
+			&models.UserIdentity{},
+			&models.RecoveryCode{},
+			&models.UserToken{},
+		} {
+			if err := tx.Where("user_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		if _, err := deleteCarts(tx, "user_id = ?", id); err != nil {
+			return err
+		}
+
+		// Спроби входу, зроблені до реєстрації, не прив'язані до користувача, тому
+		// видаляються і за його адресою (у журналі вона в нижньому регістрі)
+		err := tx.Where("user_id = ? OR email IN (SELECT LOWER(TRIM(email)) FROM users WHERE id = ?)", id, id).
+			Delete(&models.LoginAttempt{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.APIKey{}).
+			Where("user_id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", deletedAt).Error
+		if err != nil {
+			return err
+		}
+
+		// Готові архіви даних видаляються під час наступного очищення
+		err = tx.Model(&models.DataExport{}).
+			Where("user_id = ?", id).
+			Update("expires_at", deletedAt).Error
+		if err != nil {
+			return err
+		}
+
+		// Пароль замінюється рядком, який не є bcrypt хешем: увійти неможливо
+		return tx.Model(&models.User{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"email":             placeholderEmail,
+				"password":          "!",
+				"name":              "",
+				"locale":            "",
+				"preferences":       nil,
+				"pending_email":     "",
+				"email_verified_at": nil,
+				"totp_secret":       "",
+				"totp_enabled_at":   nil,
+				"deleted_at":        deletedAt,
+			}).Error
+	})
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"product-recommendations-go/internal/models"
+	"time"
+)
+
+type userTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewUserTokenRepository створює новий екземпляр репозиторію одноразових токенів користувачів
+func NewUserTokenRepository(db *gorm.DB) UserTokenRepository {
+	return &userTokenRepository{
+		db: db,
+	}
+}
+
+func (r *userTokenRepository) Create(ctx context.Context, token *models.UserToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *userTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.UserToken, error) {
+	var token models.UserToken
+
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil // Токен не знайдено
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// MarkUsed позначає токен використаним. Повертає false, якщо токен уже був
+// використаний (зокрема паралельним запитом).
+func (r *userTokenRepository) MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.UserToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	return result.RowsAffected == 1, result.Error
+}
+
+// InvalidateUser позначає використаними всі невикористані токени користувача з призначенням purpose
+func (r *userTokenRepository) InvalidateUser(ctx context.Context, userID uint, purpose models.TokenPurpose, usedAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", usedAt).Error
+}
+
+// DeleteExpired видаляє токени, термін дії яких закінчився до before
+func (r *userTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("expires_at < ?", before).
+		Delete(&models.UserToken{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,65 @@
+// Package secretbox шифрує секрети, що зберігаються в базі даних, ключем сервера
+// (AES-256-GCM). Викрадена копія бази даних без ключа не розкриває секретів.
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// KeySize довжина ключа в байтах
+const KeySize = 32
+
+// ErrInvalidCiphertext шифротекст пошкоджено, зашифровано іншим ключем або для іншого запису
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+// Box шифрує та розшифровує секрети одним ключем
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New створює Box з ключем довжиною KeySize
+func New(key []byte) (*Box, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("secretbox key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// Seal шифрує plaintext і повертає шифротекст у Base64. associated - дані, до яких
+// прив'язується шифротекст (наприклад, ID запису): з іншими даними Open його не прийме,
+// тож секрет не можна переставити в інший запис.
+func (b *Box) Seal(plaintext, associated string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), []byte(associated))
+	return base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open розшифровує шифротекст, створений Seal з тими самими associated
+func (b *Box) Open(ciphertext, associated string) (string, error) {
+	sealed, err := base64.RawStdEncoding.DecodeString(ciphertext)
+	if err != nil || len(sealed) < b.aead.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+	nonce, sealed := sealed[:b.aead.NonceSize()], sealed[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, sealed, []byte(associated))
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,66 @@
+package secretbox
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	box, err := New(bytes.Repeat([]byte{1}, KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := New(bytes.Repeat([]byte{2}, KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := box.Seal("JBSWY3DPEHPK3PXP", "user:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(sealed, "JBSWY3DPEHPK3PXP") {
+		t.Fatal("ciphertext contains the plaintext")
+	}
+	if again, _ := box.Seal("JBSWY3DPEHPK3PXP", "user:1"); again == sealed {
+		t.Error("equal plaintexts produce equal ciphertexts")
+	}
+
+	tampered := "A" + sealed[1:]
+	if sealed[0] == 'A' {
+		tampered = "B" + sealed[1:]
+	}
+
+	tests := []struct {
+		name       string
+		box        *Box
+		ciphertext string
+		associated string
+		want       string
+		wantErr    error
+	}{
+		{name: "same key and record", box: box, ciphertext: sealed, associated: "user:1", want: "JBSWY3DPEHPK3PXP"},
+		{name: "other record", box: box, ciphertext: sealed, associated: "user:2", wantErr: ErrInvalidCiphertext},
+		{name: "other key", box: other, ciphertext: sealed, associated: "user:1", wantErr: ErrInvalidCiphertext},
+		{name: "tampered", box: box, ciphertext: tampered, associated: "user:1", wantErr: ErrInvalidCiphertext},
+		{name: "plaintext", box: box, ciphertext: "JBSWY3DPEHPK3PXP", associated: "user:1", wantErr: ErrInvalidCiphertext},
+		{name: "empty", box: box, ciphertext: "", associated: "user:1", wantErr: ErrInvalidCiphertext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.box.Open(tt.ciphertext, tt.associated)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("Open = %q, %v; want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewRejectsShortKeys(t *testing.T) {
+	if _, err := New(make([]byte, 16)); err == nil {
+		t.Error("16-byte key accepted")
+	}
+}
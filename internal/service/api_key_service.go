@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log"
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/repository"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Помилки API ключів
+var (
+	// ErrInvalidAPIKey ключ не існує, прострочений або відкликаний
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	// ErrAPIKeyNotFound ключ не знайдено серед ключів користувача
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrInvalidAPIKeyInput некоректна назва, область доступу чи термін дії ключа
+	ErrInvalidAPIKeyInput = errors.New("invalid api key parameters")
+	// ErrScopeNotPermitted роль користувача не дозволяє область доступу
+	ErrScopeNotPermitted = errors.New("role does not permit the requested scope")
+	// ErrTwoFactorRequired дія потребує сесії з підтвердженням другого фактора
+	ErrTwoFactorRequired = errors.New("two-factor authentication required")
+)
+
+const (
+	// APIKeyPrefix початок кожного API ключа; за ним ключ відрізняється від JWT
+	APIKeyPrefix = "prk_"
+	// apiKeyIDSize довжина відкритої ідентифікуючої частини ключа в байтах
+	apiKeyIDSize = 6
+	// apiKeyLastUsedInterval як часто оновлюється час останнього використання ключа
+	apiKeyLastUsedInterval = time.Minute
+	// maxAPIKeyNameLength максимальна довжина назви ключа
+	maxAPIKeyNameLength = 100
+)
+
+// IsAPIKey повідомляє, чи має рядок формат API ключа
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+type apiKeyService struct {
+	apiKeyRepo repository.APIKeyRepository
+	userRepo   repository.UserRepository
+}
+
+// NewAPIKeyService створює новий екземпляр сервісу API ключів
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, userRepo repository.UserRepository) APIKeyService {
+	return &apiKeyService{
+		apiKeyRepo: apiKeyRepo,
+		userRepo:   userRepo,
+	}
+}
+
+func (s *apiKeyService) Create(ctx context.Context, userID uint, input APIKeyInput, twoFactor bool) (*CreatedAPIKey, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxAPIKeyNameLength || len(input.Scopes) == 0 {
+		return nil, ErrInvalidAPIKeyInput
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidAPIKeyInput
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	scopes := make([]models.Scope, 0, len(input.Scopes))
+	seen := make(map[models.Scope]bool, len(input.Scopes))
+	for _, scope := range input.Scopes {
+		if !scope.Valid() {
+			return nil, ErrInvalidAPIKeyInput
+		}
+		if seen[scope] {
+			continue
+		}
+		seen[scope] = true
+
+		// Ключ не може мати більше прав, ніж його власник. Ключ з адміністративними
+		// правами діє без другого фактора, тому створюється лише із сесії з ним.
+		if permission := scope.Permission(); permission != "" {
+			if !user.Role.Can(permission) {
+				return nil, ErrScopeNotPermitted
+			}
+			if !twoFactor {
+				return nil, ErrTwoFactorRequired
+			}
+		}
+		scopes = append(scopes, scope)
+	}
+
+	b := make([]byte, apiKeyIDSize)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	prefix := APIKeyPrefix + hex.EncodeToString(b)
+
+	secret, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	key := prefix + "_" + secret
+
+	record := &models.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   hashToken(key),
+		Scopes:    models.JoinScopes(scopes),
+		ExpiresAt: input.ExpiresAt,
+	}
+	if err := s.apiKeyRepo.Create(ctx, record); err != nil {
+		return nil, err
+	}
+
+	log.Printf("API key %s created for user %d with scopes %q", prefix, userID, record.Scopes)
+	return &CreatedAPIKey{Key: key, APIKey: record}, nil
+}
+
+func (s *apiKeyService) List(ctx context.Context, userID uint) ([]*models.APIKey, error) {
+	return s.apiKeyRepo.GetByUserID(ctx, userID)
+}
+
+func (s *apiKeyService) Revoke(ctx context.Context, userID, id uint) error {
+	revoked, err := s.apiKeyRepo.Revoke(ctx, id, userID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+
+	log.Printf("API key %d revoked by user %d", id, userID)
+	return nil
+}
+
+func (s *apiKeyService) Authenticate(ctx context.Context, key string) (*APIKeyPrincipal, error) {
+	// Формат ключа: prk_<12 hex>_<секрет>; відкрита частина знаходить запис, хеш перевіряє секрет
+	prefixLength := len(APIKeyPrefix) + 2*apiKeyIDSize
+	if !IsAPIKey(key) || len(key) <= prefixLength+1 || key[prefixLength] != '_' {
+		return nil, ErrInvalidAPIKey
+	}
+
+	stored, err := s.apiKeyRepo.GetByPrefix(ctx, key[:prefixLength])
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || subtle.ConstantTimeCompare([]byte(stored.KeyHash), []byte(hashToken(key))) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if stored.RevokedAt != nil || (stored.ExpiresAt != nil && now.After(*stored.ExpiresAt)) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	// Роль береться з поточного запису власника: зміна ролі одразу діє і на його ключі
+	user, err := s.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if err := s.apiKeyRepo.TouchLastUsed(ctx, stored.ID, now, apiKeyLastUsedInterval); err != nil {
+		log.Printf("Failed to update last use of API key %d: %v", stored.ID, err)
+	}
+
+	return &APIKeyPrincipal{
+		KeyID:  stored.ID,
+		UserID: user.ID,
+		Role:   user.Role,
+		Scopes: stored.ScopeList(),
+	}, nil
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"product-recommendations-go/internal/models"
+)
+
+// memoryAPIKeys API ключі в пам'яті
+type memoryAPIKeys struct {
+	keys    []*models.APIKey
+	touched int
+}
+
+func (r *memoryAPIKeys) Create(_ context.Context, key *models.APIKey) error {
+	key.ID = uint(len(r.keys) + 1)
+	copied := *key
+	r.keys = append(r.keys, &copied)
+	return nil
+}
+
+func (r *memoryAPIKeys) GetByPrefix(_ context.Context, prefix string) (*models.APIKey, error) {
+	for _, key := range r.keys {
+		if key.Prefix == prefix {
+			copied := *key
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryAPIKeys) GetByUserID(_ context.Context, userID uint) ([]*models.APIKey, error) {
+	var keys []*models.APIKey
+	for _, key := range r.keys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (r *memoryAPIKeys) Revoke(_ context.Context, id, userID uint, revokedAt time.Time) (bool, error) {
+	for _, key := range r.keys {
+		if key.ID == id && key.UserID == userID && key.RevokedAt == nil {
+			key.RevokedAt = &revokedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryAPIKeys) TouchLastUsed(context.Context, uint, time.Time, time.Duration) error {
+	r.touched++
+	return nil
+}
+
+func TestAPIKeyAuthenticate(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// key повертає ключ, яким автентифікується запит
+		key        func(t *testing.T, s APIKeyService, keys *memoryAPIKeys, users *accountUsers) string
+		wantRole   models.Role
+		wantScopes []models.Scope
+		wantErr    error
+	}{
+		{
+			name: "valid key",
+			key: func(t *testing.T, s APIKeyService, _ *memoryAPIKeys, _ *accountUsers) string {
+				return createAPIKey(t, s, models.ScopeRecommendationsRead)
+			},
+			wantRole:   models.RoleAdmin,
+			wantScopes: []models.Scope{models.ScopeRecommendationsRead},
+		},
+		{
+			name: "owner role changed",
+			key: func(t *testing.T, s APIKeyService, _ *memoryAPIKeys, users *accountUsers) string {
+				key := createAPIKey(t, s, models.ScopeRecommendationsRead, models.ScopeRecommendationsBatch)
+				users.user.Role = models.RoleCustomer
+				return key
+			},
+			wantRole:   models.RoleCustomer,
+			wantScopes: []models.Scope{models.ScopeRecommendationsRead, models.ScopeRecommendationsBatch},
+		},
+		{
+			name: "wrong secret",
+			key: func(t *testing.T, s APIKeyService, _ *memoryAPIKeys, _ *accountUsers) string {
+				key := createAPIKey(t, s, models.ScopeRecommendationsRead)
+				if strings.HasSuffix(key, "x") {
+					return key[:len(key)-1] + "y"
+				}
+				return key[:len(key)-1] + "x"
+			},
+			wantErr: ErrInvalidAPIKey,
+		},
+		{
+			name: "unknown prefix",
+			key: func(*testing.T, APIKeyService, *memoryAPIKeys, *accountUsers) string {
+				return APIKeyPrefix + "000000000000_secret"
+			},
+			wantErr: ErrInvalidAPIKey,
+		},
+		{
+			name: "malformed",
+			key: func(*testing.T, APIKeyService, *memoryAPIKeys, *accountUsers) string {
+				return APIKeyPrefix + "short"
+			},
+			wantErr: ErrInvalidAPIKey,
+		},
+		{
+			name: "revoked",
+			key: func(t *testing.T, s APIKeyService, _ *memoryAPIKeys, _ *accountUsers) string {
+				key := createAPIKey(t, s, models.ScopeRecommendationsRead)
+				if err := s.Revoke(ctx, 7, 1); err != nil {
+					t.Fatal(err)
+				}
+				return key
+			},
+			wantErr: ErrInvalidAPIKey,
+		},
+		{
+			name: "expired",
+			key: func(t *testing.T, s APIKeyService, keys *memoryAPIKeys, _ *accountUsers) string {
+				key := createAPIKey(t, s, models.ScopeRecommendationsRead)
+				expired := time.Now().Add(-time.Second)
+				keys.keys[0].ExpiresAt = &expired
+				return key
+			},
+			wantErr: ErrInvalidAPIKey,
+		},
+		{
+			name: "owner deleted",
+			key: func(t *testing.T, s APIKeyService, _ *memoryAPIKeys, users *accountUsers) string {
+				key := createAPIKey(t, s, models.ScopeRecommendationsRead)
+				users.user = &models.User{ID: 8}
+				return key
+			},
+			wantErr: ErrInvalidAPIKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := &memoryAPIKeys{}
+			users := &accountUsers{user: &models.User{ID: 7, Role: models.RoleAdmin}}
+			s := NewAPIKeyService(keys, users)
+
+			principal, err := s.Authenticate(ctx, tt.key(t, s, keys, users))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if keys.touched != 0 {
+					t.Error("rejected key marked as used")
+				}
+				return
+			}
+			if principal.UserID != 7 || principal.Role != tt.wantRole || !reflect.DeepEqual(principal.Scopes, tt.wantScopes) {
+				t.Errorf("got %+v", principal)
+			}
+			if keys.touched != 1 {
+				t.Errorf("last use updated %d times, want 1", keys.touched)
+			}
+		})
+	}
+}
+
+func TestAPIKeyCreateScopes(t *testing.T) {
+	tests := []struct {
+		name      string
+		role      models.Role
+		scopes    []models.Scope
+		twoFactor bool
+		wantErr   error
+	}{
+		{name: "customer reads recommendations", role: models.RoleCustomer, scopes: []models.Scope{models.ScopeRecommendationsRead}},
+		{name: "customer asks for batch", role: models.RoleCustomer, scopes: []models.Scope{models.ScopeRecommendationsBatch}, twoFactor: true, wantErr: ErrScopeNotPermitted},
+		{name: "admin batch with second factor", role: models.RoleAdmin, scopes: []models.Scope{models.ScopeRecommendationsBatch}, twoFactor: true},
+		{name: "admin batch without second factor", role: models.RoleAdmin, scopes: []models.Scope{models.ScopeRecommendationsBatch}, wantErr: ErrTwoFactorRequired},
+		{name: "unknown scope", role: models.RoleAdmin, scopes: []models.Scope{"orders:write"}, twoFactor: true, wantErr: ErrInvalidAPIKeyInput},
+		{name: "no scopes", role: models.RoleAdmin, twoFactor: true, wantErr: ErrInvalidAPIKeyInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := &memoryAPIKeys{}
+			s := NewAPIKeyService(keys, &accountUsers{user: &models.User{ID: 7, Role: tt.role}})
+
+			created, err := s.Create(context.Background(), 7, APIKeyInput{Name: "integration", Scopes: tt.scopes}, tt.twoFactor)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(keys.keys) != 0 {
+					t.Error("rejected key stored")
+				}
+				return
+			}
+			if !strings.HasPrefix(created.Key, created.APIKey.Prefix+"_") || keys.keys[0].KeyHash == created.Key {
+				t.Errorf("key %q stored as %+v", created.Key, keys.keys[0])
+			}
+		})
+	}
+}
+
+// createAPIKey створює ключ користувача 7 і повертає його значення
+func createAPIKey(t *testing.T, s APIKeyService, scopes ...models.Scope) string {
+	t.Helper()
+	created, err := s.Create(context.Background(), 7, APIKeyInput{Name: "integration", Scopes: scopes}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return created.Key
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"log"
+	netmail "net/mail"
+	"product-recommendations-go/internal/mail"
+	"product-recommendations-go/internal/models"
+	"strings"
+	"time"
+)
+
+// Помилки керування обліковим записом
+var (
+	// ErrInvalidEmail адреса має некоректний формат
+	ErrInvalidEmail = errors.New("invalid email address")
+	// ErrEmailTaken адреса вже належить іншому обліковому запису
+	ErrEmailTaken = errors.New("email is already in use")
+	// ErrSameEmail нова адреса збігається з поточною
+	ErrSameEmail = errors.New("new email is the same as the current one")
+)
+
+func (s *authService) ChangePassword(ctx context.Context, userID uint, currentPassword, newPassword string, twoFactor bool, client ClientInfo) (*TokenPair, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.reauthenticate(ctx, user, client, func() error {
+		return checkPassword(user, currentPassword)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hashed, err := hashPassword(newPassword)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = hashed
+	if err := s.userRepo.UpdateColumns(ctx, user, "password"); err != nil {
+		return nil, err
+	}
+
+	// Зміна пароля завершує всі сесії, зокрема можливі сесії зловмисника; поточний
+	// клієнт отримує нову сесію з тим самим рівнем підтвердження
+	if err := s.revokeUserSessions(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Password changed for user %d", user.ID)
+	return s.startSession(ctx, user, twoFactor)
+}
+
+func (s *authService) RequestEmailChange(ctx context.Context, userID uint, newEmail, password string, client ClientInfo) error {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	err = s.reauthenticate(ctx, user, client, func() error {
+		return checkPassword(user, password)
+	})
+	if err != nil {
+		return err
+	}
+
+	newEmail, err = parseEmail(newEmail)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(newEmail, user.Email) {
+		return ErrSameEmail
+	}
+	if err := s.checkEmailAvailable(ctx, newEmail); err != nil {
+		return err
+	}
+
+	user.PendingEmail = newEmail
+	if err := s.userRepo.UpdateColumns(ctx, user, "pending_email"); err != nil {
+		return err
+	}
+
+	// Новий токен робить недійсними посилання, надіслані на попередню нову адресу
+	token, err := s.issueUserToken(ctx, user.ID, models.TokenPurposeEmailChange, s.cfg.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      newEmail,
+		Subject: "Підтвердження нової адреси електронної пошти",
+		Body: fmt.Sprintf("Ви змінюєте адресу електронної пошти облікового запису.\n\n"+
+			"Щоб підтвердити нову адресу, перейдіть за посиланням (діє %s):\n%s\n\n"+
+			"Якщо ви не змінювали адресу, просто проігноруйте цей лист.\n",
+			formatTTL(s.cfg.EmailVerificationTTL), s.link("/confirm-email-change", token)),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Власник поточної адреси дізнається про спробу зміни, навіть якщо токен викрадено
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Запит на зміну адреси електронної пошти",
+		Body: "Для вашого облікового запису надіслано запит на зміну адреси електронної пошти.\n\n" +
+			"Адреса зміниться після підтвердження за посиланням, надісланим на нову адресу. " +
+			"Якщо це були не ви, змініть пароль.\n",
+	})
+	if err != nil {
+		log.Printf("Failed to notify user %d about email change: %v", user.ID, err)
+	}
+	return nil
+}
+
+func (s *authService) ConfirmEmailChange(ctx context.Context, token string) error {
+	user, err := s.consumeUserToken(ctx, token, models.TokenPurposeEmailChange)
+	if err != nil {
+		return err
+	}
+	if user.PendingEmail == "" {
+		return ErrInvalidUserToken
+	}
+
+	// Адресу могли зайняти після запиту на зміну
+	if err := s.checkEmailAvailable(ctx, user.PendingEmail); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	previous := user.Email
+	user.Email = user.PendingEmail
+	user.PendingEmail = ""
+	user.EmailVerifiedAt = &now
+	if err := s.userRepo.UpdateColumns(ctx, user, "email", "pending_email", "email_verified_at"); err != nil {
+		return err
+	}
+
+	log.Printf("User %d changed email address", user.ID)
+
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      previous,
+		Subject: "Адресу електронної пошти змінено",
+		Body:    "Адресу електронної пошти вашого облікового запису змінено. Якщо це були не ви, зверніться до підтримки.\n",
+	})
+	if err != nil {
+		log.Printf("Failed to notify user %d about email change: %v", user.ID, err)
+	}
+	return nil
+}
+
+func (s *authService) DeleteAccount(ctx context.Context, userID uint, password, code string, client ClientInfo) error {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	err = s.reauthenticate(ctx, user, client, func() error {
+		if err := checkPassword(user, password); err != nil {
+			return err
+		}
+		if user.TOTPEnabledAt != nil {
+			return s.checkSecondFactor(ctx, user, code)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Сесії відкликаються до видалення: після нього записи refresh токенів уже не потрібні
+	if err := s.revokeUserSessions(ctx, user.ID); err != nil {
+		return err
+	}
+
+	// Заглушка звільняє адресу для повторної реєстрації та лишається унікальною
+	placeholder := fmt.Sprintf("deleted-%d@deleted.invalid", user.ID)
+	if err := s.userRepo.Anonymize(ctx, user.ID, placeholder, time.Now()); err != nil {
+		return err
+	}
+
+	log.Printf("User %d deleted their account", user.ID)
+	return nil
+}
+
+// checkPassword порівнює пароль з хешем пароля користувача
+func checkPassword(user *models.User, password string) error {
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
+// checkEmailAvailable перевіряє, що адресу не використовує жоден обліковий запис
+func (s *authService) checkEmailAvailable(ctx context.Context, email string) error {
+	existing, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return ErrEmailTaken
+	}
+	return nil
+}
+
+// parseEmail перевіряє формат адреси і повертає її без імені та пробілів
+func parseEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	addr, err := netmail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > 255 {
+		return "", ErrInvalidEmail
+	}
+	return addr.Address, nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/repository"
+)
+
+// accountUsers репозиторій з одним користувачем; решта методів у тесті не викликається
+type accountUsers struct {
+	repository.UserRepository
+	user *models.User
+}
+
+func (r *accountUsers) GetByID(_ context.Context, id uint) (*models.User, error) {
+	if r.user.ID != id {
+		return nil, nil
+	}
+	return r.user, nil
+}
+
+func (r *accountUsers) UpdateColumns(_ context.Context, user *models.User, _ ...string) error {
+	stored := *user
+	r.user = &stored
+	return nil
+}
+
+func (r *accountUsers) EnableTOTP(_ context.Context, _ uint, secret string, enabledAt time.Time) (bool, error) {
+	if r.user.TOTPSecret != secret || r.user.TOTPEnabledAt != nil {
+		return false, nil
+	}
+	r.user.TOTPEnabledAt = &enabledAt
+	return true, nil
+}
+
+func (r *accountUsers) AdvanceTOTPStep(_ context.Context, _ uint, step int64) (bool, error) {
+	if step <= r.user.TOTPLastStep {
+		return false, nil
+	}
+	r.user.TOTPLastStep = step
+	return true, nil
+}
+
+func TestAccountPasswordChecksAreThrottled(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		call func(s *authService, password string) error
+	}{
+		{
+			name: "change password",
+			call: func(s *authService, password string) error {
+				_, err := s.ChangePassword(context.Background(), 1, password, "new password 123", false, ClientInfo{})
+				return err
+			},
+		},
+		{
+			name: "request email change",
+			call: func(s *authService, password string) error {
+				return s.RequestEmailChange(context.Background(), 1, "new@example.com", password, ClientInfo{})
+			},
+		},
+		{
+			name: "delete account",
+			call: func(s *authService, password string) error {
+				return s.DeleteAccount(context.Background(), 1, password, "", ClientInfo{})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &authService{
+				userRepo: &accountUsers{user: &models.User{ID: 1, Email: "user@example.com", Password: string(hash)}},
+				throttle: testLoginThrottle(),
+			}
+
+			for i := 0; i < 3; i++ {
+				if err := tt.call(s, "wrong password"); !errors.Is(err, ErrInvalidCredentials) {
+					t.Fatalf("attempt %d: got %v, want ErrInvalidCredentials", i+1, err)
+				}
+			}
+
+			// Після безкоштовних спроб не приймається навіть правильний пароль
+			var throttled *LoginThrottledError
+			if err := tt.call(s, "correct password"); !errors.As(err, &throttled) {
+				t.Errorf("got %v, want throttling", err)
+			}
+		})
+	}
+}
+
+func TestReauthenticateReleasesSuccessfulAttempts(t *testing.T) {
+	s := &authService{throttle: testLoginThrottle()}
+	user := &models.User{ID: 1, Email: "user@example.com"}
+
+	// Успішні та завершені з іншою помилкою перевірки не враховуються як невдалі
+	for i := 0; i < 10; i++ {
+		check := func() error { return nil }
+		if i%2 == 1 {
+			check = func() error { return ErrTwoFactorNotEnabled }
+		}
+		if err := s.reauthenticate(context.Background(), user, ClientInfo{}, check); err != nil && !errors.Is(err, ErrTwoFactorNotEnabled) {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		err := s.reauthenticate(context.Background(), user, ClientInfo{}, func() error { return ErrInvalidTwoFactorCode })
+		if !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Fatalf("failed attempt %d: got %v", i+1, err)
+		}
+	}
+	var throttled *LoginThrottledError
+	if err := s.reauthenticate(context.Background(), user, ClientInfo{}, func() error { return nil }); !errors.As(err, &throttled) {
+		t.Errorf("got %v, want throttling after failed codes", err)
+	}
+}
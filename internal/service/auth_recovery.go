@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"log"
+	"net/url"
+	"product-recommendations-go/internal/mail"
+	"product-recommendations-go/internal/models"
+	"time"
+	"unicode/utf8"
+)
+
+// Помилки підтвердження адреси та скидання пароля
+var (
+	// ErrInvalidUserToken токен підтвердження чи скидання не існує, прострочений або вже використаний
+	ErrInvalidUserToken = errors.New("invalid or expired token")
+	// ErrEmailAlreadyVerified адресу користувача вже підтверджено
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+	// ErrWeakPassword пароль не відповідає вимогам до довжини
+	ErrWeakPassword = fmt.Errorf("password must be %d to %d bytes long", minPasswordLength, maxPasswordLength)
+)
+
+const (
+	// minPasswordLength мінімальна довжина пароля в символах
+	minPasswordLength = 8
+	// maxPasswordLength максимальна довжина пароля в байтах (обмеження bcrypt)
+	maxPasswordLength = 72
+)
+
+// hashPassword перевіряє довжину пароля і повертає його bcrypt хеш
+func hashPassword(password string) (string, error) {
+	if utf8.RuneCountInString(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return "", ErrWeakPassword
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+func (s *authService) ResendVerification(ctx context.Context, userID uint) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	return s.sendVerificationEmail(ctx, user)
+}
+
+func (s *authService) VerifyEmail(ctx context.Context, token string) error {
+	user, err := s.consumeUserToken(ctx, token, models.TokenPurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+
+	// Повторне підтвердження вже підтвердженої адреси нічого не змінює
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	return s.userRepo.UpdateColumns(ctx, user, "email_verified_at")
+}
+
+func (s *authService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	// Відповідь не повинна розкривати, чи зареєстрована адреса, тому для
+	// невідомої адреси та помилки доставки повертається той самий результат
+	if user == nil {
+		return nil
+	}
+
+	token, err := s.issueUserToken(ctx, user.ID, models.TokenPurposePasswordReset, s.cfg.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Скидання пароля",
+		Body: fmt.Sprintf("Ми отримали запит на скидання пароля до вашого облікового запису.\n\n"+
+			"Щоб встановити новий пароль, перейдіть за посиланням (діє %s):\n%s\n\n"+
+			"Якщо ви не надсилали запит, просто проігноруйте цей лист - пароль не зміниться.\n",
+			formatTTL(s.cfg.PasswordResetTTL), s.link("/reset-password", token)),
+	})
+	if err != nil {
+		log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+	}
+	return nil
+}
+
+func (s *authService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	// Пароль перевіряється до використання токена, щоб помилка у паролі не спалила посилання
+	hashed, err := hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.consumeUserToken(ctx, token, models.TokenPurposePasswordReset)
+	if err != nil {
+		return err
+	}
+
+	user.Password = hashed
+	// Посилання надійшло на адресу користувача, отже адресу підтверджено
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	if err := s.userRepo.UpdateColumns(ctx, user, "password", "email_verified_at"); err != nil {
+		return err
+	}
+
+	// Після скидання пароля всі наявні сесії (зокрема зловмисника) завершуються
+	return s.revokeUserSessions(ctx, user.ID)
+}
+
+// sendVerificationEmail видає новий токен підтвердження адреси та надсилає його листом
+func (s *authService) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	token, err := s.issueUserToken(ctx, user.ID, models.TokenPurposeEmailVerification, s.cfg.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Підтвердження адреси електронної пошти",
+		Body: fmt.Sprintf("Дякуємо за реєстрацію!\n\n"+
+			"Щоб підтвердити адресу електронної пошти, перейдіть за посиланням (діє %s):\n%s\n\n"+
+			"Якщо ви не реєструвалися, просто проігноруйте цей лист.\n",
+			formatTTL(s.cfg.EmailVerificationTTL), s.link("/verify-email", token)),
+	})
+}
+
+// issueUserToken видає одноразовий токен з призначенням purpose. Раніше видані
+// токени з тим самим призначенням стають недійсними: діє лише останній лист.
+func (s *authService) issueUserToken(ctx context.Context, userID uint, purpose models.TokenPurpose, ttl time.Duration) (string, error) {
+	now := time.Now()
+	if err := s.userTokenRepo.InvalidateUser(ctx, userID, purpose, now); err != nil {
+		return "", err
+	}
+
+	token, err := newRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.userTokenRepo.Create(ctx, &models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeUserToken позначає токен використаним і повертає його власника
+func (s *authService) consumeUserToken(ctx context.Context, token string, purpose models.TokenPurpose) (*models.User, error) {
+	if token == "" {
+		return nil, ErrInvalidUserToken
+	}
+
+	stored, err := s.userTokenRepo.GetByHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if stored == nil || stored.Purpose != purpose || stored.UsedAt != nil || now.After(stored.ExpiresAt) {
+		return nil, ErrInvalidUserToken
+	}
+
+	// Позначка атомарна: з двох паралельних запитів з тим самим токеном успішний лише один
+	fresh, err := s.userTokenRepo.MarkUsed(ctx, stored.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, ErrInvalidUserToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidUserToken
+	}
+	return user, nil
+}
+
+// revokeUserSessions відкликає всі активні сесії користувача
+func (s *authService) revokeUserSessions(ctx context.Context, userID uint) error {
+	sessions, err := s.refreshRepo.ActiveFamilies(ctx, userID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, sessionID := range sessions {
+		if err := s.revokeSession(ctx, sessionID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// link формує посилання на сторінку клієнтського додатка з токеном у параметрі запиту
+func (s *authService) link(path, token string) string {
+	return s.cfg.AppURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// formatTTL форматує термін дії посилання для тексту листа
+func formatTTL(ttl time.Duration) string {
+	if ttl >= time.Hour && ttl%time.Hour == 0 {
+		return fmt.Sprintf("%d год", int(ttl/time.Hour))
+	}
+	return fmt.Sprintf("%d хв", int(ttl.Round(time.Minute)/time.Minute))
+}
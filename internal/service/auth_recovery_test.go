@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"product-recommendations-go/internal/mail"
+	"product-recommendations-go/internal/models"
+)
+
+// memoryUserTokens одноразові токени в пам'яті
+type memoryUserTokens struct {
+	tokens []*models.UserToken
+}
+
+func (r *memoryUserTokens) Create(_ context.Context, token *models.UserToken) error {
+	token.ID = uint(len(r.tokens) + 1)
+	copied := *token
+	r.tokens = append(r.tokens, &copied)
+	return nil
+}
+
+func (r *memoryUserTokens) GetByHash(_ context.Context, tokenHash string) (*models.UserToken, error) {
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryUserTokens) MarkUsed(_ context.Context, id uint, usedAt time.Time) (bool, error) {
+	for _, token := range r.tokens {
+		if token.ID == id && token.UsedAt == nil {
+			token.UsedAt = &usedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryUserTokens) InvalidateUser(_ context.Context, userID uint, purpose models.TokenPurpose, usedAt time.Time) error {
+	for _, token := range r.tokens {
+		if token.UserID == userID && token.Purpose == purpose && token.UsedAt == nil {
+			token.UsedAt = &usedAt
+		}
+	}
+	return nil
+}
+
+func (r *memoryUserTokens) DeleteExpired(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+// expireAll переносить термін дії всіх токенів у минуле
+func (r *memoryUserTokens) expireAll() {
+	for _, token := range r.tokens {
+		token.ExpiresAt = time.Now().Add(-time.Second)
+	}
+}
+
+// recordingMailer запам'ятовує надіслані листи
+type recordingMailer struct {
+	sent []mail.Message
+}
+
+func (m *recordingMailer) Send(_ context.Context, msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// lastToken повертає токен з посилання в останньому листі
+func (m *recordingMailer) lastToken(t *testing.T) string {
+	t.Helper()
+	if len(m.sent) == 0 {
+		t.Fatal("no email sent")
+	}
+	body := m.sent[len(m.sent)-1].Body
+	start := strings.Index(body, "?token=")
+	if start < 0 {
+		t.Fatalf("no link in %q", body)
+	}
+	escaped := strings.Fields(body[start+len("?token="):])[0]
+	token, err := url.QueryUnescape(escaped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func (r *accountUsers) GetByEmail(_ context.Context, email string) (*models.User, error) {
+	if r.user.Email != email {
+		return nil, nil
+	}
+	return r.user, nil
+}
+
+// newRecoveryService сервіс з одним користувачем з непідтвердженою адресою
+func newRecoveryService() (*authService, *accountUsers, *memoryUserTokens, *recordingMailer) {
+	s, _ := newSessionService()
+	users := &accountUsers{user: &models.User{ID: 7, Email: "user@example.com", Role: models.RoleCustomer}}
+	tokens := &memoryUserTokens{}
+	mailer := &recordingMailer{}
+	s.userRepo = users
+	s.userTokenRepo = tokens
+	s.mailer = mailer
+	s.cfg.PasswordResetTTL = time.Hour
+	s.cfg.EmailVerificationTTL = time.Hour
+	return s, users, tokens, mailer
+}
+
+func TestResetPasswordTokens(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// token повертає токен, з яким викликається ResetPassword
+		token   func(t *testing.T, s *authService, tokens *memoryUserTokens, mailer *recordingMailer) string
+		wantErr error
+	}{
+		{
+			name: "fresh token",
+			token: func(t *testing.T, s *authService, _ *memoryUserTokens, mailer *recordingMailer) string {
+				if err := s.ForgotPassword(ctx, "user@example.com"); err != nil {
+					t.Fatal(err)
+				}
+				return mailer.lastToken(t)
+			},
+		},
+		{
+			name: "used token",
+			token: func(t *testing.T, s *authService, _ *memoryUserTokens, mailer *recordingMailer) string {
+				if err := s.ForgotPassword(ctx, "user@example.com"); err != nil {
+					t.Fatal(err)
+				}
+				token := mailer.lastToken(t)
+				if err := s.ResetPassword(ctx, token, "first new password"); err != nil {
+					t.Fatal(err)
+				}
+				return token
+			},
+			wantErr: ErrInvalidUserToken,
+		},
+		{
+			name: "expired token",
+			token: func(t *testing.T, s *authService, tokens *memoryUserTokens, mailer *recordingMailer) string {
+				if err := s.ForgotPassword(ctx, "user@example.com"); err != nil {
+					t.Fatal(err)
+				}
+				tokens.expireAll()
+				return mailer.lastToken(t)
+			},
+			wantErr: ErrInvalidUserToken,
+		},
+		{
+			name: "superseded by a newer email",
+			token: func(t *testing.T, s *authService, _ *memoryUserTokens, mailer *recordingMailer) string {
+				if err := s.ForgotPassword(ctx, "user@example.com"); err != nil {
+					t.Fatal(err)
+				}
+				token := mailer.lastToken(t)
+				if err := s.ForgotPassword(ctx, "user@example.com"); err != nil {
+					t.Fatal(err)
+				}
+				return token
+			},
+			wantErr: ErrInvalidUserToken,
+		},
+		{
+			name: "email verification token",
+			token: func(t *testing.T, s *authService, _ *memoryUserTokens, mailer *recordingMailer) string {
+				if err := s.ResendVerification(ctx, 7); err != nil {
+					t.Fatal(err)
+				}
+				return mailer.lastToken(t)
+			},
+			wantErr: ErrInvalidUserToken,
+		},
+		{
+			name: "empty token",
+			token: func(*testing.T, *authService, *memoryUserTokens, *recordingMailer) string {
+				return ""
+			},
+			wantErr: ErrInvalidUserToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, users, tokens, mailer := newRecoveryService()
+			session, err := s.issueTokens(ctx, users.user, "session", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			token := tt.token(t, s, tokens, mailer)
+
+			err = s.ResetPassword(ctx, token, "new password 123")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if bcrypt.CompareHashAndPassword([]byte(users.user.Password), []byte("new password 123")) != nil {
+				t.Error("password not changed")
+			}
+			if users.user.EmailVerifiedAt == nil {
+				t.Error("email not verified by the reset link")
+			}
+			// Скидання пароля завершує наявні сесії
+			if _, err := s.ParseToken(ctx, session.AccessToken); err == nil {
+				t.Error("session survived the reset")
+			}
+		})
+	}
+}
+
+func TestResetPasswordKeepsTokenForWeakPassword(t *testing.T) {
+	ctx := context.Background()
+	s, _, _, mailer := newRecoveryService()
+	if err := s.ForgotPassword(ctx, "user@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	token := mailer.lastToken(t)
+
+	if err := s.ResetPassword(ctx, token, "short"); !errors.Is(err, ErrWeakPassword) {
+		t.Fatalf("got %v, want ErrWeakPassword", err)
+	}
+	if err := s.ResetPassword(ctx, token, "new password 123"); err != nil {
+		t.Errorf("token burned by a rejected password: %v", err)
+	}
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	s, _, tokens, mailer := newRecoveryService()
+	if err := s.ForgotPassword(context.Background(), "nobody@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if len(mailer.sent) != 0 || len(tokens.tokens) != 0 {
+		t.Errorf("sent %d emails and issued %d tokens for an unknown address", len(mailer.sent), len(tokens.tokens))
+	}
+}
+
+func TestVerifyEmailTokens(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// prepare виконується після видачі токена підтвердження
+		prepare func(t *testing.T, s *authService, tokens *memoryUserTokens, token string)
+		wantErr error
+	}{
+		{
+			name:    "fresh token",
+			prepare: func(*testing.T, *authService, *memoryUserTokens, string) {},
+		},
+		{
+			name: "used token",
+			prepare: func(t *testing.T, s *authService, _ *memoryUserTokens, token string) {
+				if err := s.VerifyEmail(ctx, token); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: ErrInvalidUserToken,
+		},
+		{
+			name: "expired token",
+			prepare: func(_ *testing.T, _ *authService, tokens *memoryUserTokens, _ string) {
+				tokens.expireAll()
+			},
+			wantErr: ErrInvalidUserToken,
+		},
+		{
+			name: "superseded by a newer email",
+			prepare: func(t *testing.T, s *authService, _ *memoryUserTokens, _ string) {
+				if err := s.ResendVerification(ctx, 7); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: ErrInvalidUserToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, users, tokens, mailer := newRecoveryService()
+			if err := s.ResendVerification(ctx, 7); err != nil {
+				t.Fatal(err)
+			}
+			token := mailer.lastToken(t)
+			tt.prepare(t, s, tokens, token)
+			verified := users.user.EmailVerifiedAt != nil
+
+			err := s.VerifyEmail(ctx, token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && users.user.EmailVerifiedAt == nil {
+				t.Error("email not verified")
+			}
+			if tt.wantErr != nil && (users.user.EmailVerifiedAt != nil) != verified {
+				t.Error("rejected token changed the verification state")
+			}
+		})
+	}
+
+	// Підтверджену адресу повторно не підтверджують
+	s, users, _, _ := newRecoveryService()
+	now := time.Now()
+	users.user.EmailVerifiedAt = &now
+	if err := s.ResendVerification(ctx, 7); !errors.Is(err, ErrEmailAlreadyVerified) {
+		t.Errorf("got %v, want ErrEmailAlreadyVerified", err)
+	}
+}
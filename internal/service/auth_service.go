@@ -3,25 +3,89 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
+	"log"
+	"product-recommendations-go/internal/jwtkeys"
+	"product-recommendations-go/internal/mail"
 	"product-recommendations-go/internal/models"
 	"product-recommendations-go/internal/repository"
+	"product-recommendations-go/internal/secretbox"
 	"time"
 )
 
+// Помилки оновлення токенів
+var (
+	// ErrInvalidRefreshToken refresh токен не існує, прострочений або відкликаний
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused refresh токен використано повторно; сесію відкликано
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+	// ErrInvalidCredentials неправильна адреса або пароль
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// dummyPasswordHash bcrypt хеш, з яким порівнюється пароль для незареєстрованих адрес
+var dummyPasswordHash = []byte("$2a$10$b6VCZJxPX0A1KRZToJ/JFuxxtZxIipL4NYFsxaifOd5EXXN0qo7Ze")
+
+// sessionRevocationPrefix префікс записів списку відкликаних, що відкликають усі токени сесії
+const sessionRevocationPrefix = "sid:"
+
+// accessTokenAudience значення claim "aud" токенів доступу; сервіси, що перевіряють
+// токени за JWKS, мають вимагати його
+const accessTokenAudience = "api"
+
+// AuthConfig параметри видачі токенів
+type AuthConfig struct {
+	// Keys ключі підпису та перевірки токенів доступу
+	Keys *jwtkeys.KeySet
+	// AccessTokenTTL термін дії токена доступу
+	AccessTokenTTL time.Duration
+	// RefreshTokenTTL термін дії refresh токена (і максимальна тривалість сесії без входу)
+	RefreshTokenTTL time.Duration
+	// EmailVerificationTTL термін дії посилання для підтвердження адреси
+	EmailVerificationTTL time.Duration
+	// PasswordResetTTL термін дії посилання для скидання пароля
+	PasswordResetTTL time.Duration
+	// AppURL адреса клієнтського додатка, на яку ведуть посилання в листах
+	AppURL string
+	// TOTPIssuer назва сервісу, що показується в застосунку-автентифікаторі
+	TOTPIssuer string
+	// TOTPSecrets ключ, яким секрети TOTP шифруються в базі даних
+	TOTPSecrets *secretbox.Box
+	// ChallengeTTL час на введення коду двофакторної автентифікації після пароля
+	ChallengeTTL time.Duration
+}
+
 type authService struct {
-	userRepo  repository.UserRepository
-	jwtSecret string
+	userRepo      repository.UserRepository
+	refreshRepo   repository.RefreshTokenRepository
+	userTokenRepo repository.UserTokenRepository
+	recoveryRepo  repository.RecoveryCodeRepository
+	denylist      TokenDenylist
+	throttle      LoginThrottle
+	mailer        mail.Mailer
+	cfg           AuthConfig
 }
 
-// NewAuthService створює новий екземпляр сервісу аутентифікації
-func NewAuthService(userRepo repository.UserRepository, jwtSecret string) AuthService {
+// NewAuthService створює новий екземпляр сервісу аутентифікації.
+// denylist - список відкликаних токенів, який поповнює Logout і перевіряє ParseToken;
+// throttle обмежує спроби входу; mailer надсилає листи підтвердження адреси та скидання пароля.
+func NewAuthService(userRepo repository.UserRepository, refreshRepo repository.RefreshTokenRepository, userTokenRepo repository.UserTokenRepository, recoveryRepo repository.RecoveryCodeRepository, denylist TokenDenylist, throttle LoginThrottle, mailer mail.Mailer, cfg AuthConfig) AuthService {
 	return &authService{
-		userRepo:  userRepo,
-		jwtSecret: jwtSecret,
+		userRepo:      userRepo,
+		refreshRepo:   refreshRepo,
+		userTokenRepo: userTokenRepo,
+		recoveryRepo:  recoveryRepo,
+		denylist:      denylist,
+		throttle:      throttle,
+		mailer:        mailer,
+		cfg:           cfg,
 	}
 }
 
@@ -36,120 +100,436 @@ func (s *authService) Register(ctx context.Context, user *models.User) error {
 	}
 
 	// Хешування пароля
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	hashedPassword, err := hashPassword(user.Password)
 	if err != nil {
 		return err
 	}
 
-	user.Password = string(hashedPassword)
+	user.Password = hashedPassword
+
+	// Самостійна реєстрація завжди створює покупця; інші ролі призначає адміністратор
+	user.Role = models.RoleCustomer
+	user.EmailVerifiedAt = nil
 
 	// Створення користувача
-	return s.userRepo.Create(ctx, user)
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return err
+	}
+
+	// Реєстрація не залежить від доставки листа: користувач може запросити його повторно
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+	return nil
 }
 
-func (s *authService) Login(ctx context.Context, email, password string) (string, error) {
+func (s *authService) Login(ctx context.Context, email, password string, client ClientInfo) (*LoginResult, error) {
+	// Обмеження перевіряються до пароля: під час блокування навіть правильний
+	// пароль не приймається, інакше блокування не зупиняло б підбір. Спроба
+	// враховується як невдала наперед і скасовується, якщо пароль правильний.
+	reservation, err := s.throttle.Reserve(ctx, email, client)
+	if err != nil {
+		var throttled *LoginThrottledError
+		if errors.As(err, &throttled) {
+			s.recordLoginFailure(ctx, email, nil, client, models.LoginFailureThrottled)
+		}
+		return nil, err
+	}
+
 	// Отримання користувача за email
 	user, err := s.userRepo.GetByEmail(ctx, email)
 	if err != nil {
-		return "", err
+		s.releaseLoginAttempt(ctx, reservation)
+		return nil, err
 	}
 	if user == nil {
-		return "", errors.New("invalid credentials")
+		// Порівняння з фіктивним хешем вирівнює час відповіді для невідомих адрес
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		s.recordLoginFailure(ctx, email, nil, client, models.LoginFailureUnknownUser)
+		return nil, ErrInvalidCredentials
 	}
 
 	// Перевірка пароля
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
-		return "", errors.New("invalid credentials")
+		s.recordLoginFailure(ctx, email, &user.ID, client, models.LoginFailureInvalidPassword)
+		return nil, ErrInvalidCredentials
 	}
+	s.releaseLoginAttempt(ctx, reservation)
 
-	// Створення JWT токена
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"email":   user.Email,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-	})
+	// З увімкненою двофакторною автентифікацією пароль лише відкриває другий крок.
+	// Лічильник невдалих спроб не скидається до підтвердження коду, інакше знання
+	// пароля дозволяло б необмежено підбирати код.
+	if user.TOTPEnabledAt != nil {
+		challenge, err := s.issueChallenge(user)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{Challenge: challenge}, nil
+	}
 
-	// Підписання токена
-	tokenString, err := token.SignedString([]byte(s.jwtSecret))
+	tokens, err := s.startSession(ctx, user, false)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-
-	return tokenString, nil
+	return &LoginResult{Tokens: tokens}, nil
 }
 
-func (s *authService) Logout(_ context.Context, token string) error {
-	// Parse the token to extract expiration time
-	parsedToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+func (s *authService) CompleteExternalLogin(ctx context.Context, userID uint) (*LoginResult, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Постачальник ідентичності замінює лише пароль: другий фактор, увімкнений
+	// у нас, вимагається і під час входу через постачальника
+	if user.TOTPEnabledAt != nil {
+		challenge, err := s.issueChallenge(user)
+		if err != nil {
+			return nil, err
 		}
-		return []byte(s.jwtSecret), nil
-	})
+		return &LoginResult{Challenge: challenge}, nil
+	}
 
+	tokens, err := s.startSession(ctx, user, false)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return &LoginResult{Tokens: tokens}, nil
+}
 
-	// Ensure the token is valid
-	if !parsedToken.Valid {
-		return errors.New("invalid token")
+// startSession завершує успішний вхід: скидає лічильник невдалих спроб і відкриває
+// нову сесію - родину refresh токенів
+func (s *authService) startSession(ctx context.Context, user *models.User, twoFactor bool) (*TokenPair, error) {
+	if err := s.throttle.RecordSuccess(ctx, user.Email); err != nil {
+		log.Printf("Failed to reset login attempts for user %d: %v", user.ID, err)
 	}
 
-	claims, ok := parsedToken.Claims.(jwt.MapClaims)
-	if !ok {
-		return errors.New("invalid token claims")
+	sessionID, err := newTokenID()
+	if err != nil {
+		return nil, err
 	}
 
-	// Get expiration time
-	exp, ok := claims["exp"].(float64)
-	if !ok {
-		return errors.New("invalid exp claim")
+	return s.issueTokens(ctx, user, sessionID, twoFactor)
+}
+
+func (s *authService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	stored, err := s.refreshRepo.GetByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Токен можна обміняти лише один раз. Повторне використання означає, що токен
+	// викрадено (або його копія є в іншого клієнта), тому відкликаємо всю сесію.
+	now := time.Now()
+	fresh, err := s.refreshRepo.MarkUsed(ctx, stored.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		log.Printf("Refresh token reuse detected for user %d, revoking session %s", stored.UserID, stored.FamilyID)
+		if err := s.revokeSession(ctx, stored.FamilyID, stored.UserID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	// Роль і стан користувача беруться з бази, а не з попереднього токена
+	user, err := s.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return s.issueTokens(ctx, user, stored.FamilyID, stored.TwoFactor)
+}
+
+func (s *authService) Logout(ctx context.Context, token string) error {
+	claims, err := s.verifyToken(token)
+	if err != nil {
+		return err
 	}
 
-	expiresAt := time.Unix(int64(exp), 0)
+	return s.revokeSession(ctx, claims.SessionID, claims.UserID)
+}
+
+// Cleanup видаляє прострочені записи відкликаних і refresh токенів
+func (s *authService) Cleanup(ctx context.Context) error {
+	if err := s.denylist.Cleanup(ctx); err != nil {
+		return err
+	}
+
+	if err := s.throttle.Cleanup(ctx); err != nil {
+		return err
+	}
 
-	// For a complete implementation, you would:
-	// 1. Add this token to a blacklist storage (Redis/DB)
-	// 2. Set the blacklist entry to expire at the token's expiration time
-	// 3. Check the blacklist in ParseToken before validating tokens
+	now := time.Now()
+	deleted, err := s.refreshRepo.DeleteExpired(ctx, now)
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Printf("Removed %d expired refresh tokens", deleted)
+	}
 
-	// Placeholder return until blacklist storage is implemented
-	_ = expiresAt // Avoid unused variable warning
+	deleted, err = s.userTokenRepo.DeleteExpired(ctx, now)
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Printf("Removed %d expired verification and password reset tokens", deleted)
+	}
 	return nil
 }
 
-func (s *authService) ParseToken(token string) (uint, error) {
-	// Парсинг JWT токена
-	parsedToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		// Перевірка методу підпису
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+// releaseLoginAttempt скасовує наперед враховану спробу входу, яка не виявилася
+// невдалою
+func (s *authService) releaseLoginAttempt(ctx context.Context, reservation *LoginReservation) {
+	if err := s.throttle.Release(ctx, reservation); err != nil {
+		log.Printf("Failed to release login attempt: %v", err)
+	}
+}
+
+// reauthenticate перевіряє пароль або код, яким користувач підтверджує чутливу
+// дію в уже відкритій сесії, під тими самими обмеженнями, що й вхід: інакше
+// викрадений токен доступу дозволяв би необмежено підбирати пароль. check
+// повертає ErrInvalidCredentials або ErrInvalidTwoFactorCode для невдалої спроби.
+func (s *authService) reauthenticate(ctx context.Context, user *models.User, client ClientInfo, check func() error) error {
+	reservation, err := s.throttle.Reserve(ctx, user.Email, client)
+	if err != nil {
+		var throttled *LoginThrottledError
+		if errors.As(err, &throttled) {
+			s.recordLoginFailure(ctx, user.Email, &user.ID, client, models.LoginFailureThrottled)
 		}
-		return []byte(s.jwtSecret), nil
+		return err
+	}
+
+	err = check()
+	switch {
+	case errors.Is(err, ErrInvalidCredentials):
+		s.recordLoginFailure(ctx, user.Email, &user.ID, client, models.LoginFailureInvalidPassword)
+	case errors.Is(err, ErrInvalidTwoFactorCode):
+		s.recordLoginFailure(ctx, user.Email, &user.ID, client, models.LoginFailureInvalidCode)
+	default:
+		s.releaseLoginAttempt(ctx, reservation)
+	}
+	return err
+}
+
+// recordLoginFailure записує невдалу спробу входу до журналу. Помилка сховища не змінює
+// відповідь клієнту, щоб збій журналу не розкривав, чи існує обліковий запис.
+func (s *authService) recordLoginFailure(ctx context.Context, email string, userID *uint, client ClientInfo, reason models.LoginFailureReason) {
+	err := s.throttle.RecordFailure(ctx, LoginAttemptInfo{
+		Email:     email,
+		UserID:    userID,
+		Client:    client,
+		Reason:    reason,
+		Timestamp: time.Now(),
 	})
+	if err != nil {
+		log.Printf("Failed to record login attempt: %v", err)
+	}
+}
 
+// issueTokens видає токен доступу та новий refresh токен сесії sessionID.
+// twoFactor - сесію відкрито з підтвердженням другого фактора.
+func (s *authService) issueTokens(ctx context.Context, user *models.User, sessionID string, twoFactor bool) (*TokenPair, error) {
+	// Унікальний ідентифікатор токена дозволяє відкликати саме цей токен
+	jti, err := newTokenID()
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	// Методи автентифікації сесії (RFC 8176): пароль і, можливо, одноразовий код
+	amr := []string{"pwd"}
+	if twoFactor {
+		amr = append(amr, "otp")
+	}
+
+	// Створення та підписання JWT токена активним ключем
+	now := time.Now()
+	accessToken, err := s.cfg.Keys.Sign(jwt.MapClaims{
+		"aud":     accessTokenAudience,
+		"jti":     jti,
+		"sid":     sessionID,
+		"iat":     now.Unix(),
+		"user_id": user.ID,
+		"email":   user.Email,
+		"role":    string(user.Role),
+		"amr":     amr,
+		"exp":     now.Add(s.cfg.AccessTokenTTL).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Refresh токен - випадковий рядок; у базі зберігається лише його хеш
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	err = s.refreshRepo.Create(ctx, &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  sessionID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: now.Add(s.cfg.RefreshTokenTTL),
+		TwoFactor: twoFactor,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    s.cfg.AccessTokenTTL,
+	}, nil
+}
+
+// revokeSession відкликає всі refresh токени сесії та всі видані в ній токени доступу
+func (s *authService) revokeSession(ctx context.Context, sessionID string, userID uint) error {
+	now := time.Now()
+	if err := s.refreshRepo.RevokeFamily(ctx, sessionID, now); err != nil {
+		return err
+	}
+
+	// Токени доступу сесії перестають діяти не пізніше ніж через AccessTokenTTL
+	return s.denylist.Revoke(ctx, sessionRevocationPrefix+sessionID, userID, now.Add(s.cfg.AccessTokenTTL))
+}
+
+func (s *authService) ParseToken(ctx context.Context, token string) (*TokenClaims, error) {
+	claims, err := s.verifyToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Перевірка, чи не був відкликаний сам токен або вся його сесія
+	revoked, err := s.denylist.IsRevoked(ctx, claims.ID, sessionRevocationPrefix+claims.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("check token revocation: %w", err)
+	}
+	if revoked {
+		return nil, errors.New("token has been revoked")
+	}
+
+	return claims, nil
+}
+
+// parseSigned перевіряє підпис, термін дії та призначення (claim "aud") JWT і
+// повертає його claims
+func (s *authService) parseSigned(token, audience string) (jwt.MapClaims, error) {
+	// Парсинг JWT токена: ключ перевірки вибирається за заголовком "kid",
+	// а дозволені алгоритми обмежені алгоритмами ключів набору
+	parsedToken, err := jwt.Parse(token, s.cfg.Keys.Keyfunc,
+		jwt.WithValidMethods(s.cfg.Keys.Methods()),
+		jwt.WithAudience(audience))
+
+	if err != nil {
+		return nil, err
 	}
 
 	// Перевірка валідності токена
 	if !parsedToken.Valid {
-		return 0, errors.New("invalid token")
+		return nil, errors.New("invalid token")
 	}
 
 	// Отримання claims
 	claims, ok := parsedToken.Claims.(jwt.MapClaims)
 	if !ok {
-		return 0, errors.New("invalid token claims")
+		return nil, errors.New("invalid token claims")
+	}
+	return claims, nil
+}
+
+// verifyToken перевіряє токен доступу та повертає його claims
+func (s *authService) verifyToken(token string) (*TokenClaims, error) {
+	// Токени іншого призначення (наприклад, другого кроку входу) підписані тими
+	// самими ключами, але мають інший "aud" і не є токенами доступу
+	claims, err := s.parseSigned(token, accessTokenAudience)
+	if err != nil {
+		return nil, err
+	}
+	if typ, ok := claims["typ"]; ok && typ != "" {
+		return nil, errors.New("invalid token type")
+	}
+
+	// Без jti токен неможливо відкликати, тому такі токени не приймаються
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return nil, errors.New("invalid jti claim")
+	}
+
+	// Сесія відкликається цілком, тому токен без неї відкликати неможливо
+	sessionID, ok := claims["sid"].(string)
+	if !ok || sessionID == "" {
+		return nil, errors.New("invalid sid claim")
 	}
 
 	// Отримання ID користувача
 	userID, ok := claims["user_id"].(float64)
 	if !ok {
-		return 0, errors.New("invalid user_id claim")
+		return nil, errors.New("invalid user_id claim")
 	}
 
-	return uint(userID), nil
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return nil, errors.New("invalid exp claim")
+	}
+
+	claim, _ := claims["role"].(string)
+	role := models.Role(claim)
+	if !role.Valid() {
+		return nil, errors.New("invalid role claim")
+	}
+
+	// Без "amr" неможливо встановити, чи пройдено другий фактор
+	amr, ok := claims["amr"].([]interface{})
+	if !ok || len(amr) == 0 {
+		return nil, errors.New("invalid amr claim")
+	}
+	var twoFactor bool
+	for _, method := range amr {
+		if method == "otp" {
+			twoFactor = true
+		}
+	}
+
+	return &TokenClaims{
+		ID:        jti,
+		SessionID: sessionID,
+		UserID:    uint(userID),
+		Role:      role,
+		TwoFactor: twoFactor,
+		ExpiresAt: exp.Time,
+	}, nil
+}
+
+// newRefreshToken генерує випадковий refresh токен
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken повертає SHA-256 хеш токена для зберігання в базі. Повільний хеш
+// не потрібен: токен має 256 біт випадковості і не підбирається перебором.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newTokenID генерує випадковий ідентифікатор токена (claim "jti")
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"product-recommendations-go/internal/jwtkeys"
+	"product-recommendations-go/internal/models"
+)
+
+// accessClaims повертає claims токена доступу, як їх видає issueTokens
+func accessClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"aud":     accessTokenAudience,
+		"jti":     "token-id",
+		"sid":     "session-id",
+		"iat":     now.Unix(),
+		"user_id": 7,
+		"email":   "user@example.com",
+		"role":    "customer",
+		"amr":     []string{"pwd"},
+		"exp":     now.Add(time.Minute).Unix(),
+	}
+}
+
+func TestVerifyTokenRequiresClaims(t *testing.T) {
+	keys := jwtkeys.NewHMACKeySet([]byte("test-secret"))
+	s := &authService{cfg: AuthConfig{Keys: keys}}
+
+	tests := []struct {
+		name          string
+		modify        func(claims jwt.MapClaims)
+		wantTwoFactor bool
+		wantErr       bool
+	}{
+		{name: "issued claims", modify: func(jwt.MapClaims) {}},
+		{name: "second factor", modify: func(c jwt.MapClaims) { c["amr"] = []string{"pwd", "otp"} }, wantTwoFactor: true},
+		{name: "without role", modify: func(c jwt.MapClaims) { delete(c, "role") }, wantErr: true},
+		{name: "unknown role", modify: func(c jwt.MapClaims) { c["role"] = "owner" }, wantErr: true},
+		{name: "without jti", modify: func(c jwt.MapClaims) { delete(c, "jti") }, wantErr: true},
+		{name: "without session", modify: func(c jwt.MapClaims) { delete(c, "sid") }, wantErr: true},
+		{name: "empty session", modify: func(c jwt.MapClaims) { c["sid"] = "" }, wantErr: true},
+		{name: "without amr", modify: func(c jwt.MapClaims) { delete(c, "amr") }, wantErr: true},
+		{name: "empty amr", modify: func(c jwt.MapClaims) { c["amr"] = []string{} }, wantErr: true},
+		{name: "amr is not a list", modify: func(c jwt.MapClaims) { c["amr"] = "pwd" }, wantErr: true},
+		{name: "without audience", modify: func(c jwt.MapClaims) { delete(c, "aud") }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := accessClaims()
+			tt.modify(claims)
+			token, err := keys.Sign(claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := s.verifyToken(token)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("token accepted: %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("token rejected: %v", err)
+			}
+			if got.UserID != 7 || got.ID != "token-id" || got.SessionID != "session-id" || got.Role != "customer" ||
+				got.TwoFactor != tt.wantTwoFactor {
+				t.Errorf("got %+v", got)
+			}
+		})
+	}
+}
+
+// memoryRefreshTokens refresh токени в пам'яті
+type memoryRefreshTokens struct {
+	tokens []*models.RefreshToken
+}
+
+func (r *memoryRefreshTokens) Create(_ context.Context, token *models.RefreshToken) error {
+	token.ID = uint(len(r.tokens) + 1)
+	copied := *token
+	r.tokens = append(r.tokens, &copied)
+	return nil
+}
+
+func (r *memoryRefreshTokens) GetByHash(_ context.Context, tokenHash string) (*models.RefreshToken, error) {
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryRefreshTokens) MarkUsed(_ context.Context, id uint, usedAt time.Time) (bool, error) {
+	for _, token := range r.tokens {
+		if token.ID == id && token.UsedAt == nil {
+			token.UsedAt = &usedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryRefreshTokens) RevokeFamily(_ context.Context, familyID string, revokedAt time.Time) error {
+	for _, token := range r.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+func (r *memoryRefreshTokens) ActiveFamilies(_ context.Context, userID uint, now time.Time) ([]string, error) {
+	seen := make(map[string]bool)
+	var families []string
+	for _, token := range r.tokens {
+		if token.UserID == userID && token.RevokedAt == nil && token.ExpiresAt.After(now) && !seen[token.FamilyID] {
+			seen[token.FamilyID] = true
+			families = append(families, token.FamilyID)
+		}
+	}
+	return families, nil
+}
+
+func (r *memoryRefreshTokens) GetByUserID(_ context.Context, userID uint) ([]*models.RefreshToken, error) {
+	var tokens []*models.RefreshToken
+	for _, token := range r.tokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (r *memoryRefreshTokens) DeleteExpired(_ context.Context, before time.Time) (int64, error) {
+	kept := r.tokens[:0]
+	for _, token := range r.tokens {
+		if token.ExpiresAt.After(before) {
+			kept = append(kept, token)
+		}
+	}
+	deleted := int64(len(r.tokens) - len(kept))
+	r.tokens = kept
+	return deleted, nil
+}
+
+// newSessionService сервіс з репозиторіями сесій у пам'яті та одним користувачем
+func newSessionService() (*authService, *memoryRefreshTokens) {
+	refresh := &memoryRefreshTokens{}
+	return &authService{
+		userRepo:    &accountUsers{user: &models.User{ID: 7, Email: "user@example.com", Role: models.RoleCustomer}},
+		refreshRepo: refresh,
+		denylist:    NewTokenDenylist(&memoryRevokedTokens{}, 0),
+		cfg: AuthConfig{
+			Keys:            jwtkeys.NewHMACKeySet([]byte("test-secret")),
+			AccessTokenTTL:  time.Minute,
+			RefreshTokenTTL: time.Hour,
+		},
+	}, refresh
+}
+
+func TestRefreshRotatesAndDetectsReuse(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newSessionService()
+	user := &models.User{ID: 7, Role: models.RoleCustomer}
+
+	first, err := s.issueTokens(ctx, user, "family-a", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := s.issueTokens(ctx, user, "family-b", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := s.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := s.ParseToken(ctx, second.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.SessionID != "family-a" || !claims.TwoFactor {
+		t.Errorf("rotated token lost the session: %+v", claims)
+	}
+
+	// Повторне використання обміняного токена відкликає всю сесію
+	if _, err := s.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reused token: got %v, want ErrRefreshTokenReused", err)
+	}
+
+	tests := []struct {
+		name    string
+		refresh func() error
+	}{
+		{
+			name:    "rotated refresh token",
+			refresh: func() error { _, err := s.Refresh(ctx, second.RefreshToken); return err },
+		},
+		{
+			name:    "access token of the session",
+			refresh: func() error { _, err := s.ParseToken(ctx, second.AccessToken); return err },
+		},
+		{
+			name:    "first access token",
+			refresh: func() error { _, err := s.ParseToken(ctx, first.AccessToken); return err },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.refresh(); err == nil {
+				t.Error("revoked session still usable")
+			}
+		})
+	}
+
+	if _, err := s.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Errorf("other session revoked: %v", err)
+	}
+}
+
+func TestRefreshRejectsInvalidTokens(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, s *authService, refresh *memoryRefreshTokens) string
+		wantErr error
+	}{
+		{
+			name:    "unknown",
+			prepare: func(*testing.T, *authService, *memoryRefreshTokens) string { return "unknown" },
+			wantErr: ErrInvalidRefreshToken,
+		},
+		{
+			name: "expired",
+			prepare: func(_ *testing.T, s *authService, refresh *memoryRefreshTokens) string {
+				tokens, _ := s.issueTokens(ctx, &models.User{ID: 7, Role: models.RoleCustomer}, "family", false)
+				refresh.tokens[0].ExpiresAt = time.Now().Add(-time.Second)
+				return tokens.RefreshToken
+			},
+			wantErr: ErrInvalidRefreshToken,
+		},
+		{
+			name: "revoked by logout",
+			prepare: func(t *testing.T, s *authService, _ *memoryRefreshTokens) string {
+				tokens, _ := s.issueTokens(ctx, &models.User{ID: 7, Role: models.RoleCustomer}, "family", false)
+				if err := s.Logout(ctx, tokens.AccessToken); err != nil {
+					t.Fatal(err)
+				}
+				return tokens.RefreshToken
+			},
+			wantErr: ErrInvalidRefreshToken,
+		},
+		{
+			name: "deleted user",
+			prepare: func(_ *testing.T, s *authService, _ *memoryRefreshTokens) string {
+				tokens, _ := s.issueTokens(ctx, &models.User{ID: 8, Role: models.RoleCustomer}, "family", false)
+				return tokens.RefreshToken
+			},
+			wantErr: ErrInvalidRefreshToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, refresh := newSessionService()
+			token := tt.prepare(t, s, refresh)
+			if _, err := s.Refresh(ctx, token); !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
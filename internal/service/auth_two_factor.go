@@ -0,0 +1,362 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"log"
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/totp"
+	"strings"
+	"time"
+)
+
+// Помилки двофакторної автентифікації
+var (
+	// ErrTwoFactorAlreadyEnabled двофакторну автентифікацію вже увімкнено
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTwoFactorNotEnabled двофакторну автентифікацію не увімкнено
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrTwoFactorNotSetUp підтвердження без попереднього SetupTwoFactor
+	ErrTwoFactorNotSetUp = errors.New("two-factor authentication setup has not been started")
+	// ErrInvalidTwoFactorCode неправильний код TOTP або резервний код
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	// ErrInvalidChallenge токен другого кроку входу недійсний або прострочений
+	ErrInvalidChallenge = errors.New("invalid or expired login challenge")
+)
+
+const (
+	// challengeTokenType значення claim "typ" токена другого кроку входу
+	challengeTokenType = "2fa"
+	// challengeTokenAudience значення claim "aud" токена другого кроку входу. Токен
+	// підписується ключами токенів доступу, тому інше призначення не дозволяє
+	// сервісам, що перевіряють токени за JWKS, прийняти його як токен доступу.
+	challengeTokenAudience = "2fa-challenge"
+	// totpSkew допустима розбіжність годинників у кроках TOTP
+	totpSkew = 1
+	// recoveryCodeCount кількість резервних кодів
+	recoveryCodeCount = 10
+	// recoveryCodeSize довжина резервного коду в байтах (80 біт)
+	recoveryCodeSize = 10
+)
+
+// recoveryEncoding кодування резервних кодів: лише великі літери та цифри 2-7
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func (s *authService) VerifyTwoFactor(ctx context.Context, challenge, code string, client ClientInfo) (*TokenPair, error) {
+	userID, err := s.parseChallenge(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.TOTPEnabledAt == nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	// Коди підбираються під тими самими обмеженнями, що й пароль
+	reservation, err := s.throttle.Reserve(ctx, user.Email, client)
+	if err != nil {
+		var throttled *LoginThrottledError
+		if errors.As(err, &throttled) {
+			s.recordLoginFailure(ctx, user.Email, &user.ID, client, models.LoginFailureThrottled)
+		}
+		return nil, err
+	}
+
+	if err := s.checkSecondFactor(ctx, user, code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			s.recordLoginFailure(ctx, user.Email, &user.ID, client, models.LoginFailureInvalidCode)
+		} else {
+			s.releaseLoginAttempt(ctx, reservation)
+		}
+		return nil, err
+	}
+
+	s.releaseLoginAttempt(ctx, reservation)
+	return s.startSession(ctx, user, true)
+}
+
+func (s *authService) SetupTwoFactor(ctx context.Context, userID uint) (*TwoFactorSetup, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	// Повторний виклик до підтвердження замінює секрет: діє лише останній QR-код
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.cfg.TOTPSecrets.Seal(secret, totpSecretContext(user.ID))
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPSecret = sealed
+	user.TOTPLastStep = 0
+	if err := s.userRepo.UpdateColumns(ctx, user, "totp_secret", "totp_last_step"); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorSetup{
+		Secret: secret,
+		URI:    totp.URI(s.cfg.TOTPIssuer, user.Email, secret),
+	}, nil
+}
+
+func (s *authService) EnableTwoFactor(ctx context.Context, userID uint, code string, client ClientInfo) ([]string, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotSetUp
+	}
+
+	// Код підтверджує, що застосунок налаштовано правильно
+	err = s.reauthenticate(ctx, user, client, func() error {
+		return s.checkTOTP(ctx, user, code)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := s.replaceRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Паралельний SetupTwoFactor міг замінити секрет після перевірки коду: тоді
+	// вмикати нічим не підтверджений секрет не можна
+	now := time.Now()
+	enabled, err := s.userRepo.EnableTOTP(ctx, user.ID, user.TOTPSecret, now)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrTwoFactorNotSetUp
+	}
+	user.TOTPEnabledAt = &now
+
+	log.Printf("Two-factor authentication enabled for user %d", user.ID)
+	return codes, nil
+}
+
+func (s *authService) DisableTwoFactor(ctx context.Context, userID uint, password, code string, client ClientInfo) error {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.TOTPEnabledAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+
+	// Викрадений токен доступу не повинен дозволяти вимкнути другий фактор
+	err = s.reauthenticate(ctx, user, client, func() error {
+		if err := checkPassword(user, password); err != nil {
+			return err
+		}
+		return s.checkSecondFactor(ctx, user, code)
+	})
+	if err != nil {
+		return err
+	}
+
+	user.TOTPSecret = ""
+	user.TOTPEnabledAt = nil
+	user.TOTPLastStep = 0
+	if err := s.userRepo.UpdateColumns(ctx, user, "totp_secret", "totp_enabled_at", "totp_last_step"); err != nil {
+		return err
+	}
+	if err := s.recoveryRepo.Replace(ctx, user.ID, nil); err != nil {
+		return err
+	}
+
+	log.Printf("Two-factor authentication disabled for user %d", user.ID)
+	return nil
+}
+
+func (s *authService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string, client ClientInfo) ([]string, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt == nil {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	// Нові коди видаються лише за кодом з застосунку: резервний код тут не підходить
+	err = s.reauthenticate(ctx, user, client, func() error {
+		return s.checkTOTP(ctx, user, code)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.replaceRecoveryCodes(ctx, user.ID)
+}
+
+// issueChallenge видає токен другого кроку входу. Токен підписаний ключами токенів
+// доступу, але має claim "typ" і не приймається як токен доступу.
+func (s *authService) issueChallenge(user *models.User) (*TwoFactorChallenge, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	token, err := s.cfg.Keys.Sign(jwt.MapClaims{
+		"typ":     challengeTokenType,
+		"aud":     challengeTokenAudience,
+		"jti":     jti,
+		"iat":     now.Unix(),
+		"user_id": user.ID,
+		"exp":     now.Add(s.cfg.ChallengeTTL).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorChallenge{Token: token, ExpiresIn: s.cfg.ChallengeTTL}, nil
+}
+
+// parseChallenge перевіряє токен другого кроку входу та повертає ID користувача
+func (s *authService) parseChallenge(token string) (uint, error) {
+	claims, err := s.parseSigned(token, challengeTokenAudience)
+	if err != nil {
+		return 0, ErrInvalidChallenge
+	}
+	if claims["typ"] != challengeTokenType {
+		return 0, ErrInvalidChallenge
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, ErrInvalidChallenge
+	}
+	return uint(userID), nil
+}
+
+// checkSecondFactor приймає код TOTP або, якщо код не схожий на TOTP, резервний код
+func (s *authService) checkSecondFactor(ctx context.Context, user *models.User, code string) error {
+	normalized := normalizeRecoveryCode(code)
+	if len(normalized) == totp.Digits {
+		return s.checkTOTP(ctx, user, code)
+	}
+
+	used, err := s.recoveryRepo.Use(ctx, user.ID, hashToken(normalized), time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+
+	remaining, err := s.recoveryRepo.CountUnused(ctx, user.ID)
+	if err == nil && remaining <= 2 {
+		log.Printf("User %d has %d recovery codes left", user.ID, remaining)
+	}
+	return nil
+}
+
+// checkTOTP перевіряє код TOTP. Кожен код приймається лише один раз: крок
+// прийнятого коду атомарно запам'ятовується в записі користувача.
+func (s *authService) checkTOTP(ctx context.Context, user *models.User, code string) error {
+	secret, err := s.cfg.TOTPSecrets.Open(user.TOTPSecret, totpSecretContext(user.ID))
+	if err != nil {
+		return fmt.Errorf("decrypt TOTP secret of user %d: %w", user.ID, err)
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now(), totpSkew, user.TOTPLastStep)
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	advanced, err := s.userRepo.AdvanceTOTPStep(ctx, user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !advanced {
+		return ErrInvalidTwoFactorCode
+	}
+	user.TOTPLastStep = step
+	return nil
+}
+
+// totpSecretContext дані, до яких прив'язується зашифрований секрет TOTP: секрет,
+// скопійований в інший запис користувача, не розшифровується
+func totpSecretContext(userID uint) string {
+	return fmt.Sprintf("totp:%d", userID)
+}
+
+// replaceRecoveryCodes генерує нові резервні коди замість наявних і повертає їх
+// у відкритому вигляді - єдиний раз, коли їх можна показати користувачу
+func (s *authService) replaceRecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]*models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := recoveryEncoding.EncodeToString(b)
+
+		codes = append(codes, formatRecoveryCode(code))
+		records = append(records, &models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: hashToken(code),
+		})
+	}
+
+	if err := s.recoveryRepo.Replace(ctx, userID, records); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// getUser повертає користувача або помилку, якщо його не існує
+func (s *authService) getUser(ctx context.Context, userID uint) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+// formatRecoveryCode розбиває код на групи по 4 символи для зручності введення
+func formatRecoveryCode(code string) string {
+	var b strings.Builder
+	for i, r := range code {
+		if i > 0 && i%4 == 0 {
+			b.WriteByte('-')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// normalizeRecoveryCode прибирає роздільники та пробіли і переводить код у верхній регістр
+func normalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', ' ':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(code)))
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"product-recommendations-go/internal/jwtkeys"
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/secretbox"
+	"product-recommendations-go/internal/totp"
+)
+
+func TestChallengeTokenIsNotAnAccessToken(t *testing.T) {
+	keys := jwtkeys.NewHMACKeySet([]byte("test-secret"))
+	s := &authService{cfg: AuthConfig{Keys: keys, ChallengeTTL: time.Minute}}
+
+	challenge, err := s.issueChallenge(&models.User{ID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID, err := s.parseChallenge(challenge.Token); err != nil || userID != 7 {
+		t.Fatalf("parseChallenge = %d, %v; want 7", userID, err)
+	}
+	if _, err := s.verifyToken(challenge.Token); err == nil {
+		t.Error("challenge token accepted as an access token")
+	}
+
+	// Сторонній сервіс, що перевіряє лише підпис і "aud", також відрізняє токени
+	_, err = jwt.Parse(challenge.Token, keys.Keyfunc, jwt.WithAudience(accessTokenAudience))
+	if err == nil {
+		t.Error("challenge token has the access token audience")
+	}
+
+	// Токен доступу не приймається як токен другого кроку
+	access, err := keys.Sign(accessClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.verifyToken(access); err != nil {
+		t.Fatalf("access token rejected: %v", err)
+	}
+	if _, err := s.parseChallenge(access); err == nil {
+		t.Error("access token accepted as a challenge token")
+	}
+
+	// Токени доступу без "aud" від попередніх версій не приймаються
+	legacy, err := keys.Sign(jwt.MapClaims{"jti": "id", "user_id": 7, "exp": time.Now().Add(time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.verifyToken(legacy); err == nil {
+		t.Error("access token without audience accepted")
+	}
+}
+
+type discardRecoveryCodes struct{}
+
+func (discardRecoveryCodes) Replace(context.Context, uint, []*models.RecoveryCode) error { return nil }
+
+func (discardRecoveryCodes) Use(context.Context, uint, string, time.Time) (bool, error) {
+	return false, nil
+}
+
+func (discardRecoveryCodes) CountUnused(context.Context, uint) (int64, error) { return 0, nil }
+
+func TestTwoFactorSecretIsEncryptedAndCodesAreThrottled(t *testing.T) {
+	box, err := secretbox.New(bytes.Repeat([]byte{7}, secretbox.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := &accountUsers{user: &models.User{ID: 1, Email: "user@example.com"}}
+	s := &authService{
+		userRepo:     users,
+		recoveryRepo: discardRecoveryCodes{},
+		throttle:     testLoginThrottle(),
+		cfg:          AuthConfig{TOTPIssuer: "Test", TOTPSecrets: box},
+	}
+	ctx := context.Background()
+
+	setup, err := s.SetupTwoFactor(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if users.user.TOTPSecret == "" || strings.Contains(users.user.TOTPSecret, setup.Secret) {
+		t.Fatalf("stored secret %q is not encrypted", users.user.TOTPSecret)
+	}
+
+	// Неправильні коди враховуються обмеженнями входу
+	for i := 0; i < 3; i++ {
+		if _, err := s.EnableTwoFactor(ctx, 1, "000000", ClientInfo{}); !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Fatalf("attempt %d: got %v, want ErrInvalidTwoFactorCode", i+1, err)
+		}
+	}
+	code, err := totp.Code(setup.Secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var throttled *LoginThrottledError
+	if _, err := s.EnableTwoFactor(ctx, 1, code, ClientInfo{}); !errors.As(err, &throttled) {
+		t.Fatalf("got %v, want throttling", err)
+	}
+
+	// Після скидання лічильника правильний код з розшифрованого секрету приймається
+	if err := s.throttle.RecordSuccess(ctx, "user@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.EnableTwoFactor(ctx, 1, code, ClientInfo{}); err != nil {
+		t.Fatalf("valid code rejected: %v", err)
+	}
+	if users.user.TOTPEnabledAt == nil {
+		t.Error("two-factor authentication not enabled")
+	}
+}
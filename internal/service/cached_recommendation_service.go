@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"product-recommendations-go/internal/cache"
+	"product-recommendations-go/internal/models"
+)
+
+type cachedRecommendationService struct {
+	next     RecommendationService
+	cache    cache.RecommendationCache
+	strategy string
+}
+
+// NewCachedRecommendationService створює сервіс рекомендацій з кешуванням результатів.
+// Ключ кешу складається з користувача, ліміту, фільтра та конфігурації стратегії strategy,
+// тому зміна конфігурації не призводить до повернення результатів старої стратегії.
+func NewCachedRecommendationService(next RecommendationService, recommendationCache cache.RecommendationCache, strategy string) RecommendationService {
+	return &cachedRecommendationService{
+		next:     next,
+		cache:    recommendationCache,
+		strategy: strategy,
+	}
+}
+
+func (s *cachedRecommendationService) GetRecommendations(ctx context.Context, userID uint, limit int, filter models.RecommendationFilter) ([]*models.ProductRecommendation, error) {
+	// Нормалізуємо ліміт так само, як базовий сервіс, щоб не дублювати записи
+	if limit <= 0 {
+		limit = 10
+	}
+	key := fmt.Sprintf("strategy=%s|limit=%d|%s", s.strategy, limit, filter.Key())
+
+	// Версію читаємо до обчислення: якщо під час обчислення кеш користувача
+	// буде інвалідовано, результат збережеться під застарілою версією і не буде прочитаний
+	version, err := s.cache.Version(ctx, userID)
+	if err != nil {
+		log.Printf("Recommendation cache unavailable: %v", err)
+		return s.next.GetRecommendations(ctx, userID, limit, filter)
+	}
+
+	cached, found, err := s.cache.Get(ctx, userID, version, key)
+	if err != nil {
+		log.Printf("Error reading recommendation cache: %v", err)
+	}
+	if found {
+		return cached, nil
+	}
+
+	recommendations, err := s.next.GetRecommendations(ctx, userID, limit, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cache.Set(ctx, userID, version, key, recommendations); err != nil {
+		log.Printf("Error writing recommendation cache: %v", err)
+	}
+
+	return recommendations, nil
+}
+
+// GetBatchRecommendations обчислює пакет напряму, оминаючи кеш: пакетні
+// розсилки охоплюють тисячі користувачів і лише витісняли б інтерактивні записи
+func (s *cachedRecommendationService) GetBatchRecommendations(
+	ctx context.Context,
+	userIDs []uint,
+	limit, concurrency int,
+	filter models.RecommendationFilter,
+	emit func(result *models.BatchRecommendationResult) error,
+) error {
+	return s.next.GetBatchRecommendations(ctx, userIDs, limit, concurrency, filter, emit)
+}
+
+// GetCartRecommendations не кешується: вміст кошика змінюється частіше, ніж
+// кеш рекомендацій користувача інвалідовується
+func (s *cachedRecommendationService) GetCartRecommendations(ctx context.Context, productIDs []uint, limit int, filter models.RecommendationFilter) ([]*models.ProductRecommendation, error) {
+	return s.next.GetCartRecommendations(ctx, productIDs, limit, filter)
+}
+
+// GetSimilarProducts не кешується: результат не залежить від користувача, а
+// кеш рекомендацій зберігає записи по користувачах
+func (s *cachedRecommendationService) GetSimilarProducts(ctx context.Context, productID uint, limit int, filter models.RecommendationFilter) ([]*models.ProductRecommendation, error) {
+	return s.next.GetSimilarProducts(ctx, productID, limit, filter)
+}
+
+// invalidateRecommendations скидає кеш рекомендацій користувача після нової взаємодії.
+// Помилка кешу не скасовує вже збережену взаємодію, тому лише логується.
+func invalidateRecommendations(ctx context.Context, invalidator RecommendationInvalidator, userID uint) {
+	if invalidator == nil {
+		return
+	}
+	if err := invalidator.InvalidateUser(ctx, userID); err != nil {
+		log.Printf("Error invalidating recommendations for user %d: %v", userID, err)
+	}
+}
+
+// invalidateCatalog скидає кеш рекомендацій усіх користувачів після зміни товарів
+// або наявності товару на складі: закешовані результати можуть містити видалені
+// товари, старі ціни чи товари, яких уже немає в наявності. Звичайне замовлення
+// скидає лише кеш покупця, інакше кеш майже ніколи не спрацьовував би.
+func invalidateCatalog(ctx context.Context, invalidator RecommendationInvalidator) {
+	if invalidator == nil {
+		return
+	}
+	if err := invalidator.InvalidateAll(ctx); err != nil {
+		log.Printf("Error invalidating recommendations after catalog change: %v", err)
+	}
+}
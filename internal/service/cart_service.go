@@ -0,0 +1,413 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/repository"
+	"time"
+)
+
+const (
+	// maxCartItemQuantity максимальна кількість одного товару в кошику
+	maxCartItemQuantity = 99
+	// maxCartItems максимальна кількість різних товарів у кошику
+	maxCartItems = 100
+)
+
+var (
+	// ErrInvalidQuantity кількість товару поза межами 1..maxCartItemQuantity
+	ErrInvalidQuantity = errors.New("quantity must be between 1 and 99")
+	// ErrCartItemNotFound товару немає в кошику
+	ErrCartItemNotFound = errors.New("product is not in the cart")
+	// ErrCartFull у кошику максимальна кількість різних товарів
+	ErrCartFull = errors.New("cart is full")
+	// ErrCartEmpty кошик порожній
+	ErrCartEmpty = errors.New("cart is empty")
+	// ErrCartChanged ціни товарів кошика змінилися або товари більше недоступні
+	ErrCartChanged = errors.New("cart prices have changed")
+	// ErrCheckoutUnauthenticated замовлення з кошика оформлює лише автентифікований користувач
+	ErrCheckoutUnauthenticated = errors.New("checkout requires an authenticated user")
+)
+
+type cartService struct {
+	transactor            repository.Transactor
+	cartRepo              repository.CartRepository
+	productRepo           repository.ProductRepository
+	orderService          OrderService
+	recommendationService RecommendationService
+	guestTTL              time.Duration
+}
+
+// NewCartService створює новий екземпляр сервісу кошика. Кошики гостів, які не
+// змінювалися guestTTL, видаляються під час Cleanup.
+func NewCartService(transactor repository.Transactor, cartRepo repository.CartRepository, productRepo repository.ProductRepository, orderService OrderService, recommendationService RecommendationService, guestTTL time.Duration) CartService {
+	return &cartService{
+		transactor:            transactor,
+		cartRepo:              cartRepo,
+		productRepo:           productRepo,
+		orderService:          orderService,
+		recommendationService: recommendationService,
+		guestTTL:              guestTTL,
+	}
+}
+
+func (s *cartService) Get(ctx context.Context, owner CartOwner) (*CartView, error) {
+	cart, err := s.find(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	return newCartView(cart), nil
+}
+
+func (s *cartService) AddItem(ctx context.Context, owner CartOwner, productID uint, quantity int) (*CartView, error) {
+	if quantity < 1 || quantity > maxCartItemQuantity {
+		return nil, ErrInvalidQuantity
+	}
+
+	product, err := s.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, ErrProductNotFound
+	}
+
+	cart, err := s.find(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	var token string
+	if cart == nil {
+		cart, token, err = s.create(ctx, owner)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	existing := findItem(cart, productID)
+	if len(cart.Items) >= maxCartItems && existing == nil {
+		return nil, ErrCartFull
+	}
+
+	// Залишок остаточно перевіряється під час оформлення; тут лише не даємо
+	// покласти в кошик більше, ніж є на складі зараз
+	inCart := 0
+	if existing != nil {
+		inCart = existing.Quantity
+	}
+	if product.Stock < min(inCart+quantity, maxCartItemQuantity) {
+		return nil, fmt.Errorf("%w: product %d has %d in stock", ErrInsufficientStock, productID, product.Stock)
+	}
+
+	// Ціна фіксується під час додавання; зміни ціни в каталозі показуються в кошику
+	item := &models.CartItem{
+		CartID:    cart.ID,
+		ProductID: productID,
+		Quantity:  quantity,
+		Price:     product.Price,
+	}
+	if err := s.cartRepo.AddItem(ctx, item, maxCartItemQuantity); err != nil {
+		return nil, err
+	}
+
+	view, err := s.reload(ctx, cart)
+	if err != nil {
+		return nil, err
+	}
+	view.Token = token
+	return view, nil
+}
+
+func (s *cartService) UpdateItem(ctx context.Context, owner CartOwner, productID uint, quantity int) (*CartView, error) {
+	if quantity < 1 || quantity > maxCartItemQuantity {
+		return nil, ErrInvalidQuantity
+	}
+
+	cart, err := s.find(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if cart == nil {
+		return nil, ErrCartItemNotFound
+	}
+
+	// Зменшувати кількість можна завжди, збільшувати - лише в межах залишку
+	if item := findItem(cart, productID); item != nil && item.Product.ID != 0 &&
+		quantity > item.Quantity && item.Product.Stock < quantity {
+		return nil, fmt.Errorf("%w: product %d has %d in stock", ErrInsufficientStock, productID, item.Product.Stock)
+	}
+
+	updated, err := s.cartRepo.SetItemQuantity(ctx, cart.ID, productID, quantity)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrCartItemNotFound
+	}
+
+	return s.reload(ctx, cart)
+}
+
+func (s *cartService) RemoveItem(ctx context.Context, owner CartOwner, productID uint) (*CartView, error) {
+	cart, err := s.find(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if cart == nil {
+		return nil, ErrCartItemNotFound
+	}
+
+	removed, err := s.cartRepo.RemoveItem(ctx, cart.ID, productID)
+	if err != nil {
+		return nil, err
+	}
+	if !removed {
+		return nil, ErrCartItemNotFound
+	}
+
+	return s.reload(ctx, cart)
+}
+
+func (s *cartService) RefreshPrices(ctx context.Context, owner CartOwner) (*CartView, error) {
+	cart, err := s.find(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if cart == nil {
+		return newCartView(nil), nil
+	}
+
+	for _, item := range cart.Items {
+		switch {
+		case item.Product.ID == 0:
+			if _, err := s.cartRepo.RemoveItem(ctx, cart.ID, item.ProductID); err != nil {
+				return nil, err
+			}
+		case item.Product.Price != item.Price:
+			if err := s.cartRepo.SetItemPrice(ctx, cart.ID, item.ProductID, item.Product.Price); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return s.reload(ctx, cart)
+}
+
+func (s *cartService) Checkout(ctx context.Context, owner CartOwner) (*models.Order, error) {
+	if owner.UserID == 0 {
+		return nil, ErrCheckoutUnauthenticated
+	}
+
+	cart, err := s.find(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	view := newCartView(cart)
+	if len(view.Lines) == 0 {
+		return nil, ErrCartEmpty
+	}
+	// Користувач має погодитися з новими цінами до оформлення замовлення
+	if view.Changed {
+		return nil, ErrCartChanged
+	}
+
+	order := &models.Order{
+		UserID: owner.UserID,
+		Items:  make([]models.OrderItem, 0, len(view.Lines)),
+	}
+	for _, line := range view.Lines {
+		order.Items = append(order.Items, models.OrderItem{
+			ProductID: line.ProductID,
+			Quantity:  line.Quantity,
+		})
+	}
+
+	// Замовлення створюється і кошик очищується в одній транзакції. Якщо ціна
+	// змінилася після перевірки кошика, замовлення відкочується.
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.orderService.CreateOrder(ctx, order); err != nil {
+			return err
+		}
+		for i, item := range order.Items {
+			if item.Price != view.Lines[i].Price {
+				return ErrCartChanged
+			}
+		}
+		return s.cartRepo.Clear(ctx, cart.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func (s *cartService) Recommendations(ctx context.Context, owner CartOwner, limit int, filter models.RecommendationFilter) ([]*models.ProductRecommendation, error) {
+	cart, err := s.find(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if cart == nil {
+		return nil, nil
+	}
+
+	productIDs := make([]uint, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+
+	return s.recommendationService.GetCartRecommendations(ctx, productIDs, limit, filter)
+}
+
+func (s *cartService) Cleanup(ctx context.Context) error {
+	deleted, err := s.cartRepo.DeleteGuestBefore(ctx, time.Now().Add(-s.guestTTL))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Printf("Deleted %d abandoned guest carts", deleted)
+	}
+	return nil
+}
+
+// find повертає кошик власника або nil, якщо кошика немає. Кошик гостя, токен
+// якого передав автентифікований користувач, переноситься до кошика користувача.
+func (s *cartService) find(ctx context.Context, owner CartOwner) (*models.Cart, error) {
+	var guest *models.Cart
+	if owner.GuestToken != "" {
+		var err error
+		guest, err = s.cartRepo.GetByTokenHash(ctx, hashToken(owner.GuestToken))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if owner.UserID == 0 {
+		return guest, nil
+	}
+	if guest == nil {
+		return s.cartRepo.GetByUserID(ctx, owner.UserID)
+	}
+
+	// Перший запит після входу: кошик гостя стає кошиком користувача або
+	// додається до вже наявного. Обидва кошики блокуються (спершу гостя), тож
+	// паралельний запит з тим самим токеном чекає і вже не знаходить кошика гостя.
+	var cart *models.Cart
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		locked, err := s.cartRepo.GetByTokenHashForUpdate(ctx, hashToken(owner.GuestToken))
+		if err != nil {
+			return err
+		}
+		cart, err = s.cartRepo.GetByUserIDForUpdate(ctx, owner.UserID)
+		if err != nil || locked == nil {
+			return err
+		}
+
+		if cart == nil {
+			err = s.cartRepo.AssignToUser(ctx, locked.ID, owner.UserID)
+		} else {
+			err = s.cartRepo.Merge(ctx, locked.ID, cart.ID, maxCartItemQuantity)
+		}
+		if err != nil {
+			return err
+		}
+
+		cart, err = s.cartRepo.GetByUserID(ctx, owner.UserID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+// create створює кошик власника. Для гостя генерується новий токен кошика.
+func (s *cartService) create(ctx context.Context, owner CartOwner) (*models.Cart, string, error) {
+	cart := &models.Cart{}
+	var token string
+
+	if owner.UserID != 0 {
+		cart.UserID = &owner.UserID
+	} else {
+		var err error
+		token, err = newRefreshToken()
+		if err != nil {
+			return nil, "", err
+		}
+		tokenHash := hashToken(token)
+		cart.TokenHash = &tokenHash
+	}
+
+	if err := s.cartRepo.Create(ctx, cart); err != nil {
+		// Кошик користувача міг бути створений паралельним запитом
+		if owner.UserID != 0 {
+			if existing, getErr := s.cartRepo.GetByUserID(ctx, owner.UserID); getErr == nil && existing != nil {
+				return existing, "", nil
+			}
+		}
+		return nil, "", err
+	}
+
+	return cart, token, nil
+}
+
+// reload повертає актуальний вміст кошика після зміни
+func (s *cartService) reload(ctx context.Context, cart *models.Cart) (*CartView, error) {
+	var err error
+	if cart.UserID != nil {
+		cart, err = s.cartRepo.GetByUserID(ctx, *cart.UserID)
+	} else {
+		cart, err = s.cartRepo.GetByTokenHash(ctx, *cart.TokenHash)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newCartView(cart), nil
+}
+
+// findItem повертає товар кошика або nil, якщо товару в кошику немає
+func findItem(cart *models.Cart, productID uint) *models.CartItem {
+	for i := range cart.Items {
+		if cart.Items[i].ProductID == productID {
+			return &cart.Items[i]
+		}
+	}
+	return nil
+}
+
+// newCartView порівнює збережені ціни кошика з поточними цінами каталогу
+func newCartView(cart *models.Cart) *CartView {
+	view := &CartView{Lines: []CartLine{}}
+	if cart == nil {
+		return view
+	}
+	view.UpdatedAt = &cart.UpdatedAt
+
+	for _, item := range cart.Items {
+		line := CartLine{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			Price:     item.Price,
+			AddedAt:   item.CreatedAt,
+		}
+		if item.Product.ID != 0 {
+			product := item.Product
+			line.Product = &product
+			line.CurrentPrice = product.Price
+		}
+		if line.Product == nil || line.CurrentPrice != line.Price {
+			view.Changed = true
+		}
+
+		view.Total += item.Price * float64(item.Quantity)
+		view.Lines = append(view.Lines, line)
+	}
+
+	return view
+}
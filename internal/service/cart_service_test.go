@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/repository"
+)
+
+// memoryCarts зберігає кошики в пам'яті і перевіряє, що блокування беруться в транзакції
+type memoryCarts struct {
+	repository.CartRepository
+	t          *testing.T
+	transactor *fakeTransactor
+	carts      map[uint]*models.Cart
+	// takenByOther кошик гостя, який перед блокуванням переніс паралельний запит
+	takenByOther bool
+	locks        []string
+	assigned     bool
+	merged       bool
+}
+
+func (r *memoryCarts) find(match func(cart *models.Cart) bool) *models.Cart {
+	for _, cart := range r.carts {
+		if match(cart) {
+			copied := *cart
+			return &copied
+		}
+	}
+	return nil
+}
+
+func (r *memoryCarts) GetByUserID(_ context.Context, userID uint) (*models.Cart, error) {
+	return r.find(func(cart *models.Cart) bool { return cart.UserID != nil && *cart.UserID == userID }), nil
+}
+
+func (r *memoryCarts) GetByTokenHash(_ context.Context, tokenHash string) (*models.Cart, error) {
+	return r.find(func(cart *models.Cart) bool {
+		return cart.UserID == nil && cart.TokenHash != nil && *cart.TokenHash == tokenHash
+	}), nil
+}
+
+func (r *memoryCarts) lock(name string) {
+	if r.transactor.depth == 0 {
+		r.t.Errorf("%s lock taken outside a transaction", name)
+	}
+	r.locks = append(r.locks, name)
+}
+
+func (r *memoryCarts) GetByTokenHashForUpdate(ctx context.Context, tokenHash string) (*models.Cart, error) {
+	r.lock("guest")
+	if r.takenByOther {
+		return nil, nil
+	}
+	return r.GetByTokenHash(ctx, tokenHash)
+}
+
+func (r *memoryCarts) GetByUserIDForUpdate(ctx context.Context, userID uint) (*models.Cart, error) {
+	r.lock("user")
+	return r.GetByUserID(ctx, userID)
+}
+
+func (r *memoryCarts) AssignToUser(_ context.Context, cartID, userID uint) error {
+	r.assigned = true
+	r.carts[cartID].UserID = &userID
+	r.carts[cartID].TokenHash = nil
+	return nil
+}
+
+func (r *memoryCarts) Merge(_ context.Context, fromID, toID uint, _ int) error {
+	r.merged = true
+	r.carts[toID].Items = append(r.carts[toID].Items, r.carts[fromID].Items...)
+	delete(r.carts, fromID)
+	return nil
+}
+
+func TestCartFindMergesGuestCart(t *testing.T) {
+	const guestToken = "guest-token"
+	userID := uint(7)
+
+	tests := []struct {
+		name         string
+		userCart     bool
+		takenByOther bool
+		wantAssigned bool
+		wantMerged   bool
+		wantItems    int
+	}{
+		{name: "guest cart becomes the user cart", wantAssigned: true, wantItems: 1},
+		{name: "guest cart is merged into the user cart", userCart: true, wantMerged: true, wantItems: 2},
+		{name: "guest cart already taken by a parallel request", userCart: true, takenByOther: true, wantItems: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transactor := &fakeTransactor{}
+			tokenHash := hashToken(guestToken)
+			carts := &memoryCarts{
+				t:            t,
+				transactor:   transactor,
+				takenByOther: tt.takenByOther,
+				carts: map[uint]*models.Cart{
+					1: {ID: 1, TokenHash: &tokenHash, Items: []models.CartItem{{ProductID: 1, Quantity: 1}}},
+				},
+			}
+			if tt.userCart {
+				carts.carts[2] = &models.Cart{ID: 2, UserID: &userID, Items: []models.CartItem{{ProductID: 2, Quantity: 1}}}
+			}
+			s := &cartService{transactor: transactor, cartRepo: carts}
+
+			cart, err := s.find(context.Background(), CartOwner{UserID: userID, GuestToken: guestToken})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cart == nil || cart.UserID == nil || *cart.UserID != userID || len(cart.Items) != tt.wantItems {
+				t.Fatalf("got cart %+v, want the user cart with %d items", cart, tt.wantItems)
+			}
+			if carts.assigned != tt.wantAssigned || carts.merged != tt.wantMerged {
+				t.Errorf("got assigned %v and merged %v, want %v and %v", carts.assigned, carts.merged, tt.wantAssigned, tt.wantMerged)
+			}
+			// Кошик гостя блокується першим, щоб паралельні запити не взаємоблокувалися
+			if len(carts.locks) != 2 || carts.locks[0] != "guest" || carts.locks[1] != "user" {
+				t.Errorf("got locks %v, want [guest user]", carts.locks)
+			}
+		})
+	}
+}
+
+func TestCartFindWithoutGuestCartSkipsTransaction(t *testing.T) {
+	userID := uint(7)
+	carts := &memoryCarts{
+		t:          t,
+		transactor: &fakeTransactor{},
+		carts:      map[uint]*models.Cart{2: {ID: 2, UserID: &userID}},
+	}
+	s := &cartService{transactor: carts.transactor, cartRepo: carts}
+
+	cart, err := s.find(context.Background(), CartOwner{UserID: userID, GuestToken: "merged-earlier"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cart == nil || cart.ID != 2 || len(carts.locks) != 0 {
+		t.Errorf("got cart %+v and locks %v, want cart 2 without locks", cart, carts.locks)
+	}
+}
+
+func TestCheckoutRequiresUser(t *testing.T) {
+	s := &cartService{}
+	if _, err := s.Checkout(context.Background(), CartOwner{GuestToken: "guest-token"}); !errors.Is(err, ErrCheckoutUnauthenticated) {
+		t.Errorf("got %v, want %v", err, ErrCheckoutUnauthenticated)
+	}
+}
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/repository"
+	"time"
+)
+
+// DataExportConfig параметри вивантаження даних користувачів
+type DataExportConfig struct {
+	// Dir каталог, у якому зберігаються архіви; за кількох екземплярів API має бути спільним
+	Dir string
+	// TTL як довго готовий архів доступний для завантаження
+	TTL time.Duration
+	// PollInterval як часто перевіряється черга запитів, створених іншими екземплярами
+	PollInterval time.Duration
+	// StaleAfter через який час запит, що обробляється, вважається покинутим і береться повторно
+	StaleAfter time.Duration
+}
+
+type dataExportService struct {
+	exportRepo            repository.DataExportRepository
+	userRepo              repository.UserRepository
+	likeRepo              repository.UserLikeRepository
+	orderRepo             repository.OrderRepository
+	loginAttemptRepo      repository.LoginAttemptRepository
+	refreshRepo           repository.RefreshTokenRepository
+	apiKeyRepo            repository.APIKeyRepository
+	identityRepo          repository.UserIdentityRepository
+	cartRepo              repository.CartRepository
+	recommendationService RecommendationService
+	cfg                   DataExportConfig
+
+	// wake будить обробник черги після нового запиту в цьому екземплярі
+	wake chan struct{}
+}
+
+// NewDataExportService створює новий екземпляр сервісу вивантаження даних.
+// Архіви формуються у фоні методом Run.
+func NewDataExportService(exportRepo repository.DataExportRepository, userRepo repository.UserRepository, likeRepo repository.UserLikeRepository, orderRepo repository.OrderRepository, loginAttemptRepo repository.LoginAttemptRepository, refreshRepo repository.RefreshTokenRepository, apiKeyRepo repository.APIKeyRepository, identityRepo repository.UserIdentityRepository, cartRepo repository.CartRepository, recommendationService RecommendationService, cfg DataExportConfig) DataExportService {
+	return &dataExportService{
+		exportRepo:            exportRepo,
+		userRepo:              userRepo,
+		likeRepo:              likeRepo,
+		orderRepo:             orderRepo,
+		loginAttemptRepo:      loginAttemptRepo,
+		refreshRepo:           refreshRepo,
+		apiKeyRepo:            apiKeyRepo,
+		identityRepo:          identityRepo,
+		cartRepo:              cartRepo,
+		recommendationService: recommendationService,
+		cfg:                   cfg,
+		wake:                  make(chan struct{}, 1),
+	}
+}
+
+func (s *dataExportService) Request(ctx context.Context, userID uint) (*models.DataExport, error) {
+	latest, err := s.exportRepo.GetLatestByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Поки архів формується або доступний, новий запит не створюється: це й
+	// обмежує частоту вивантажень
+	if latest != nil {
+		switch latest.Status {
+		case models.DataExportPending, models.DataExportProcessing:
+			return latest, nil
+		case models.DataExportReady:
+			if latest.ExpiresAt != nil && time.Now().Before(*latest.ExpiresAt) {
+				return latest, nil
+			}
+		}
+	}
+
+	export := &models.DataExport{
+		UserID: userID,
+		Status: models.DataExportPending,
+	}
+	if err := s.exportRepo.Create(ctx, export); err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return export, nil
+}
+
+func (s *dataExportService) Open(export *models.DataExport) (*os.File, error) {
+	if export.Status != models.DataExportReady || export.FilePath == "" {
+		return nil, errors.New("data export is not ready")
+	}
+	return os.Open(filepath.Join(s.cfg.Dir, export.FilePath))
+}
+
+func (s *dataExportService) Run(ctx context.Context) {
+	if err := os.MkdirAll(s.cfg.Dir, 0o700); err != nil {
+		log.Printf("Data export directory %s is unavailable: %v", s.cfg.Dir, err)
+	}
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.processQueue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+func (s *dataExportService) Cleanup(ctx context.Context) error {
+	expired, err := s.exportRepo.GetExpired(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, export := range expired {
+		if export.FilePath != "" {
+			path := filepath.Join(s.cfg.Dir, export.FilePath)
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		if err := s.exportRepo.Delete(ctx, export.ID); err != nil {
+			return err
+		}
+	}
+
+	if len(expired) > 0 {
+		log.Printf("Removed %d expired data exports", len(expired))
+	}
+	return nil
+}
+
+// processQueue формує архіви, доки в черзі є запити
+func (s *dataExportService) processQueue(ctx context.Context) {
+	for ctx.Err() == nil {
+		now := time.Now()
+		export, err := s.exportRepo.ClaimNext(ctx, now, now.Add(-s.cfg.StaleAfter))
+		if err != nil {
+			log.Printf("Failed to claim data export: %v", err)
+			return
+		}
+		if export == nil {
+			return
+		}
+
+		s.process(ctx, export)
+	}
+}
+
+// process формує архів запиту і зберігає результат
+func (s *dataExportService) process(ctx context.Context, export *models.DataExport) {
+	started := time.Now()
+	name, size, err := s.writeArchive(ctx, export)
+
+	now := time.Now()
+	expiresAt := now.Add(s.cfg.TTL)
+	export.CompletedAt = &now
+	export.ExpiresAt = &expiresAt
+	if err != nil {
+		log.Printf("Data export %d for user %d failed: %v", export.ID, export.UserID, err)
+		export.Status = models.DataExportFailed
+		export.Error = truncate(err.Error(), 255)
+	} else {
+		export.Status = models.DataExportReady
+		export.FilePath = name
+		export.Size = size
+		log.Printf("Data export %d for user %d is ready (%d bytes, %s)", export.ID, export.UserID, size, now.Sub(started).Round(time.Millisecond))
+	}
+
+	if err := s.exportRepo.Update(ctx, export); err != nil {
+		log.Printf("Failed to save data export %d: %v", export.ID, err)
+	}
+}
+
+// writeArchive записує дані користувача в zip-архів і повертає ім'я файлу та розмір.
+// Архів спершу пишеться у тимчасовий файл, щоб незавершений архів не став доступним.
+func (s *dataExportService) writeArchive(ctx context.Context, export *models.DataExport) (string, int64, error) {
+	if err := os.MkdirAll(s.cfg.Dir, 0o700); err != nil {
+		return "", 0, err
+	}
+
+	tmp, err := os.CreateTemp(s.cfg.Dir, "export-*.tmp")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	archive := zip.NewWriter(tmp)
+	if err := s.writeEntries(ctx, archive, export.UserID); err != nil {
+		return "", 0, err
+	}
+	if err := archive.Close(); err != nil {
+		return "", 0, err
+	}
+
+	info, err := tmp.Stat()
+	if err != nil {
+		return "", 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, err
+	}
+
+	// Випадкова частина імені не дає вгадати шлях до чужого архіву
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", 0, err
+	}
+	name := fmt.Sprintf("%d-%d-%s.zip", export.UserID, export.ID, hex.EncodeToString(b))
+	if err := os.Rename(tmp.Name(), filepath.Join(s.cfg.Dir, name)); err != nil {
+		return "", 0, err
+	}
+	return name, info.Size(), nil
+}
+
+// exportedAPIKey API ключ в архіві: без хешу, з переліком областей доступу
+type exportedAPIKey struct {
+	*models.APIKey
+	Scopes []models.Scope `json:"scopes"`
+}
+
+// exportedIdentity зовнішній обліковий запис в архіві разом з ідентифікатором у постачальника
+type exportedIdentity struct {
+	*models.UserIdentity
+	Subject string `json:"subject"`
+}
+
+// writeEntries записує файли архіву: кожна категорія даних - окремий JSON файл
+func (s *dataExportService) writeEntries(ctx context.Context, archive *zip.Writer, userID uint) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	likes, err := s.likeRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	orders, err := s.orderRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	loginAttempts, err := s.loginAttemptRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	sessions, err := s.refreshRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	keys, err := s.apiKeyRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	apiKeys := make([]exportedAPIKey, 0, len(keys))
+	for _, key := range keys {
+		apiKeys = append(apiKeys, exportedAPIKey{APIKey: key, Scopes: key.ScopeList()})
+	}
+
+	linked, err := s.identityRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	identities := make([]exportedIdentity, 0, len(linked))
+	for _, identity := range linked {
+		identities = append(identities, exportedIdentity{UserIdentity: identity, Subject: identity.Subject})
+	}
+
+	cart, err := s.cartRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	cartItems := []models.CartItem{}
+	if cart != nil {
+		cartItems = cart.Items
+	}
+
+	// Збережених рекомендацій як окремих даних немає: кеш містить результати того
+	// самого обчислення, тому в архів потрапляють актуальні рекомендації
+	recommendations, err := s.recommendationService.GetRecommendations(ctx, userID, 0, models.RecommendationFilter{})
+	if err != nil {
+		return err
+	}
+
+	entries := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", user},
+		{"likes.json", likes},
+		{"orders.json", orders},
+		{"cart.json", cartItems},
+		{"events.json", map[string]interface{}{
+			"login_attempts": loginAttempts,
+			"sessions":       sessions,
+		}},
+		{"api_keys.json", apiKeys},
+		{"identities.json", identities},
+		{"recommendations.json", recommendations},
+	}
+
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if err := writeJSONEntry(archive, entry.name, entry.data); err != nil {
+			return err
+		}
+		files = append(files, entry.name)
+	}
+
+	return writeJSONEntry(archive, "export.json", map[string]interface{}{
+		"user_id":      userID,
+		"generated_at": time.Now().UTC(),
+		"files":        files,
+	})
+}
+
+func writeJSONEntry(archive *zip.Writer, name string, data interface{}) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
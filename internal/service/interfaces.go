@@ -2,21 +2,199 @@ package service
 
 import (
 	"context"
+	"os"
 	"product-recommendations-go/internal/models"
+	"time"
 )
 
 // AuthService інтерфейс для роботи з аутентифікацією
 type AuthService interface {
 	Register(ctx context.Context, user *models.User) error
-	Login(ctx context.Context, email, password string) (string, error)
+	Login(ctx context.Context, email, password string, client ClientInfo) (*LoginResult, error)
+	Refresh(ctx context.Context, refreshToken string) (*TokenPair, error)
 	Logout(ctx context.Context, token string) error
-	ParseToken(token string) (uint, error)
+	ParseToken(ctx context.Context, token string) (*TokenClaims, error)
+	Cleanup(ctx context.Context) error
+
+	// ResendVerification надсилає новий лист підтвердження адреси
+	ResendVerification(ctx context.Context, userID uint) error
+	// VerifyEmail підтверджує адресу за токеном з листа
+	VerifyEmail(ctx context.Context, token string) error
+	// ForgotPassword надсилає лист для скидання пароля, якщо адресу зареєстровано
+	ForgotPassword(ctx context.Context, email string) error
+	// ResetPassword встановлює новий пароль за токеном з листа та завершує всі сесії
+	ResetPassword(ctx context.Context, token, newPassword string) error
+
+	// VerifyTwoFactor завершує вхід кодом TOTP або резервним кодом
+	VerifyTwoFactor(ctx context.Context, challenge, code string, client ClientInfo) (*TokenPair, error)
+	// SetupTwoFactor генерує секрет TOTP; двофакторна автентифікація вмикається після EnableTwoFactor
+	SetupTwoFactor(ctx context.Context, userID uint) (*TwoFactorSetup, error)
+	// EnableTwoFactor підтверджує підключення кодом з застосунку і повертає резервні коди
+	EnableTwoFactor(ctx context.Context, userID uint, code string, client ClientInfo) ([]string, error)
+	// DisableTwoFactor вимикає двофакторну автентифікацію; потрібні пароль і код
+	DisableTwoFactor(ctx context.Context, userID uint, password, code string, client ClientInfo) error
+	// RegenerateRecoveryCodes замінює резервні коди новими
+	RegenerateRecoveryCodes(ctx context.Context, userID uint, code string, client ClientInfo) ([]string, error)
+
+	// CompleteExternalLogin завершує вхід користувача, автентифікованого зовнішнім
+	// постачальником ідентичності: відкриває сесію або, якщо увімкнена двофакторна
+	// автентифікація, повертає токен другого кроку входу
+	CompleteExternalLogin(ctx context.Context, userID uint) (*LoginResult, error)
+
+	// ChangePassword змінює пароль після перевірки поточного, завершує всі сесії
+	// і відкриває нову; twoFactor - поточну сесію відкрито з другим фактором.
+	// Пароль у цьому та інших методах керування обліковим записом перевіряється
+	// з обмеженнями входу і може повернути *LoginThrottledError.
+	ChangePassword(ctx context.Context, userID uint, currentPassword, newPassword string, twoFactor bool, client ClientInfo) (*TokenPair, error)
+	// RequestEmailChange надсилає посилання для підтвердження нової адреси
+	RequestEmailChange(ctx context.Context, userID uint, newEmail, password string, client ClientInfo) error
+	// ConfirmEmailChange замінює адресу на підтверджену за токеном з листа
+	ConfirmEmailChange(ctx context.Context, token string) error
+	// DeleteAccount видаляє обліковий запис: вподобання видаляються, замовлення
+	// залишаються знеособленими; code потрібен, якщо увімкнена двофакторна автентифікація
+	DeleteAccount(ctx context.Context, userID uint, password, code string, client ClientInfo) error
+}
+
+// UserService інтерфейс для роботи з профілем користувача
+type UserService interface {
+	GetProfile(ctx context.Context, userID uint) (*models.User, error)
+	UpdateProfile(ctx context.Context, userID uint, input ProfileInput) (*models.User, error)
+}
+
+// ProfileInput зміни профілю; nil означає, що поле не змінюється
+type ProfileInput struct {
+	Name   *string
+	Locale *string
+	// Preferences замінює налаштування повністю
+	Preferences *models.Preferences
+}
+
+// OIDCService інтерфейс для входу через зовнішніх постачальників ідентичності (OpenID Connect)
+type OIDCService interface {
+	// Providers повертає назви налаштованих постачальників
+	Providers() []string
+	// AuthURL починає вхід і повертає адресу сторінки входу постачальника та
+	// значення прив'язки входу до браузера, яке зберігається в cookie
+	AuthURL(ctx context.Context, provider string) (string, string, error)
+	// Callback завершує вхід за кодом авторизації, отриманим від постачальника;
+	// binding - значення прив'язки з cookie браузера, що повернувся від постачальника
+	Callback(ctx context.Context, provider, state, binding, code string) (*LoginResult, error)
+	// Cleanup видаляє стани незавершених входів
+	Cleanup(ctx context.Context) error
+}
+
+// LoginResult результат перевірки пароля: пара токенів або, якщо увімкнена
+// двофакторна автентифікація, токен другого кроку входу
+type LoginResult struct {
+	Tokens    *TokenPair
+	Challenge *TwoFactorChallenge
+}
+
+// TwoFactorChallenge токен другого кроку входу, що обмінюється на пару токенів разом з кодом
+type TwoFactorChallenge struct {
+	Token     string
+	ExpiresIn time.Duration
+}
+
+// TwoFactorSetup дані для підключення застосунку-автентифікатора
+type TwoFactorSetup struct {
+	// Secret секрет у Base32 для ручного введення
+	Secret string
+	// URI посилання otpauth:// (для QR-коду)
+	URI string
+}
+
+// ClientInfo дані клієнта, від якого надійшов запит
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// TokenPair токени, видані під час входу або оновлення
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	// ExpiresIn термін дії токена доступу
+	ExpiresIn time.Duration
+}
+
+// TokenClaims дані автентифікованого користувача, отримані з токена доступу
+type TokenClaims struct {
+	// ID унікальний ідентифікатор токена (claim "jti")
+	ID string
+	// SessionID ідентифікатор сесії (claim "sid"), спільний для токенів від одного входу
+	SessionID string
+	UserID    uint
+	Role      models.Role
+	// TwoFactor сесію відкрито з підтвердженням другого фактора
+	TwoFactor bool
+	ExpiresAt time.Time
+}
+
+// APIKeyService інтерфейс для роботи з API ключами
+type APIKeyService interface {
+	// Create створює ключ користувача; twoFactor - запит зроблено із сесії з другим фактором
+	Create(ctx context.Context, userID uint, input APIKeyInput, twoFactor bool) (*CreatedAPIKey, error)
+	List(ctx context.Context, userID uint) ([]*models.APIKey, error)
+	Revoke(ctx context.Context, userID, id uint) error
+	// Authenticate перевіряє ключ і повертає дані його власника
+	Authenticate(ctx context.Context, key string) (*APIKeyPrincipal, error)
+}
+
+// APIKeyInput параметри нового API ключа
+type APIKeyInput struct {
+	Name   string
+	Scopes []models.Scope
+	// ExpiresAt термін дії ключа (nil - безстроковий)
+	ExpiresAt *time.Time
+}
+
+// CreatedAPIKey створений ключ. Key повертається лише під час створення.
+type CreatedAPIKey struct {
+	Key    string
+	APIKey *models.APIKey
+}
+
+// APIKeyPrincipal власник перевіреного API ключа
+type APIKeyPrincipal struct {
+	KeyID  uint
+	UserID uint
+	// Role поточна роль власника ключа
+	Role   models.Role
+	Scopes []models.Scope
+}
+
+// DataExportService інтерфейс для вивантаження даних користувача (GDPR)
+type DataExportService interface {
+	// Request повертає поточний запит користувача (в обробці або готовий) або створює новий
+	Request(ctx context.Context, userID uint) (*models.DataExport, error)
+	// Open відкриває архів готового запиту
+	Open(export *models.DataExport) (*os.File, error)
+	// Run формує архіви з черги запитів, доки ctx не скасовано
+	Run(ctx context.Context)
+	// Cleanup видаляє прострочені архіви та запити
+	Cleanup(ctx context.Context) error
 }
 
 // ProductService інтерфейс для роботи з товарами
 type ProductService interface {
 	GetByID(ctx context.Context, id uint) (*models.Product, error)
 	GetAll(ctx context.Context, page, limit int) ([]*models.Product, int64, error)
+	Create(ctx context.Context, input models.ProductInput) (*models.Product, error)
+	Update(ctx context.Context, id uint, input models.ProductInput) (*models.Product, error)
+	Delete(ctx context.Context, id uint) error
+	Restore(ctx context.Context, id uint) (*models.Product, error)
+	AdjustStock(ctx context.Context, id uint, adjustment StockAdjustment) (*models.Product, error)
+	// GetLowStock повертає товари із залишком не більше threshold; від'ємний
+	// threshold означає поріг за замовчуванням
+	GetLowStock(ctx context.Context, threshold, limit int) ([]*models.Product, error)
+}
+
+// StockAdjustment зміна залишку товару: нове значення Stock або різниця Delta
+// (від'ємна - списання). Має бути вказане рівно одне поле.
+type StockAdjustment struct {
+	Stock *int `json:"stock"`
+	Delta *int `json:"delta"`
 }
 
 // LikeService інтерфейс для роботи з вподобаннями
@@ -31,9 +209,82 @@ type OrderService interface {
 	CreateOrder(ctx context.Context, order *models.Order) error
 	GetOrderByID(ctx context.Context, id, userID uint) (*models.Order, error)
 	GetUserOrders(ctx context.Context, userID uint) ([]*models.Order, error)
+	// CancelOrder скасовує неоплачене замовлення на прохання покупця
+	CancelOrder(ctx context.Context, id, userID uint, reason string) (*models.Order, error)
+	// UpdateStatus змінює статус будь-якого замовлення від імені адміністратора actorID
+	UpdateStatus(ctx context.Context, id uint, status models.OrderStatus, actorID uint, reason string) (*models.Order, error)
+}
+
+// CartService інтерфейс для роботи з кошиком покупок. Якщо власник - користувач,
+// який передав і токен кошика гостя, кошик гостя спершу переноситься до кошика
+// користувача (так кошик зберігається після входу).
+type CartService interface {
+	// Get повертає кошик власника; кошик, якого ще немає, повертається порожнім
+	Get(ctx context.Context, owner CartOwner) (*CartView, error)
+	// AddItem додає товар до кошика, створюючи кошик за потреби
+	AddItem(ctx context.Context, owner CartOwner, productID uint, quantity int) (*CartView, error)
+	// UpdateItem змінює кількість товару в кошику
+	UpdateItem(ctx context.Context, owner CartOwner, productID uint, quantity int) (*CartView, error)
+	RemoveItem(ctx context.Context, owner CartOwner, productID uint) (*CartView, error)
+	// RefreshPrices замінює збережені ціни поточними і видаляє товари, яких більше немає в каталозі
+	RefreshPrices(ctx context.Context, owner CartOwner) (*CartView, error)
+	// Checkout створює замовлення з кошика користувача і очищає кошик. Якщо ціни
+	// змінилися, повертає ErrCartChanged: спершу потрібно оновити ціни.
+	Checkout(ctx context.Context, owner CartOwner) (*models.Order, error)
+	// Recommendations рекомендує товари до вмісту кошика
+	Recommendations(ctx context.Context, owner CartOwner, limit int, filter models.RecommendationFilter) ([]*models.ProductRecommendation, error)
+	// Cleanup видаляє покинуті кошики гостів
+	Cleanup(ctx context.Context) error
+}
+
+// CartOwner власник кошика: автентифікований користувач або гість з токеном кошика
+type CartOwner struct {
+	// UserID ID користувача (0 - гість)
+	UserID uint
+	// GuestToken токен кошика гостя
+	GuestToken string
+}
+
+// CartView кошик з поточними цінами товарів
+type CartView struct {
+	// Token токен нового кошика гостя; повертається лише під час створення кошика
+	Token string
+	Lines []CartLine
+	// Total сума за збереженими цінами
+	Total float64
+	// Changed ціна хоча б одного товару змінилася або товар більше недоступний
+	Changed   bool
+	UpdatedAt *time.Time
+}
+
+// CartLine товар у кошику
+type CartLine struct {
+	ProductID uint
+	// Product товар каталогу (nil - товар видалено з каталогу)
+	Product  *models.Product
+	Quantity int
+	// Price ціна, збережена під час додавання до кошика
+	Price float64
+	// CurrentPrice поточна ціна в каталозі
+	CurrentPrice float64
+	AddedAt      time.Time
 }
 
 // RecommendationService інтерфейс для роботи з рекомендаціями
 type RecommendationService interface {
-	GetRecommendations(ctx context.Context, userID uint, limit int) ([]*models.ProductRecommendation, error)
+	GetRecommendations(ctx context.Context, userID uint, limit int, filter models.RecommendationFilter) ([]*models.ProductRecommendation, error)
+	// GetBatchRecommendations обчислює рекомендації для багатьох користувачів з обмеженою
+	// паралельністю і передає кожен результат у emit по мірі готовності
+	GetBatchRecommendations(ctx context.Context, userIDs []uint, limit, concurrency int, filter models.RecommendationFilter, emit func(result *models.BatchRecommendationResult) error) error
+	// GetCartRecommendations рекомендує товари, які доповнюють товари кошика productIDs
+	GetCartRecommendations(ctx context.Context, productIDs []uint, limit int, filter models.RecommendationFilter) ([]*models.ProductRecommendation, error)
+	// GetSimilarProducts повертає товари, схожі на productID
+	GetSimilarProducts(ctx context.Context, productID uint, limit int, filter models.RecommendationFilter) ([]*models.ProductRecommendation, error)
+}
+
+// RecommendationInvalidator інтерфейс для скидання збережених рекомендацій користувача
+type RecommendationInvalidator interface {
+	InvalidateUser(ctx context.Context, userID uint) error
+	// InvalidateAll скидає рекомендації всіх користувачів після зміни каталогу або залишків
+	InvalidateAll(ctx context.Context) error
 }
@@ -10,13 +10,16 @@ import (
 type likeService struct {
 	likeRepo    repository.UserLikeRepository
 	productRepo repository.ProductRepository
+	invalidator RecommendationInvalidator
 }
 
-// NewLikeService створює новий екземпляр сервісу лайків
-func NewLikeService(likeRepo repository.UserLikeRepository, productRepo repository.ProductRepository) LikeService {
+// NewLikeService створює новий екземпляр сервісу лайків.
+// invalidator може бути nil, якщо кеш рекомендацій не використовується.
+func NewLikeService(likeRepo repository.UserLikeRepository, productRepo repository.ProductRepository, invalidator RecommendationInvalidator) LikeService {
 	return &likeService{
 		likeRepo:    likeRepo,
 		productRepo: productRepo,
+		invalidator: invalidator,
 	}
 }
 
@@ -47,12 +50,23 @@ func (s *likeService) LikeProduct(ctx context.Context, userID, productID uint) e
 		ProductID: productID,
 	}
 
-	return s.likeRepo.Create(ctx, like)
+	if err := s.likeRepo.Create(ctx, like); err != nil {
+		return err
+	}
+
+	// Рекомендації мають враховувати новий лайк
+	invalidateRecommendations(ctx, s.invalidator, userID)
+	return nil
 }
 
 func (s *likeService) UnlikeProduct(ctx context.Context, userID, productID uint) error {
 	// Видаляємо лайк
-	return s.likeRepo.Delete(ctx, userID, productID)
+	if err := s.likeRepo.Delete(ctx, userID, productID); err != nil {
+		return err
+	}
+
+	invalidateRecommendations(ctx, s.invalidator, userID)
+	return nil
 }
 
 func (s *likeService) GetUserLikes(ctx context.Context, userID uint) ([]*models.UserLike, error) {
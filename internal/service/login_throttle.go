@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/repository"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// LoginThrottledError спробу входу відхилено до перевірки пароля: для облікового
+// запису або IP-адреси діє затримка між спробами чи тимчасове блокування
+type LoginThrottledError struct {
+	// RetryAfter через який час можна повторити спробу
+	RetryAfter time.Duration
+	// Locked обліковий запис або адресу тимчасово заблоковано
+	Locked bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return "too many failed login attempts, try again later"
+	}
+	return "login attempts are too frequent, try again later"
+}
+
+// LoginThrottleConfig параметри захисту від підбору пароля
+type LoginThrottleConfig struct {
+	// Window за який проміжок часу враховуються невдалі спроби
+	Window time.Duration
+	// FreeAttempts кількість невдалих спроб для облікового запису без затримки
+	FreeAttempts int
+	// BaseDelay затримка після першої спроби понад FreeAttempts; кожна наступна подвоює її
+	BaseDelay time.Duration
+	// MaxDelay верхня межа затримки між спробами
+	MaxDelay time.Duration
+	// MaxAccountFailures кількість невдалих спроб, після якої обліковий запис блокується
+	MaxAccountFailures int
+	// MaxIPFailures кількість невдалих спроб з однієї адреси, після якої адреса блокується
+	MaxIPFailures int
+	// LockoutDuration тривалість блокування
+	LockoutDuration time.Duration
+	// AuditRetention як довго зберігаються записи журналу невдалих входів
+	AuditRetention time.Duration
+}
+
+// LoginAttemptInfo дані невдалої спроби входу для журналу аудиту
+type LoginAttemptInfo struct {
+	Email     string
+	UserID    *uint
+	Client    ClientInfo
+	Reason    models.LoginFailureReason
+	Timestamp time.Time
+}
+
+// LoginReservation спроба входу, врахована лічильниками до перевірки пароля або коду
+type LoginReservation struct {
+	at       time.Time
+	keys     []string
+	previous []time.Time
+}
+
+// LoginThrottle захист від підбору пароля: рахує невдалі спроби окремо для
+// облікового запису та IP-адреси, вимагає зростаючої затримки між спробами,
+// тимчасово блокує вхід і веде журнал невдалих входів
+type LoginThrottle interface {
+	// Reserve атомарно враховує спробу входу як невдалу ще до перевірки пароля,
+	// щоб паралельні спроби не могли пройти перевірку з тим самим значенням
+	// лічильника. Повертає *LoginThrottledError, якщо спробу слід відхилити;
+	// відхилена спроба лічильниками не враховується.
+	Reserve(ctx context.Context, email string, client ClientInfo) (*LoginReservation, error)
+	// Release скасовує спробу, що не виявилася невдалою (правильний пароль або код)
+	Release(ctx context.Context, reservation *LoginReservation) error
+	// RecordFailure додає невдалу спробу до журналу аудиту
+	RecordFailure(ctx context.Context, attempt LoginAttemptInfo) error
+	// RecordSuccess скидає лічильник облікового запису після успішного входу
+	RecordSuccess(ctx context.Context, email string) error
+	// Cleanup видаляє застарілі лічильники та записи журналу
+	Cleanup(ctx context.Context) error
+}
+
+type loginThrottle struct {
+	store repository.LoginFailureStore
+	audit repository.LoginAttemptRepository
+	cfg   LoginThrottleConfig
+}
+
+// NewLoginThrottle створює захист від підбору пароля з лічильниками у store.
+// Щоб обмеження діяли спільно для всіх екземплярів API, store має зберігати
+// дані в базі даних.
+func NewLoginThrottle(store repository.LoginFailureStore, audit repository.LoginAttemptRepository, cfg LoginThrottleConfig) LoginThrottle {
+	return &loginThrottle{
+		store: store,
+		audit: audit,
+		cfg:   cfg,
+	}
+}
+
+func (t *loginThrottle) Reserve(ctx context.Context, email string, client ClientInfo) (*LoginReservation, error) {
+	now := time.Now()
+	since := now.Add(-t.cfg.Window)
+	reservation := &LoginReservation{at: now}
+
+	// reject скасовує вже враховані ключі: відхилені без перевірки пароля спроби
+	// не рахуються, інакше зловмисник, продовжуючи спроби, продовжував би
+	// блокування нескінченно
+	reject := func(err error) (*LoginReservation, error) {
+		if releaseErr := t.Release(ctx, reservation); releaseErr != nil {
+			log.Printf("Failed to release login attempt: %v", releaseErr)
+		}
+		return nil, err
+	}
+
+	// Адреса блокується лише після значної кількості спроб: за однією адресою
+	// (NAT, корпоративний проксі) можуть бути багато користувачів
+	if client.IP != "" {
+		failures, last, err := t.reserve(ctx, reservation, ipKey(client.IP), since)
+		if err != nil {
+			return reject(err)
+		}
+		if failures >= t.cfg.MaxIPFailures {
+			if wait := last.Add(t.cfg.LockoutDuration).Sub(now); wait > 0 {
+				return reject(&LoginThrottledError{RetryAfter: wait, Locked: true})
+			}
+		}
+	}
+
+	failures, last, err := t.reserve(ctx, reservation, accountKey(email), since)
+	if err != nil {
+		return reject(err)
+	}
+	if failures >= t.cfg.MaxAccountFailures {
+		if wait := last.Add(t.cfg.LockoutDuration).Sub(now); wait > 0 {
+			return reject(&LoginThrottledError{RetryAfter: wait, Locked: true})
+		}
+		return reservation, nil
+	}
+	if wait := last.Add(t.delay(failures)).Sub(now); wait > 0 {
+		return reject(&LoginThrottledError{RetryAfter: wait})
+	}
+	return reservation, nil
+}
+
+// reserve враховує спробу для ключа і запам'ятовує його в reservation
+func (t *loginThrottle) reserve(ctx context.Context, reservation *LoginReservation, key string, since time.Time) (int, time.Time, error) {
+	failures, last, err := t.store.Reserve(ctx, key, reservation.at, since)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("check login attempts: %w", err)
+	}
+	reservation.keys = append(reservation.keys, key)
+	reservation.previous = append(reservation.previous, last)
+	return failures, last, nil
+}
+
+func (t *loginThrottle) Release(ctx context.Context, reservation *LoginReservation) error {
+	for i, key := range reservation.keys {
+		if err := t.store.Release(ctx, key, reservation.at, reservation.previous[i]); err != nil {
+			return err
+		}
+	}
+	reservation.keys = nil
+	return nil
+}
+
+func (t *loginThrottle) RecordFailure(ctx context.Context, attempt LoginAttemptInfo) error {
+	// Лічильники вже враховали спробу в Reserve
+	return t.audit.Create(ctx, &models.LoginAttempt{
+		Email:     truncate(normalizeEmail(attempt.Email), 255),
+		UserID:    attempt.UserID,
+		IP:        truncate(attempt.Client.IP, 64),
+		UserAgent: truncate(attempt.Client.UserAgent, 255),
+		Reason:    attempt.Reason,
+		CreatedAt: attempt.Timestamp,
+	})
+}
+
+func (t *loginThrottle) RecordSuccess(ctx context.Context, email string) error {
+	// Лічильник адреси не скидається: успішний вхід в один обліковий запис
+	// не повинен відкривати підбір паролів до інших
+	return t.store.Reset(ctx, accountKey(email))
+}
+
+func (t *loginThrottle) Cleanup(ctx context.Context) error {
+	now := time.Now()
+
+	// Лічильники потрібні лише в межах вікна та блокування
+	keep := max(t.cfg.Window, t.cfg.LockoutDuration)
+	if _, err := t.store.DeleteBefore(ctx, now.Add(-keep)); err != nil {
+		return err
+	}
+
+	deleted, err := t.audit.DeleteBefore(ctx, now.Add(-t.cfg.AuditRetention))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Printf("Removed %d login audit records", deleted)
+	}
+	return nil
+}
+
+// delay повертає обов'язкову затримку після failures невдалих спроб
+func (t *loginThrottle) delay(failures int) time.Duration {
+	if failures < t.cfg.FreeAttempts {
+		return 0
+	}
+
+	delay := t.cfg.BaseDelay
+	for i := t.cfg.FreeAttempts; i < failures && delay < t.cfg.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, t.cfg.MaxDelay)
+}
+
+// accountKey ключ лічильника облікового запису. Лічильник ведеться і для
+// незареєстрованих адрес, щоб відповідь не розкривала, чи існує обліковий запис.
+func accountKey(email string) string {
+	return "email:" + truncate(normalizeEmail(email), 255)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + truncate(ip, 64)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// truncate обрізає рядок до limit байт, не розриваючи символи UTF-8
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit]
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/repository"
+)
+
+type discardLoginAttempts struct{}
+
+func (discardLoginAttempts) Create(context.Context, *models.LoginAttempt) error { return nil }
+
+func (discardLoginAttempts) GetByUserID(context.Context, uint) ([]*models.LoginAttempt, error) {
+	return nil, nil
+}
+
+func (discardLoginAttempts) DeleteBefore(context.Context, time.Time) (int64, error) { return 0, nil }
+
+func testLoginThrottle() LoginThrottle {
+	return NewLoginThrottle(repository.NewMemoryLoginFailureStore(), discardLoginAttempts{}, LoginThrottleConfig{
+		Window:             time.Hour,
+		FreeAttempts:       3,
+		BaseDelay:          time.Minute,
+		MaxDelay:           time.Hour,
+		MaxAccountFailures: 10,
+		MaxIPFailures:      100,
+		LockoutDuration:    time.Hour,
+	})
+}
+
+func TestLoginThrottleReservesConcurrentAttempts(t *testing.T) {
+	throttle := testLoginThrottle()
+	ctx := context.Background()
+	client := ClientInfo{IP: "203.0.113.7"}
+
+	const attempts = 50
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		allowed   int
+		throttled int
+	)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := throttle.Reserve(ctx, "user@example.com", client)
+			var throttledErr *LoginThrottledError
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				allowed++
+			case errors.As(err, &throttledErr):
+				throttled++
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Паралельні спроби не можуть пройти перевірку з тим самим значенням лічильника
+	if allowed != 3 || throttled != attempts-3 {
+		t.Errorf("allowed %d and throttled %d attempts, want 3 and %d", allowed, throttled, attempts-3)
+	}
+}
+
+func TestLoginThrottleRelease(t *testing.T) {
+	throttle := testLoginThrottle()
+	ctx := context.Background()
+
+	// Скасовані спроби (правильний пароль) не наближають затримку
+	for i := 0; i < 10; i++ {
+		reservation, err := throttle.Reserve(ctx, "user@example.com", ClientInfo{})
+		if err != nil {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+		if err := throttle.Release(ctx, reservation); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Невдалі спроби враховуються, а відхилені - ні
+	for i := 0; i < 3; i++ {
+		if _, err := throttle.Reserve(ctx, "user@example.com", ClientInfo{}); err != nil {
+			t.Fatalf("failed attempt %d: %v", i+1, err)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		_, err := throttle.Reserve(ctx, "user@example.com", ClientInfo{})
+		var throttled *LoginThrottledError
+		if !errors.As(err, &throttled) {
+			t.Fatalf("attempt after failures: got %v, want throttling", err)
+		}
+		if throttled.Locked || throttled.RetryAfter <= 0 || throttled.RetryAfter > time.Minute {
+			t.Errorf("got %+v, want a delay of at most the base delay", throttled)
+		}
+	}
+
+	// Успішний вхід скидає лічильник облікового запису
+	if err := throttle.RecordSuccess(ctx, "user@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := throttle.Reserve(ctx, "user@example.com", ClientInfo{}); err != nil {
+		t.Errorf("attempt after success: %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/oidc"
+	"product-recommendations-go/internal/repository"
+	"sort"
+	"time"
+)
+
+// Помилки входу через зовнішнього постачальника ідентичності
+var (
+	// ErrUnknownProvider постачальника з такою назвою не налаштовано
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	// ErrInvalidOIDCState параметр state не відповідає жодному незавершеному входу
+	ErrInvalidOIDCState = errors.New("invalid or expired login state")
+	// ErrExternalEmailNotVerified постачальник не підтвердив адресу користувача
+	ErrExternalEmailNotVerified = errors.New("identity provider did not return a verified email")
+	// ErrAccountLinkNotAllowed обліковий запис з такою адресою існує, але адресу в нас не підтверджено
+	ErrAccountLinkNotAllowed = errors.New("an account with this email exists but its email is not verified")
+	// ErrExternalLoginFailed постачальник відхилив вхід або повернув недійсні дані
+	ErrExternalLoginFailed = errors.New("external login failed")
+)
+
+type oidcService struct {
+	providers    map[string]*oidc.Provider
+	authService  AuthService
+	userRepo     repository.UserRepository
+	identityRepo repository.UserIdentityRepository
+	stateRepo    repository.OIDCStateRepository
+	stateTTL     time.Duration
+}
+
+// NewOIDCService створює новий екземпляр сервісу входу через постачальників ідентичності.
+// stateTTL - час, за який користувач має завершити вхід на сторінці постачальника.
+func NewOIDCService(providers []*oidc.Provider, authService AuthService, userRepo repository.UserRepository, identityRepo repository.UserIdentityRepository, stateRepo repository.OIDCStateRepository, stateTTL time.Duration) OIDCService {
+	byName := make(map[string]*oidc.Provider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+
+	return &oidcService{
+		providers:    byName,
+		authService:  authService,
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		stateRepo:    stateRepo,
+		stateTTL:     stateTTL,
+	}
+}
+
+func (s *oidcService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AuthURL повертає як прив'язку хеш state: браузер, що почав вхід, зберігає його
+// в cookie, і Callback приймає state лише разом з ним. Без прив'язки зловмисник
+// міг би підкинути користувачу посилання на зворотний виклик зі своїм state і
+// кодом і непомітно ввійти користувача у свій обліковий запис.
+func (s *oidcService) AuthURL(ctx context.Context, providerName string) (string, string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	state, err := oidc.RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		return "", "", err
+	}
+
+	// Адреса формується до збереження стану, щоб недоступний постачальник не залишав записів
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, oidc.Challenge(verifier))
+	if err != nil {
+		return "", "", err
+	}
+
+	err = s.stateRepo.Create(ctx, &models.OIDCLoginState{
+		StateHash:    hashToken(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(s.stateTTL),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, hashToken(state), nil
+}
+
+func (s *oidcService) Callback(ctx context.Context, providerName, state, binding, code string) (*LoginResult, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	if state == "" {
+		return nil, ErrInvalidOIDCState
+	}
+	// Вхід завершується лише в браузері, який його почав
+	if subtle.ConstantTimeCompare([]byte(binding), []byte(hashToken(state))) != 1 {
+		return nil, ErrInvalidOIDCState
+	}
+
+	// Стан використовується один раз навіть у разі помилки: повторити вхід можна лише з початку
+	stored, err := s.stateRepo.Consume(ctx, hashToken(state))
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || stored.Provider != providerName || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidOIDCState
+	}
+	if code == "" {
+		return nil, ErrExternalLoginFailed
+	}
+
+	claims, err := provider.Exchange(ctx, code, stored.CodeVerifier)
+	if err != nil {
+		log.Printf("OIDC login via %s failed: %v", providerName, err)
+		return nil, ErrExternalLoginFailed
+	}
+	// nonce прив'язує ID токен до цього входу і не дає підставити токен з іншого
+	if claims.Nonce != stored.Nonce {
+		log.Printf("OIDC login via %s failed: nonce mismatch", providerName)
+		return nil, ErrExternalLoginFailed
+	}
+
+	user, err := s.resolveUser(ctx, providerName, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.authService.CompleteExternalLogin(ctx, user.ID)
+}
+
+func (s *oidcService) Cleanup(ctx context.Context) error {
+	deleted, err := s.stateRepo.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Printf("Removed %d expired OIDC login states", deleted)
+	}
+	return nil
+}
+
+// resolveUser знаходить користувача для зовнішнього облікового запису. Уже прив'язаний
+// обліковий запис визначає користувача незалежно від адреси; новий прив'язується за
+// підтвердженою адресою до наявного користувача або до створеного покупця.
+func (s *oidcService) resolveUser(ctx context.Context, providerName string, claims *oidc.Claims) (*models.User, error) {
+	identity, err := s.identityRepo.Get(ctx, providerName, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		user, err := s.userRepo.GetByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, ErrExternalLoginFailed
+		}
+
+		if claims.Email != "" && claims.Email != identity.Email {
+			identity.Email = claims.Email
+			if err := s.identityRepo.Update(ctx, identity); err != nil {
+				log.Printf("Failed to update identity %d email: %v", identity.ID, err)
+			}
+		}
+		return user, nil
+	}
+
+	// Прив'язка за адресою безпечна лише тоді, коли постачальник підтвердив, що
+	// адреса належить користувачу
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrExternalEmailNotVerified
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, claims.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		if user, err = s.createUser(ctx, claims.Email); err != nil {
+			return nil, err
+		}
+	} else if user.EmailVerifiedAt == nil {
+		// Непідтверджену адресу міг зареєструвати хтось інший заздалегідь; прив'язка
+		// дала б йому доступ до облікового запису власника адреси
+		return nil, ErrAccountLinkNotAllowed
+	}
+
+	err = s.identityRepo.Create(ctx, &models.UserIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Linked %s identity to user %d", providerName, user.ID)
+	return user, nil
+}
+
+// createUser створює покупця з підтвердженою адресою. Пароль - випадковий і нікому
+// не відомий: увійти з паролем можна буде після його скидання.
+func (s *oidcService) createUser(ctx context.Context, email string) (*models.User, error) {
+	password, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	hashed, err := hashPassword(password)
+	if err != nil {
+		return nil, fmt.Errorf("generate password: %w", err)
+	}
+
+	now := time.Now()
+	user := &models.User{
+		Email:           email,
+		Password:        hashed,
+		Role:            models.RoleCustomer,
+		EmailVerifiedAt: &now,
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
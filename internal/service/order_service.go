@@ -3,20 +3,41 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"product-recommendations-go/internal/models"
 	"product-recommendations-go/internal/repository"
+	"strings"
 )
 
+var (
+	// ErrOrderNotFound замовлення не знайдено
+	ErrOrderNotFound = errors.New("order not found")
+	// ErrOrderAccessDenied замовлення належить іншому користувачу
+	ErrOrderAccessDenied = errors.New("unauthorized access to order")
+	// ErrInvalidOrderStatus невідомий статус замовлення
+	ErrInvalidOrderStatus = errors.New("unknown order status")
+	// ErrInvalidStatusTransition перехід з поточного статусу замовлення не дозволений
+	ErrInvalidStatusTransition = errors.New("order status transition is not allowed")
+)
+
+// maxStatusReasonLength максимальна довжина причини зміни статусу
+const maxStatusReasonLength = 500
+
 type orderService struct {
+	transactor  repository.Transactor
 	orderRepo   repository.OrderRepository
 	productRepo repository.ProductRepository
+	invalidator RecommendationInvalidator
 }
 
-// NewOrderService створює новий екземпляр сервісу замовлень
-func NewOrderService(orderRepo repository.OrderRepository, productRepo repository.ProductRepository) OrderService {
+// NewOrderService створює новий екземпляр сервісу замовлень.
+// invalidator може бути nil, якщо кеш рекомендацій не використовується.
+func NewOrderService(transactor repository.Transactor, orderRepo repository.OrderRepository, productRepo repository.ProductRepository, invalidator RecommendationInvalidator) OrderService {
 	return &orderService{
+		transactor:  transactor,
 		orderRepo:   orderRepo,
 		productRepo: productRepo,
+		invalidator: invalidator,
 	}
 }
 
@@ -26,32 +47,85 @@ func (s *orderService) CreateOrder(ctx context.Context, order *models.Order) err
 		return errors.New("order must have at least one item")
 	}
 
-	// Обчислюємо суму замовлення та перевіряємо товари
-	var total float64
-	for i, item := range order.Items {
-		// Отримуємо товар для перевірки його наявності та поточної ціни
-		product, err := s.productRepo.GetByID(ctx, item.ProductID)
+	quantities := make(map[uint]int, len(order.Items))
+	for _, item := range order.Items {
+		if item.Quantity <= 0 {
+			return errors.New("item quantity must be positive")
+		}
+		quantities[item.ProductID] += item.Quantity
+	}
+
+	// Ціни, залишки та замовлення фіксуються в одній транзакції: товари
+	// блокуються до її завершення, тому ціна й залишок не зміняться між
+	// розрахунком суми та збереженням замовлення
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		productIDs := make([]uint, 0, len(quantities))
+		for id := range quantities {
+			productIDs = append(productIDs, id)
+		}
+		products, err := s.productRepo.GetForUpdate(ctx, productIDs)
 		if err != nil {
 			return err
 		}
+		byID := make(map[uint]*models.Product, len(products))
+		for _, product := range products {
+			byID[product.ID] = product
+		}
 
-		if product == nil {
-			return errors.New("product not found")
+		// Обчислюємо суму замовлення та перевіряємо товари
+		var total float64
+		for i, item := range order.Items {
+			product := byID[item.ProductID]
+			if product == nil {
+				return errors.New("product not found")
+			}
+
+			// Встановлюємо поточну ціну
+			order.Items[i].Price = product.Price
+
+			// Обчислюємо суму для товару
+			itemTotal := product.Price * float64(item.Quantity)
+			total += itemTotal
 		}
 
-		// Встановлюємо поточну ціну
-		order.Items[i].Price = product.Price
+		// Встановлюємо загальну суму замовлення; нове замовлення очікує оплати
+		order.Total = total
+		order.Status = models.OrderStatusPending
 
-		// Обчислюємо суму для товару
-		itemTotal := product.Price * float64(item.Quantity)
-		total += itemTotal
-	}
+		// Списуємо товари зі складу, щоб не продати більше, ніж є
+		shortID, err := s.productRepo.ReserveStock(ctx, quantities)
+		if err != nil {
+			return err
+		}
+		if shortID != 0 {
+			return fmt.Errorf("%w: product %d", ErrInsufficientStock, shortID)
+		}
+		// Залишки товарів заблоковані, тож видно, чи замовлення розпродало товар
+		soldOut := false
+		for _, product := range products {
+			if product.Stock == quantities[product.ID] {
+				soldOut = true
+			}
+		}
 
-	// Встановлюємо загальну суму замовлення
-	order.Total = total
+		// Створюємо замовлення
+		if err := s.orderRepo.Create(ctx, order); err != nil {
+			return err
+		}
 
-	// Створюємо замовлення
-	return s.orderRepo.Create(ctx, order)
+		// Куплені товари більше не мають рекомендуватися покупцю; рекомендації інших
+		// користувачів змінюються, лише якщо товар закінчився. Кеш скидається після
+		// фіксації зовнішньої транзакції (наприклад, оформлення кошика): інакше
+		// паралельний запит міг би закешувати рекомендації за старими даними.
+		userID := order.UserID
+		s.transactor.AfterCommit(ctx, func(ctx context.Context) {
+			invalidateRecommendations(ctx, s.invalidator, userID)
+			if soldOut {
+				invalidateCatalog(ctx, s.invalidator)
+			}
+		})
+		return nil
+	})
 }
 
 func (s *orderService) GetOrderByID(ctx context.Context, id, userID uint) (*models.Order, error) {
@@ -62,12 +136,12 @@ func (s *orderService) GetOrderByID(ctx context.Context, id, userID uint) (*mode
 	}
 
 	if order == nil {
-		return nil, errors.New("order not found")
+		return nil, ErrOrderNotFound
 	}
 
 	// Перевіряємо, чи замовлення належить користувачу
 	if order.UserID != userID {
-		return nil, errors.New("unauthorized access to order")
+		return nil, ErrOrderAccessDenied
 	}
 
 	return order, nil
@@ -76,3 +150,124 @@ func (s *orderService) GetOrderByID(ctx context.Context, id, userID uint) (*mode
 func (s *orderService) GetUserOrders(ctx context.Context, userID uint) ([]*models.Order, error) {
 	return s.orderRepo.GetByUserID(ctx, userID)
 }
+
+func (s *orderService) CancelOrder(ctx context.Context, id, userID uint, reason string) (*models.Order, error) {
+	order, err := s.GetOrderByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Покупець може скасувати лише неоплачене замовлення; повернення коштів оформлює адміністратор
+	if order.Status != models.OrderStatusPending {
+		return nil, ErrInvalidStatusTransition
+	}
+
+	return s.transition(ctx, order, models.OrderStatusCancelled, userID, reason)
+}
+
+func (s *orderService) UpdateStatus(ctx context.Context, id uint, status models.OrderStatus, actorID uint, reason string) (*models.Order, error) {
+	if !status.Valid() {
+		return nil, ErrInvalidOrderStatus
+	}
+
+	order, err := s.orderRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, ErrOrderNotFound
+	}
+
+	return s.transition(ctx, order, status, actorID, reason)
+}
+
+// transition переводить замовлення до статусу to і записує зміну в історію
+func (s *orderService) transition(ctx context.Context, order *models.Order, to models.OrderStatus, actorID uint, reason string) (*models.Order, error) {
+	if !order.Status.CanTransitionTo(to) {
+		return nil, ErrInvalidStatusTransition
+	}
+
+	// Зміна статусу та повернення товарів на склад фіксуються разом
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		updated, err := s.orderRepo.UpdateStatus(ctx, &models.OrderStatusChange{
+			OrderID:    order.ID,
+			FromStatus: order.Status,
+			ToStatus:   to,
+			ChangedBy:  &actorID,
+			Reason:     truncate(strings.TrimSpace(reason), maxStatusReasonLength),
+		})
+		if err != nil {
+			return err
+		}
+		// Статус змінив паралельний запит
+		if !updated {
+			return ErrInvalidStatusTransition
+		}
+
+		// Товари скасованого або поверненого до відправлення замовлення повертаються на склад
+		restocked := false
+		if releasesStock(order.Status, to) {
+			quantities := orderQuantities(order)
+			restocked, err = s.restocks(ctx, quantities)
+			if err != nil {
+				return err
+			}
+			if err := s.productRepo.ReleaseStock(ctx, quantities); err != nil {
+				return err
+			}
+		}
+
+		// Скасовані та повернуті замовлення більше не вважаються покупками
+		userID := order.UserID
+		s.transactor.AfterCommit(ctx, func(ctx context.Context) {
+			if !to.IsPurchase() {
+				invalidateRecommendations(ctx, s.invalidator, userID)
+			}
+			if restocked {
+				invalidateCatalog(ctx, s.invalidator)
+			}
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.orderRepo.GetByID(ctx, order.ID)
+}
+
+// restocks блокує товари quantities до кінця транзакції і визначає, чи поверне
+// їх на склад хоча б один товар, якого зараз немає в наявності
+func (s *orderService) restocks(ctx context.Context, quantities map[uint]int) (bool, error) {
+	productIDs := make([]uint, 0, len(quantities))
+	for id := range quantities {
+		productIDs = append(productIDs, id)
+	}
+	products, err := s.productRepo.GetForUpdate(ctx, productIDs)
+	if err != nil {
+		return false, err
+	}
+	for _, product := range products {
+		if product.Stock <= 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// releasesStock визначає, чи повертаються товари на склад при переході from -> to.
+// Після відправлення товари вже в покупця, тому повернення коштів за доставлене
+// замовлення залишок не змінює.
+func releasesStock(from, to models.OrderStatus) bool {
+	return to == models.OrderStatusCancelled ||
+		(to == models.OrderStatusRefunded && from == models.OrderStatusPaid)
+}
+
+// orderQuantities підсумовує кількості товарів замовлення за ID товару
+func orderQuantities(order *models.Order) map[uint]int {
+	quantities := make(map[uint]int, len(order.Items))
+	for _, item := range order.Items {
+		quantities[item.ProductID] += item.Quantity
+	}
+	return quantities
+}
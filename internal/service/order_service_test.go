@@ -0,0 +1,380 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/repository"
+)
+
+// fakeTransactor виконує fn без справжньої транзакції: дії AfterCommit
+// відкладаються до успішного завершення зовнішнього виклику
+type fakeTransactor struct {
+	depth int
+	hooks []func(ctx context.Context)
+}
+
+func (t *fakeTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	t.depth++
+	err := fn(ctx)
+	t.depth--
+	if t.depth > 0 {
+		return err
+	}
+
+	hooks := t.hooks
+	t.hooks = nil
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		hook(ctx)
+	}
+	return nil
+}
+
+func (t *fakeTransactor) AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	if t.depth == 0 {
+		fn(ctx)
+		return
+	}
+	t.hooks = append(t.hooks, fn)
+}
+
+// memoryProducts товари в пам'яті; решта методів ProductRepository у тестах не викликається
+type memoryProducts struct {
+	repository.ProductRepository
+	products map[uint]*models.Product
+}
+
+func newMemoryProducts(stock map[uint]int) *memoryProducts {
+	r := &memoryProducts{products: make(map[uint]*models.Product)}
+	for id, s := range stock {
+		r.products[id] = &models.Product{ID: id, Name: "product", Price: 10, Stock: s}
+	}
+	return r
+}
+
+func (r *memoryProducts) GetForUpdate(_ context.Context, ids []uint) ([]*models.Product, error) {
+	var products []*models.Product
+	for _, id := range ids {
+		if product, ok := r.products[id]; ok {
+			copied := *product
+			products = append(products, &copied)
+		}
+	}
+	return products, nil
+}
+
+// ReserveStock списує все або нічого, як транзакція справжнього репозиторію
+func (r *memoryProducts) ReserveStock(_ context.Context, quantities map[uint]int) (uint, error) {
+	for id, quantity := range quantities {
+		if product, ok := r.products[id]; !ok || product.Stock < quantity {
+			return id, nil
+		}
+	}
+	for id, quantity := range quantities {
+		r.products[id].Stock -= quantity
+	}
+	return 0, nil
+}
+
+func (r *memoryProducts) ReleaseStock(_ context.Context, quantities map[uint]int) error {
+	for id, quantity := range quantities {
+		if product, ok := r.products[id]; ok {
+			product.Stock += quantity
+		}
+	}
+	return nil
+}
+
+func (r *memoryProducts) stock() map[uint]int {
+	stock := make(map[uint]int, len(r.products))
+	for id, product := range r.products {
+		stock[id] = product.Stock
+	}
+	return stock
+}
+
+// memoryOrders замовлення в пам'яті; решта методів OrderRepository у тестах не викликається
+type memoryOrders struct {
+	repository.OrderRepository
+	orders map[uint]*models.Order
+}
+
+func (r *memoryOrders) Create(_ context.Context, order *models.Order) error {
+	if r.orders == nil {
+		r.orders = make(map[uint]*models.Order)
+	}
+	order.ID = uint(len(r.orders) + 1)
+	copied := *order
+	r.orders[order.ID] = &copied
+	return nil
+}
+
+func (r *memoryOrders) GetByID(_ context.Context, id uint) (*models.Order, error) {
+	order, ok := r.orders[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *order
+	return &copied, nil
+}
+
+func (r *memoryOrders) UpdateStatus(_ context.Context, change *models.OrderStatusChange) (bool, error) {
+	order, ok := r.orders[change.OrderID]
+	if !ok || order.Status != change.FromStatus {
+		return false, nil
+	}
+	order.Status = change.ToStatus
+	return true, nil
+}
+
+// recordingInvalidator запам'ятовує скидання кешу рекомендацій
+type recordingInvalidator struct {
+	users []uint
+	all   int
+}
+
+func (i *recordingInvalidator) InvalidateUser(_ context.Context, userID uint) error {
+	i.users = append(i.users, userID)
+	return nil
+}
+
+func (i *recordingInvalidator) InvalidateAll(context.Context) error {
+	i.all++
+	return nil
+}
+
+func newTestOrder(userID uint, quantities map[uint]int) *models.Order {
+	order := &models.Order{UserID: userID}
+	for id, quantity := range quantities {
+		order.Items = append(order.Items, models.OrderItem{ProductID: id, Quantity: quantity})
+	}
+	return order
+}
+
+func TestOrderCacheInvalidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		stock     map[uint]int
+		order     map[uint]int
+		cancel    bool
+		wantUsers []uint
+		wantAll   int
+	}{
+		{
+			name:      "order leaves stock: only the buyer",
+			stock:     map[uint]int{1: 5, 2: 5},
+			order:     map[uint]int{1: 2, 2: 1},
+			wantUsers: []uint{7},
+		},
+		{
+			name:      "order sells out a product: everyone",
+			stock:     map[uint]int{1: 5, 2: 1},
+			order:     map[uint]int{1: 2, 2: 1},
+			wantUsers: []uint{7},
+			wantAll:   1,
+		},
+		{
+			name:      "cancellation keeps the product in stock: only the buyer",
+			stock:     map[uint]int{1: 5},
+			order:     map[uint]int{1: 2},
+			cancel:    true,
+			wantUsers: []uint{7, 7},
+		},
+		{
+			name:      "cancellation restocks a sold out product: everyone",
+			stock:     map[uint]int{1: 2},
+			order:     map[uint]int{1: 2},
+			cancel:    true,
+			wantUsers: []uint{7, 7},
+			wantAll:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invalidator := &recordingInvalidator{}
+			s := NewOrderService(&fakeTransactor{}, &memoryOrders{}, newMemoryProducts(tt.stock), invalidator)
+
+			order := newTestOrder(7, tt.order)
+			if err := s.CreateOrder(context.Background(), order); err != nil {
+				t.Fatal(err)
+			}
+			if tt.cancel {
+				if _, err := s.CancelOrder(context.Background(), order.ID, 7, ""); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if !reflect.DeepEqual(invalidator.users, tt.wantUsers) || invalidator.all != tt.wantAll {
+				t.Errorf("invalidated users %v and all %d times, want %v and %d",
+					invalidator.users, invalidator.all, tt.wantUsers, tt.wantAll)
+			}
+		})
+	}
+}
+
+func TestOrderStatusTransitions(t *testing.T) {
+	// paths шлях від створення замовлення до кожного статусу
+	paths := map[models.OrderStatus][]models.OrderStatus{
+		models.OrderStatusPending:   nil,
+		models.OrderStatusPaid:      {models.OrderStatusPaid},
+		models.OrderStatusShipped:   {models.OrderStatusPaid, models.OrderStatusShipped},
+		models.OrderStatusDelivered: {models.OrderStatusPaid, models.OrderStatusShipped, models.OrderStatusDelivered},
+		models.OrderStatusCancelled: {models.OrderStatusCancelled},
+		models.OrderStatusRefunded:  {models.OrderStatusPaid, models.OrderStatusRefunded},
+	}
+	statuses := []models.OrderStatus{
+		models.OrderStatusPending, models.OrderStatusPaid, models.OrderStatusShipped,
+		models.OrderStatusDelivered, models.OrderStatusCancelled, models.OrderStatusRefunded,
+	}
+
+	type transition struct{ from, to models.OrderStatus }
+	// allowed дозволені переходи та залишок товару після них (замовлено 2 з 5)
+	allowed := map[transition]int{
+		{models.OrderStatusPending, models.OrderStatusPaid}:       3,
+		{models.OrderStatusPending, models.OrderStatusCancelled}:  5,
+		{models.OrderStatusPaid, models.OrderStatusShipped}:       3,
+		{models.OrderStatusPaid, models.OrderStatusRefunded}:      5,
+		{models.OrderStatusShipped, models.OrderStatusDelivered}:  3,
+		{models.OrderStatusDelivered, models.OrderStatusRefunded}: 3,
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			wantStock, ok := allowed[transition{from, to}]
+			t.Run(string(from)+" to "+string(to), func(t *testing.T) {
+				ctx := context.Background()
+				products := newMemoryProducts(map[uint]int{1: 5})
+				s := NewOrderService(&fakeTransactor{}, &memoryOrders{}, products, nil)
+
+				order := newTestOrder(7, map[uint]int{1: 2})
+				if err := s.CreateOrder(ctx, order); err != nil {
+					t.Fatal(err)
+				}
+				for _, status := range paths[from] {
+					if _, err := s.UpdateStatus(ctx, order.ID, status, 1, ""); err != nil {
+						t.Fatalf("reach %s: %v", from, err)
+					}
+				}
+				stock := products.stock()[1]
+
+				updated, err := s.UpdateStatus(ctx, order.ID, to, 1, "")
+				if !ok {
+					if !errors.Is(err, ErrInvalidStatusTransition) {
+						t.Fatalf("got %v, want ErrInvalidStatusTransition", err)
+					}
+					if products.stock()[1] != stock {
+						t.Errorf("forbidden transition changed stock from %d to %d", stock, products.stock()[1])
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if updated.Status != to || products.stock()[1] != wantStock {
+					t.Errorf("got status %s and stock %d, want %s and %d", updated.Status, products.stock()[1], to, wantStock)
+				}
+			})
+		}
+	}
+}
+
+func TestOrderStatusRejectsUnknownStatus(t *testing.T) {
+	ctx := context.Background()
+	s := NewOrderService(&fakeTransactor{}, &memoryOrders{}, newMemoryProducts(map[uint]int{1: 5}), nil)
+	order := newTestOrder(7, map[uint]int{1: 2})
+	if err := s.CreateOrder(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.UpdateStatus(ctx, order.ID, "lost", 1, ""); !errors.Is(err, ErrInvalidOrderStatus) {
+		t.Errorf("got %v, want ErrInvalidOrderStatus", err)
+	}
+	if _, err := s.UpdateStatus(ctx, 99, models.OrderStatusPaid, 1, ""); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("got %v, want ErrOrderNotFound", err)
+	}
+}
+
+// racingOrders змінює статус замовлення паралельним запитом перед записом переходу
+type racingOrders struct {
+	*memoryOrders
+	status models.OrderStatus
+}
+
+func (r *racingOrders) UpdateStatus(ctx context.Context, change *models.OrderStatusChange) (bool, error) {
+	r.orders[change.OrderID].Status = r.status
+	return r.memoryOrders.UpdateStatus(ctx, change)
+}
+
+func TestCancelOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		// paid замовлення оплачене до скасування
+		paid bool
+		// racing статус, встановлений паралельним запитом (порожній - без гонки)
+		racing    models.OrderStatus
+		userID    uint
+		wantErr   error
+		wantStock int
+	}{
+		{name: "buyer cancels a pending order", userID: 7, wantStock: 5},
+		{name: "buyer cancels a paid order", paid: true, userID: 7, wantErr: ErrInvalidStatusTransition, wantStock: 3},
+		{name: "another user", userID: 8, wantErr: ErrOrderAccessDenied, wantStock: 3},
+		{name: "paid concurrently", racing: models.OrderStatusPaid, userID: 7, wantErr: ErrInvalidStatusTransition, wantStock: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			products := newMemoryProducts(map[uint]int{1: 5})
+			orders := &memoryOrders{}
+			s := NewOrderService(&fakeTransactor{}, orders, products, nil)
+
+			order := newTestOrder(7, map[uint]int{1: 2})
+			if err := s.CreateOrder(ctx, order); err != nil {
+				t.Fatal(err)
+			}
+			if tt.paid {
+				if _, err := s.UpdateStatus(ctx, order.ID, models.OrderStatusPaid, 1, ""); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.racing != "" {
+				s = NewOrderService(&fakeTransactor{}, &racingOrders{memoryOrders: orders, status: tt.racing}, products, nil)
+			}
+
+			_, err := s.CancelOrder(ctx, order.ID, tt.userID, "changed my mind")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if got := products.stock()[1]; got != tt.wantStock {
+				t.Errorf("got stock %d, want %d", got, tt.wantStock)
+			}
+		})
+	}
+}
+
+func TestCreateOrderInsufficientStock(t *testing.T) {
+	ctx := context.Background()
+	products := newMemoryProducts(map[uint]int{1: 5, 2: 1})
+	orders := &memoryOrders{}
+	invalidator := &recordingInvalidator{}
+	s := NewOrderService(&fakeTransactor{}, orders, products, invalidator)
+
+	err := s.CreateOrder(ctx, newTestOrder(7, map[uint]int{1: 2, 2: 2}))
+	if !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("got %v, want ErrInsufficientStock", err)
+	}
+	if !reflect.DeepEqual(products.stock(), map[uint]int{1: 5, 2: 1}) || len(orders.orders) != 0 {
+		t.Errorf("failed order left stock %v and %d orders", products.stock(), len(orders.orders))
+	}
+	if len(invalidator.users) != 0 || invalidator.all != 0 {
+		t.Errorf("failed order invalidated the cache: %+v", invalidator)
+	}
+}
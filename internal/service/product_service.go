@@ -2,18 +2,59 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
 	"product-recommendations-go/internal/models"
 	"product-recommendations-go/internal/repository"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
+// Обмеження на поля товару
+const (
+	maxProductNameLength        = 255
+	maxProductCategoryLength    = 64
+	maxProductDescriptionLength = 10000
+	maxProductPrice             = 1_000_000
+	maxProductStock             = 1_000_000
+	// maxLowStockLimit максимальна кількість товарів у звіті про малий залишок
+	maxLowStockLimit = 500
+)
+
+var (
+	// ErrProductNotFound повертається, якщо товар не існує (або, для відновлення, не був видалений)
+	ErrProductNotFound = errors.New("product not found")
+	// ErrInsufficientStock товару на складі менше, ніж потрібно
+	ErrInsufficientStock = errors.New("insufficient stock")
+)
+
+// ValidationError помилка перевірки вхідних даних; повідомлення можна показувати клієнту
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
 type productService struct {
-	productRepo repository.ProductRepository
+	productRepo       repository.ProductRepository
+	invalidator       RecommendationInvalidator
+	lowStockThreshold int
 }
 
-// NewProductService створює новий екземпляр сервісу продуктів
-func NewProductService(productRepo repository.ProductRepository) ProductService {
+// NewProductService створює новий екземпляр сервісу продуктів. lowStockThreshold -
+// поріг малого залишку за замовчуванням для GetLowStock. invalidator може бути nil,
+// якщо кеш рекомендацій не використовується.
+func NewProductService(productRepo repository.ProductRepository, invalidator RecommendationInvalidator, lowStockThreshold int) ProductService {
 	return &productService{
-		productRepo: productRepo,
+		productRepo:       productRepo,
+		invalidator:       invalidator,
+		lowStockThreshold: lowStockThreshold,
 	}
 }
 
@@ -24,3 +65,194 @@ func (s *productService) GetByID(ctx context.Context, id uint) (*models.Product,
 func (s *productService) GetAll(ctx context.Context, page, limit int) ([]*models.Product, int64, error) {
 	return s.productRepo.GetAll(ctx, page, limit)
 }
+
+func (s *productService) Create(ctx context.Context, input models.ProductInput) (*models.Product, error) {
+	input, err := normalizeProductInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	product := &models.Product{Stock: input.Stock}
+	applyProductInput(product, input)
+
+	if err := s.productRepo.Create(ctx, product); err != nil {
+		return nil, err
+	}
+
+	invalidateCatalog(ctx, s.invalidator)
+	return product, nil
+}
+
+func (s *productService) Update(ctx context.Context, id uint, input models.ProductInput) (*models.Product, error) {
+	input, err := normalizeProductInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := s.productRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, ErrProductNotFound
+	}
+
+	// Залишок змінюється лише через AdjustStock, тому input.Stock тут ігнорується
+	applyProductInput(product, input)
+
+	if err := s.productRepo.Update(ctx, product); err != nil {
+		return nil, err
+	}
+
+	invalidateCatalog(ctx, s.invalidator)
+	return product, nil
+}
+
+func (s *productService) Delete(ctx context.Context, id uint) error {
+	product, err := s.productRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if product == nil {
+		return ErrProductNotFound
+	}
+
+	// Видалення м'яке: товар зникає з каталогу, але залишається в історії замовлень
+	if err := s.productRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	invalidateCatalog(ctx, s.invalidator)
+	return nil
+}
+
+func (s *productService) Restore(ctx context.Context, id uint) (*models.Product, error) {
+	product, err := s.productRepo.Restore(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, ErrProductNotFound
+	}
+
+	invalidateCatalog(ctx, s.invalidator)
+	return product, nil
+}
+
+func (s *productService) AdjustStock(ctx context.Context, id uint, adjustment StockAdjustment) (*models.Product, error) {
+	var (
+		updated bool
+		err     error
+	)
+	switch {
+	case adjustment.Stock != nil && adjustment.Delta != nil:
+		return nil, &ValidationError{"stock", "either stock or delta must be set, not both"}
+	case adjustment.Stock != nil:
+		if *adjustment.Stock < 0 || *adjustment.Stock > maxProductStock {
+			return nil, &ValidationError{"stock", fmt.Sprintf("must be between 0 and %d", maxProductStock)}
+		}
+		updated, err = s.productRepo.SetStock(ctx, id, *adjustment.Stock)
+	case adjustment.Delta != nil:
+		if *adjustment.Delta == 0 || *adjustment.Delta < -maxProductStock || *adjustment.Delta > maxProductStock {
+			return nil, &ValidationError{"delta", fmt.Sprintf("must be non-zero and between -%d and %d", maxProductStock, maxProductStock)}
+		}
+		updated, err = s.productRepo.AdjustStock(ctx, id, *adjustment.Delta)
+	default:
+		return nil, &ValidationError{"stock", "either stock or delta is required"}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !updated {
+		product, err := s.productRepo.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if product == nil {
+			return nil, ErrProductNotFound
+		}
+		// Товар є, отже списання перевищує залишок
+		return nil, fmt.Errorf("%w: product %d has %d in stock", ErrInsufficientStock, id, product.Stock)
+	}
+
+	invalidateCatalog(ctx, s.invalidator)
+	return s.productRepo.GetByID(ctx, id)
+}
+
+func (s *productService) GetLowStock(ctx context.Context, threshold, limit int) ([]*models.Product, error) {
+	if threshold < 0 {
+		threshold = s.lowStockThreshold
+	}
+	if limit <= 0 || limit > maxLowStockLimit {
+		limit = maxLowStockLimit
+	}
+	return s.productRepo.GetLowStock(ctx, threshold, limit)
+}
+
+// normalizeProductInput прибирає зайві пробіли та перевіряє поля товару
+func normalizeProductInput(input models.ProductInput) (models.ProductInput, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	input.Description = strings.TrimSpace(input.Description)
+	input.Category = strings.TrimSpace(input.Category)
+	input.ImageURL = strings.TrimSpace(input.ImageURL)
+
+	switch {
+	case input.Name == "":
+		return input, &ValidationError{"name", "is required"}
+	case utf8.RuneCountInString(input.Name) > maxProductNameLength:
+		return input, &ValidationError{"name", fmt.Sprintf("must be at most %d characters", maxProductNameLength)}
+	case hasControlChars(input.Name):
+		return input, &ValidationError{"name", "must not contain control characters"}
+	}
+
+	switch {
+	case math.IsNaN(input.Price) || math.IsInf(input.Price, 0):
+		return input, &ValidationError{"price", "must be a number"}
+	case input.Price <= 0:
+		return input, &ValidationError{"price", "must be greater than zero"}
+	case input.Price > maxProductPrice:
+		return input, &ValidationError{"price", fmt.Sprintf("must not exceed %d", maxProductPrice)}
+	case math.Abs(math.Round(input.Price*100)-input.Price*100) > 1e-6:
+		return input, &ValidationError{"price", "must have at most two decimal places"}
+	}
+
+	switch {
+	case input.Category == "":
+		return input, &ValidationError{"category", "is required"}
+	case utf8.RuneCountInString(input.Category) > maxProductCategoryLength:
+		return input, &ValidationError{"category", fmt.Sprintf("must be at most %d characters", maxProductCategoryLength)}
+	case hasControlChars(input.Category):
+		return input, &ValidationError{"category", "must not contain control characters"}
+	}
+
+	if utf8.RuneCountInString(input.Description) > maxProductDescriptionLength {
+		return input, &ValidationError{"description", fmt.Sprintf("must be at most %d characters", maxProductDescriptionLength)}
+	}
+
+	if input.ImageURL != "" {
+		u, err := url.Parse(input.ImageURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return input, &ValidationError{"image_url", "must be an absolute http(s) URL"}
+		}
+	}
+
+	if input.Stock < 0 || input.Stock > maxProductStock {
+		return input, &ValidationError{"stock", fmt.Sprintf("must be between 0 and %d", maxProductStock)}
+	}
+
+	return input, nil
+}
+
+func applyProductInput(product *models.Product, input models.ProductInput) {
+	product.Name = input.Name
+	product.Description = input.Description
+	product.Price = input.Price
+	product.Category = input.Category
+	product.ImageURL = input.ImageURL
+	product.IsConsumable = input.IsConsumable
+}
+
+func hasControlChars(s string) bool {
+	return strings.IndexFunc(s, unicode.IsControl) >= 0
+}
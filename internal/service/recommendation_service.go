@@ -2,63 +2,341 @@ package service
 
 import (
 	"context"
+	"errors"
+	"io/fs"
 	"log"
+	"math"
 	"product-recommendations-go/internal/models"
 	"product-recommendations-go/internal/repository"
+	"product-recommendations-go/pkg/ann"
 	"product-recommendations-go/pkg/recommendation"
+	"product-recommendations-go/pkg/recommendation/snapshot"
+	"sync"
+	"time"
 )
 
 type recommendationService struct {
 	likeRepo    repository.UserLikeRepository
 	orderRepo   repository.OrderRepository
 	productRepo repository.ProductRepository
+	model       *snapshot.Snapshot
+	index       recommendation.VectorIndex
+	repurchase  *repurchaseLearner
 }
 
-// NewRecommendationService створює новий екземпляр сервісу рекомендацій
+// NewRecommendationService створює новий екземпляр сервісу рекомендацій.
+// model - навчена офлайн модель (може бути nil); якщо вона містить вектори
+// факторизації товарів, для них використовується індекс пошуку найближчих сусідів,
+// збережений у indexPath, або будується новий (див. loadVectorIndex).
+// Інтервали повторних покупок беруться з моделі, а без неї навчаються на замовленнях.
 func NewRecommendationService(
 	likeRepo repository.UserLikeRepository,
 	orderRepo repository.OrderRepository,
 	productRepo repository.ProductRepository,
+	model *snapshot.Snapshot,
+	indexPath string,
 ) RecommendationService {
-	return &recommendationService{
+	s := &recommendationService{
 		likeRepo:    likeRepo,
 		orderRepo:   orderRepo,
 		productRepo: productRepo,
+		model:       model,
 	}
+
+	if model != nil && len(model.ItemFactors) > 0 {
+		index, err := loadVectorIndex(model, indexPath)
+		if err != nil {
+			log.Printf("Failed to build vector index from model %q: %v", model.ModelVersion, err)
+		} else {
+			s.index = index
+		}
+	}
+
+	if model == nil || len(model.RepurchaseIntervals) == 0 {
+		s.repurchase = &repurchaseLearner{orderRepo: orderRepo}
+		// Перше навчання запускається одразу, щоб не чекати першого запиту
+		s.repurchase.get()
+	}
+
+	return s
 }
 
-func (s *recommendationService) GetRecommendations(ctx context.Context, userID uint, limit int) ([]*models.ProductRecommendation, error) {
+// loadVectorIndex повертає індекс векторів моделі. Індекс зі шляху path
+// використовується, якщо він містить саме вектори моделі; інакше індекс
+// будується заново і зберігається в path, щоб наступний запуск його не будував.
+// Порожній path вимикає збереження.
+func loadVectorIndex(model *snapshot.Snapshot, path string) (*ann.HNSW, error) {
+	if path != "" {
+		index, err := ann.Load(path)
+		switch {
+		case err == nil && indexMatches(index, model.ItemFactors):
+			log.Printf("Vector index for model %q loaded from %s: %d items", model.ModelVersion, path, index.Len())
+			return index, nil
+		case err == nil:
+			log.Printf("Vector index %s does not match model %q, rebuilding", path, model.ModelVersion)
+		case !errors.Is(err, fs.ErrNotExist):
+			log.Printf("Failed to load vector index %s, rebuilding: %v", path, err)
+		}
+	}
+
+	index, err := ann.Build(model.ItemFactors, ann.DefaultConfig())
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Vector index built from model %q: %d items", model.ModelVersion, index.Len())
+
+	if path != "" {
+		if err := index.Save(path); err != nil {
+			log.Printf("Failed to save vector index to %s: %v", path, err)
+		}
+	}
+	return index, nil
+}
+
+// indexMatches перевіряє, що індекс містить ті самі товари і вектори, що й factors.
+// Косинусний індекс зберігає нормовані вектори, тому порівнюються напрямки.
+func indexMatches(index *ann.HNSW, factors map[uint][]float64) bool {
+	if index.Len() != len(factors) {
+		return false
+	}
+	for id, factor := range factors {
+		stored, ok := index.Vector(id)
+		if !ok || len(stored) != len(factor) {
+			return false
+		}
+		var dot, norm float64
+		for i := range factor {
+			dot += stored[i] * factor[i]
+			norm += factor[i] * factor[i]
+		}
+		if norm == 0 || math.Abs(dot/math.Sqrt(norm)-1) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *recommendationService) GetRecommendations(ctx context.Context, userID uint, limit int, filter models.RecommendationFilter) ([]*models.ProductRecommendation, error) {
 	// Якщо ліміт не вказаний або недійсний, встановлюємо значення за замовчуванням
 	if limit <= 0 {
 		limit = 10
 	}
 
-	// Отримуємо лайки користувача
-	userLikes, err := s.likeRepo.GetByUserID(ctx, userID)
+	// Отримуємо всі товари
+	allProducts, err := s.loadProducts(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// Отримуємо замовлення користувача
-	userOrders, err := s.orderRepo.GetByUserID(ctx, userID)
+	return s.recommendForUser(ctx, userID, limit, filter, allProducts)
+}
+
+func (s *recommendationService) GetBatchRecommendations(
+	ctx context.Context,
+	userIDs []uint,
+	limit, concurrency int,
+	filter models.RecommendationFilter,
+	emit func(result *models.BatchRecommendationResult) error,
+) error {
+	if limit <= 0 {
+		limit = 10
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	// Знімок каталогу завантажується один раз для всього пакету
+	allProducts, err := s.loadProducts(ctx)
+	if err != nil {
+		return err
+	}
+
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan uint)
+	results := make(chan *models.BatchRecommendationResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for userID := range jobs {
+				result := &models.BatchRecommendationResult{UserID: userID}
+				recommendations, err := s.recommendForUser(workerCtx, userID, limit, filter, allProducts)
+				if err != nil {
+					result.Error = err.Error()
+				} else {
+					result.Recommendations = recommendations
+				}
+
+				select {
+				case results <- result:
+				case <-workerCtx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	// Роздаємо користувачів воркерам
+	go func() {
+		defer close(jobs)
+		for _, userID := range userIDs {
+			select {
+			case jobs <- userID:
+			case <-workerCtx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Результати віддаються з однієї горутини, тому emit не потребує синхронізації
+	for result := range results {
+		if err := emit(result); err != nil {
+			cancel()
+			for range results {
+				// Дочікуємося завершення воркерів
+			}
+			return err
+		}
+	}
+
+	// Якщо клієнт відключився, частина користувачів не була оброблена
+	return ctx.Err()
+}
+
+// catalogPageSize кількість товарів, що завантажуються за один запит до репозиторію
+const catalogPageSize = 1000
+
+// loadProducts завантажує знімок усього каталогу товарів для обчислення рекомендацій,
+// посторінково, щоб не обмежувати рекомендації першою сторінкою
+func (s *recommendationService) loadProducts(ctx context.Context) ([]*models.Product, error) {
+	var allProducts []*models.Product
+	for page := 1; ; page++ {
+		products, total, err := s.productRepo.GetAll(ctx, page, catalogPageSize)
+		if err != nil {
+			return nil, err
+		}
+		allProducts = append(allProducts, products...)
+		if len(products) < catalogPageSize || int64(len(allProducts)) >= total {
+			return allProducts, nil
+		}
+	}
+}
+
+// repurchaseIntervals повертає інтервали повторних покупок для стратегії поповнення
+func (s *recommendationService) repurchaseIntervals() snapshot.RepurchaseIntervals {
+	if s.repurchase != nil {
+		return s.repurchase.get()
+	}
+	return s.model.RepurchaseIntervals
+}
+
+// recommendForUser обчислює рекомендації користувача для вже завантаженого каталогу
+func (s *recommendationService) recommendForUser(ctx context.Context, userID uint, limit int, filter models.RecommendationFilter, allProducts []*models.Product) ([]*models.ProductRecommendation, error) {
+	// Отримуємо лайки користувача
+	userLikes, err := s.likeRepo.GetByUserID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Отримуємо всі товари
-	allProducts, _, err := s.productRepo.GetAll(ctx, 1, 1000) // Отримуємо велику кількість товарів
+	// Отримуємо замовлення користувача; скасовані та повернуті не враховуються
+	userOrders, err := s.orderRepo.GetByUserID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
+	userOrders = models.PurchasedOrders(userOrders)
 
 	log.Printf("User ID: %d, Likes count: %d, Orders count: %d, Products count: %d",
 		userID, len(userLikes), len(userOrders), len(allProducts))
 
 	// Викликаємо функцію для обчислення рекомендацій
-	recommendedProducts, recommendationScores := recommendation.RecommendProducts(userID, userLikes, userOrders, allProducts, limit)
+	// Випадкова добірка стабільна для користувача протягом дня
+	opts := recommendation.Options{
+		Source:              recommendation.NewDailySource(userID, time.Now()),
+		Filter:              filter,
+		Model:               s.model,
+		Index:               s.index,
+		RepurchaseIntervals: s.repurchaseIntervals(),
+	}
+	recommendedProducts, recommendationScores := recommendation.RecommendProductsWithOptions(userID, userLikes, userOrders, allProducts, limit, opts)
 
 	log.Printf("Received recommendations: %d, scores: %d", len(recommendedProducts), len(recommendationScores))
 
+	recommendations := toRecommendations(recommendedProducts, recommendationScores, limit)
+
+	log.Printf("Final recommendations with scores: %d", len(recommendations))
+	return recommendations, nil
+}
+
+func (s *recommendationService) GetCartRecommendations(ctx context.Context, productIDs []uint, limit int, filter models.RecommendationFilter) ([]*models.ProductRecommendation, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if len(productIDs) == 0 {
+		return nil, nil
+	}
+
+	allProducts, err := s.loadProducts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	coPurchases, err := s.orderRepo.GetCoPurchaseCounts(ctx, productIDs, cartCandidateLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := recommendation.Options{
+		Filter: filter,
+		Model:  s.model,
+		Index:  s.index,
+	}
+	recommendedProducts, recommendationScores := recommendation.RecommendForCart(productIDs, coPurchases, allProducts, limit, opts)
+
+	return toRecommendations(recommendedProducts, recommendationScores, limit), nil
+}
+
+func (s *recommendationService) GetSimilarProducts(ctx context.Context, productID uint, limit int, filter models.RecommendationFilter) ([]*models.ProductRecommendation, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	product, err := s.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, ErrProductNotFound
+	}
+
+	allProducts, err := s.loadProducts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := recommendation.Options{
+		Filter: filter,
+		Model:  s.model,
+		Index:  s.index,
+	}
+	similarProducts, similarityScores := recommendation.SimilarProducts(productID, allProducts, limit, opts)
+
+	return toRecommendations(similarProducts, similarityScores, limit), nil
+}
+
+// cartCandidateLimit скільки товарів, куплених разом з товарами кошика, розглядається як кандидати
+const cartCandidateLimit = 500
+
+// toRecommendations поєднує товари з їхніми оцінками і обмежує кількість рекомендацій
+func toRecommendations(recommendedProducts []*models.Product, recommendationScores []float64, limit int) []*models.ProductRecommendation {
 	// Переконуємося, що у нас однакова кількість продуктів і оцінок
 	if len(recommendedProducts) != len(recommendationScores) {
 		log.Printf("Warning: Mismatch between recommendations (%d) and scores (%d)",
@@ -88,6 +366,5 @@ func (s *recommendationService) GetRecommendations(ctx context.Context, userID u
 		recommendations = recommendations[:limit]
 	}
 
-	log.Printf("Final recommendations with scores: %d", len(recommendations))
-	return recommendations, nil
+	return recommendations
 }
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"product-recommendations-go/pkg/ann"
+	"product-recommendations-go/pkg/recommendation/snapshot"
+)
+
+func TestLoadVectorIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.hnsw")
+	model := &snapshot.Snapshot{
+		ModelVersion: "v1",
+		ItemFactors:  map[uint][]float64{1: {1, 0}, 2: {0, 2}, 3: {1, 1}},
+	}
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	// saved перевіряє, що у файлі індекс для model, і повертає час його запису
+	saved := func(t *testing.T, model *snapshot.Snapshot) time.Time {
+		t.Helper()
+		index, err := ann.Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !indexMatches(index, model.ItemFactors) {
+			t.Errorf("saved index does not match model %q", model.ModelVersion)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.ModTime()
+	}
+	load := func(t *testing.T, model *snapshot.Snapshot) {
+		t.Helper()
+		index, err := loadVectorIndex(model, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !indexMatches(index, model.ItemFactors) {
+			t.Errorf("index does not match model %q", model.ModelVersion)
+		}
+	}
+
+	t.Run("missing file is built and saved", func(t *testing.T) {
+		load(t, model)
+		saved(t, model)
+	})
+
+	t.Run("matching file is reused", func(t *testing.T) {
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+		load(t, model)
+		if !saved(t, model).Equal(old) {
+			t.Error("index was rebuilt although the saved one matches the model")
+		}
+	})
+
+	t.Run("changed model is rebuilt", func(t *testing.T) {
+		changed := &snapshot.Snapshot{
+			ModelVersion: "v2",
+			ItemFactors:  map[uint][]float64{1: {1, 0}, 2: {0, 2}, 3: {1, -1}},
+		}
+		load(t, changed)
+		saved(t, changed)
+	})
+
+	t.Run("corrupt file is rebuilt", func(t *testing.T) {
+		if err := os.WriteFile(path, []byte("not an index"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		load(t, model)
+		saved(t, model)
+	})
+}
+
+func TestLoadVectorIndexWithoutPath(t *testing.T) {
+	model := &snapshot.Snapshot{ItemFactors: map[uint][]float64{1: {1, 0}}}
+	index, err := loadVectorIndex(model, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Len() != 1 {
+		t.Errorf("got %d vectors, want 1", index.Len())
+	}
+}
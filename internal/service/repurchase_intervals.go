@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"log"
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/repository"
+	"product-recommendations-go/pkg/recommendation/snapshot"
+	"sync"
+	"time"
+)
+
+// repurchaseRefreshInterval як часто перенавчаються інтервали повторних покупок,
+// якщо навчена модель не завантажена
+const repurchaseRefreshInterval = time.Hour
+
+// repurchaseLoadTimeout час на читання покупок для одного перенавчання
+const repurchaseLoadTimeout = 2 * time.Minute
+
+// repurchaseLearner навчає інтервали повторних покупок на історії покупок усіх
+// користувачів і періодично оновлює їх у фоні
+type repurchaseLearner struct {
+	orderRepo repository.OrderRepository
+
+	mu          sync.Mutex
+	intervals   snapshot.RepurchaseIntervals
+	refreshedAt time.Time
+	refreshing  bool
+}
+
+// get повертає поточні інтервали і, якщо вони застаріли, запускає фонове
+// перенавчання. Запит не чекає на читання покупок: до завершення першого
+// навчання інтервалів немає і стратегія поповнення не застосовується. Якщо
+// оновлення не вдалося, залишаються попередні значення, а наступна спроба буде
+// після repurchaseRefreshInterval.
+func (l *repurchaseLearner) get() snapshot.RepurchaseIntervals {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.refreshing && time.Since(l.refreshedAt) >= repurchaseRefreshInterval {
+		l.refreshing = true
+		l.refreshedAt = time.Now()
+		go l.refresh()
+	}
+	return l.intervals
+}
+
+// refresh читає покупки з власним контекстом, щоб скасування запиту,
+// який запустив оновлення, не переривало його, і підміняє інтервали лише після
+// успішного навчання
+func (l *repurchaseLearner) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), repurchaseLoadTimeout)
+	defer cancel()
+
+	// Покупки читаються потоком, без завантаження всіх замовлень у пам'ять
+	learner := snapshot.NewRepurchaseLearner()
+	purchases := 0
+	err := l.orderRepo.ForEachPurchase(ctx, func(purchase *models.Purchase) error {
+		learner.Observe(purchase.UserID, purchase.ProductID, purchase.PurchasedAt)
+		purchases++
+		return nil
+	})
+
+	var intervals snapshot.RepurchaseIntervals
+	if err != nil {
+		log.Printf("Failed to load purchases for repurchase intervals: %v", err)
+	} else {
+		intervals = learner.Intervals()
+		log.Printf("Repurchase intervals learned for %d products from %d purchases", len(intervals), purchases)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err == nil {
+		l.intervals = intervals
+	}
+	l.refreshing = false
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/repository"
+	"product-recommendations-go/pkg/recommendation/snapshot"
+)
+
+// streamedPurchases віддає покупки потоком і, якщо err задана, обриває його після першої
+type streamedPurchases struct {
+	repository.OrderRepository
+	purchases []*models.Purchase
+	err       error
+}
+
+func (r *streamedPurchases) ForEachPurchase(_ context.Context, fn func(purchase *models.Purchase) error) error {
+	for _, purchase := range r.purchases {
+		if err := fn(purchase); err != nil {
+			return err
+		}
+		if r.err != nil {
+			return r.err
+		}
+	}
+	return nil
+}
+
+func TestRepurchaseLearnerRefresh(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := func(n int) time.Time { return start.AddDate(0, 0, n) }
+	purchase := func(userID, productID uint, at time.Time) *models.Purchase {
+		return &models.Purchase{UserID: userID, ProductID: productID, PurchasedAt: at}
+	}
+
+	// Товар 1: інтервали 10, 10 і 20 днів (докупівля через годину не рахується);
+	// товар 2 куплено повторно лише двічі - замало спостережень
+	orders := &streamedPurchases{purchases: []*models.Purchase{
+		purchase(1, 1, day(0)), purchase(1, 1, day(0).Add(time.Hour)), purchase(1, 1, day(10)), purchase(1, 1, day(20)),
+		purchase(1, 2, day(0)), purchase(1, 2, day(5)),
+		purchase(2, 1, day(3)), purchase(2, 1, day(23)),
+		purchase(2, 2, day(1)), purchase(2, 2, day(8)),
+	}}
+	learner := &repurchaseLearner{orderRepo: orders, refreshing: true}
+
+	learner.refresh()
+	want := snapshot.RepurchaseIntervals{1: 10}
+	if !reflect.DeepEqual(learner.intervals, want) || learner.refreshing {
+		t.Fatalf("got intervals %v (refreshing %v), want %v", learner.intervals, learner.refreshing, want)
+	}
+
+	// Невдале оновлення зберігає попередні інтервали
+	orders.err = errors.New("connection lost")
+	learner.refreshing = true
+	learner.refresh()
+	if !reflect.DeepEqual(learner.intervals, want) || learner.refreshing {
+		t.Errorf("after failure: got intervals %v (refreshing %v), want %v", learner.intervals, learner.refreshing, want)
+	}
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"log"
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/repository"
+	"sync"
+	"time"
+)
+
+// TokenDenylist список відкликаних токенів доступу
+type TokenDenylist interface {
+	// Revoke відкликає ідентифікатор (токена або сесії) до моменту expiresAt
+	Revoke(ctx context.Context, id string, userID uint, expiresAt time.Time) error
+	// IsRevoked перевіряє, чи відкликаний хоча б один з ідентифікаторів
+	IsRevoked(ctx context.Context, ids ...string) (bool, error)
+	// Cleanup видаляє записи, термін дії яких закінчився
+	Cleanup(ctx context.Context) error
+}
+
+// denylistEntry закешований результат перевірки токена
+type denylistEntry struct {
+	revoked bool
+	// validUntil до якого моменту результат можна використовувати без звернення до бази
+	validUntil time.Time
+}
+
+type tokenDenylist struct {
+	repo repository.RevokedTokenRepository
+
+	// cacheTTL час життя закешованого результату "не відкликаний"; 0 - кеш вимкнений
+	cacheTTL time.Duration
+	mu       sync.Mutex
+	cache    map[string]denylistEntry
+}
+
+// NewTokenDenylist створює список відкликаних токенів, що зберігається в базі даних.
+// Якщо cacheTTL > 0, результати перевірок кешуються в пам'яті процесу: відкликання
+// на цьому екземплярі діє одразу, а на інших екземплярах - не пізніше ніж через cacheTTL.
+func NewTokenDenylist(repo repository.RevokedTokenRepository, cacheTTL time.Duration) TokenDenylist {
+	d := &tokenDenylist{
+		repo:     repo,
+		cacheTTL: cacheTTL,
+	}
+	if cacheTTL > 0 {
+		d.cache = make(map[string]denylistEntry)
+	}
+	return d
+}
+
+func (d *tokenDenylist) Revoke(ctx context.Context, id string, userID uint, expiresAt time.Time) error {
+	err := d.repo.Create(ctx, &models.RevokedToken{
+		JTI:       id,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Відкликаний токен залишається відкликаним до кінця терміну дії
+	d.remember(id, denylistEntry{revoked: true, validUntil: expiresAt})
+	return nil
+}
+
+func (d *tokenDenylist) IsRevoked(ctx context.Context, ids ...string) (bool, error) {
+	// Якщо всі ідентифікатори є в кеші, база не потрібна
+	var unknown []string
+	for _, id := range ids {
+		entry, ok := d.lookup(id)
+		if !ok {
+			unknown = append(unknown, id)
+			continue
+		}
+		if entry.revoked {
+			return true, nil
+		}
+	}
+	if len(unknown) == 0 {
+		return false, nil
+	}
+
+	revoked, err := d.repo.FindRevoked(ctx, unknown)
+	if err != nil {
+		return false, err
+	}
+
+	revokedSet := make(map[string]bool, len(revoked))
+	for _, id := range revoked {
+		revokedSet[id] = true
+	}
+
+	validUntil := time.Now().Add(d.cacheTTL)
+	for _, id := range unknown {
+		d.remember(id, denylistEntry{revoked: revokedSet[id], validUntil: validUntil})
+	}
+
+	return len(revoked) > 0, nil
+}
+
+func (d *tokenDenylist) Cleanup(ctx context.Context) error {
+	d.pruneCache()
+
+	deleted, err := d.repo.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Printf("Removed %d expired revoked tokens", deleted)
+	}
+	return nil
+}
+
+func (d *tokenDenylist) lookup(jti string) (denylistEntry, bool) {
+	if d.cache == nil {
+		return denylistEntry{}, false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok := d.cache[jti]
+	if !ok || time.Now().After(entry.validUntil) {
+		return denylistEntry{}, false
+	}
+	return entry, true
+}
+
+func (d *tokenDenylist) remember(jti string, entry denylistEntry) {
+	if d.cache == nil {
+		return
+	}
+
+	d.mu.Lock()
+	d.cache[jti] = entry
+	d.mu.Unlock()
+}
+
+// pruneCache видаляє застарілі записи кешу
+func (d *tokenDenylist) pruneCache() {
+	if d.cache == nil {
+		return
+	}
+
+	now := time.Now()
+	d.mu.Lock()
+	for jti, entry := range d.cache {
+		if now.After(entry.validUntil) {
+			delete(d.cache, jti)
+		}
+	}
+	d.mu.Unlock()
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"product-recommendations-go/internal/jwtkeys"
+	"product-recommendations-go/internal/models"
+)
+
+// memoryRevokedTokens список відкликаних у пам'яті, що рахує звернення до бази
+type memoryRevokedTokens struct {
+	tokens  map[string]*models.RevokedToken
+	queries int
+}
+
+func (r *memoryRevokedTokens) Create(_ context.Context, token *models.RevokedToken) error {
+	if r.tokens == nil {
+		r.tokens = make(map[string]*models.RevokedToken)
+	}
+	r.tokens[token.JTI] = token
+	return nil
+}
+
+func (r *memoryRevokedTokens) FindRevoked(_ context.Context, ids []string) ([]string, error) {
+	r.queries++
+	var revoked []string
+	for _, id := range ids {
+		if _, ok := r.tokens[id]; ok {
+			revoked = append(revoked, id)
+		}
+	}
+	return revoked, nil
+}
+
+func (r *memoryRevokedTokens) DeleteExpired(_ context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	for id, token := range r.tokens {
+		if token.ExpiresAt.Before(before) {
+			delete(r.tokens, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func TestTokenDenylist(t *testing.T) {
+	tests := []struct {
+		name     string
+		cacheTTL time.Duration
+		// wantQueries звернень до бази за дві однакові перевірки невідкликаного токена
+		wantQueries int
+	}{
+		{name: "without cache", cacheTTL: 0, wantQueries: 2},
+		{name: "with cache", cacheTTL: time.Hour, wantQueries: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := &memoryRevokedTokens{}
+			d := NewTokenDenylist(repo, tt.cacheTTL)
+
+			for i := 0; i < 2; i++ {
+				if revoked, err := d.IsRevoked(ctx, "active"); err != nil || revoked {
+					t.Fatalf("active token: revoked %v, %v", revoked, err)
+				}
+			}
+			if repo.queries != tt.wantQueries {
+				t.Errorf("got %d queries, want %d", repo.queries, tt.wantQueries)
+			}
+
+			if err := d.Revoke(ctx, "stolen", 7, time.Now().Add(time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+			if revoked, err := d.IsRevoked(ctx, "active", "stolen"); err != nil || !revoked {
+				t.Errorf("revoked token: revoked %v, %v", revoked, err)
+			}
+
+			// Відкликання з іншого екземпляра видно без кешу одразу
+			if err := NewTokenDenylist(repo, 0).Revoke(ctx, "elsewhere", 7, time.Now().Add(time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+			if revoked, err := d.IsRevoked(ctx, "elsewhere"); err != nil || !revoked {
+				t.Errorf("token revoked elsewhere: revoked %v, %v", revoked, err)
+			}
+		})
+	}
+}
+
+func TestTokenDenylistCleanup(t *testing.T) {
+	ctx := context.Background()
+	repo := &memoryRevokedTokens{}
+	d := NewTokenDenylist(repo, time.Hour)
+
+	if err := d.Revoke(ctx, "expired", 7, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Revoke(ctx, "valid", 7, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Cleanup(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := repo.tokens["expired"]; ok {
+		t.Error("expired record kept")
+	}
+	if revoked, err := d.IsRevoked(ctx, "valid"); err != nil || !revoked {
+		t.Errorf("valid record: revoked %v, %v", revoked, err)
+	}
+}
+
+func TestParseTokenChecksDenylist(t *testing.T) {
+	ctx := context.Background()
+	keys := jwtkeys.NewHMACKeySet([]byte("test-secret"))
+	denylist := NewTokenDenylist(&memoryRevokedTokens{}, 0)
+	s := &authService{
+		refreshRepo: &memoryRefreshTokens{},
+		denylist:    denylist,
+		cfg:         AuthConfig{Keys: keys, AccessTokenTTL: time.Minute},
+	}
+
+	sign := func(jti, sid string) string {
+		claims := accessClaims()
+		claims["jti"] = jti
+		claims["sid"] = sid
+		token, err := keys.Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	tokens := map[string]string{
+		"revoked token":   sign("revoked", "session-a"),
+		"same session":    sign("sibling", "session-a"),
+		"logged out":      sign("logout", "session-b"),
+		"revoked session": sign("other", "session-c"),
+		"untouched token": sign("untouched", "session-d"),
+	}
+
+	if err := denylist.Revoke(ctx, "revoked", 7, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Logout(ctx, tokens["logged out"]); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.revokeSession(ctx, "session-c", 7); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		wantRevoked bool
+	}{
+		{name: "revoked token", wantRevoked: true},
+		// Відкликання одного токена не зачіпає інших токенів сесії
+		{name: "same session"},
+		{name: "logged out", wantRevoked: true},
+		{name: "revoked session", wantRevoked: true},
+		{name: "untouched token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.ParseToken(ctx, tokens[tt.name])
+			if revoked := err != nil; revoked != tt.wantRevoked {
+				t.Errorf("got %v, want revoked %v", err, tt.wantRevoked)
+			}
+		})
+	}
+}
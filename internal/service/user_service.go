@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/internal/repository"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidProfile некоректне значення поля профілю
+var ErrInvalidProfile = errors.New("invalid profile")
+
+const (
+	// maxNameLength максимальна довжина імені в символах
+	maxNameLength = 100
+	// maxPreferencesSize максимальний розмір налаштувань у серіалізованому вигляді
+	maxPreferencesSize = 4096
+)
+
+// localePattern мовний тег BCP 47 у спрощеному вигляді: мова та необов'язковий регіон (uk, uk-UA)
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2}|-[0-9]{3})?$`)
+
+type userService struct {
+	userRepo repository.UserRepository
+}
+
+// NewUserService створює новий екземпляр сервісу профілю користувача
+func NewUserService(userRepo repository.UserRepository) UserService {
+	return &userService{
+		userRepo: userRepo,
+	}
+}
+
+func (s *userService) GetProfile(ctx context.Context, userID uint) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+func (s *userService) UpdateProfile(ctx context.Context, userID uint, input ProfileInput) (*models.User, error) {
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []string
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if utf8.RuneCountInString(name) > maxNameLength {
+			return nil, ErrInvalidProfile
+		}
+		user.Name = name
+		columns = append(columns, "name")
+	}
+
+	if input.Locale != nil {
+		if *input.Locale != "" && !localePattern.MatchString(*input.Locale) {
+			return nil, ErrInvalidProfile
+		}
+		user.Locale = *input.Locale
+		columns = append(columns, "locale")
+	}
+
+	if input.Preferences != nil {
+		encoded, err := json.Marshal(*input.Preferences)
+		if err != nil || len(encoded) > maxPreferencesSize {
+			return nil, ErrInvalidProfile
+		}
+		user.Preferences = *input.Preferences
+		columns = append(columns, "preferences")
+	}
+
+	// Зберігаються лише передані поля, щоб паралельні запити не перезаписували
+	// зміни одне одного
+	if len(columns) == 0 {
+		return user, nil
+	}
+	if err := s.userRepo.UpdateColumns(ctx, user, columns...); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,105 @@
+// Package totp реалізує одноразові паролі на основі часу (TOTP, RFC 6238) з
+// параметрами, які підтримують поширені застосунки-автентифікатори: HMAC-SHA1,
+// 6 цифр, крок 30 секунд.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Digits кількість цифр коду
+	Digits = 6
+	// Period тривалість кроку
+	Period = 30 * time.Second
+	// secretSize довжина секрету в байтах (160 біт, як рекомендує RFC 4226)
+	secretSize = 20
+)
+
+// encoding Base32 без доповнення, у якому секрет передається застосунку
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret генерує новий випадковий секрет у кодуванні Base32
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// URI формує посилання otpauth:// для додавання облікового запису в застосунок
+// (зазвичай показується як QR-код)
+func URI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(Digits))
+	params.Set("period", fmt.Sprint(int(Period.Seconds())))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// Step повертає номер кроку для моменту t
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period.Seconds())
+}
+
+// Code обчислює код для кроку step
+func Code(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("decode totp secret: %w", err)
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Динамічне скорочення (RFC 4226, розділ 5.3)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, value%mod), nil
+}
+
+// Validate перевіряє код для моменту t з допуском skew кроків у кожен бік
+// (розбіжність годинників). Повертає крок, якому відповідає код; коди кроків,
+// не більших за afterStep, відхиляються, щоб один код не можна було використати двічі.
+func Validate(secret, code string, t time.Time, skew int, afterStep int64) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	current := Step(t)
+	for step := current - int64(skew); step <= current+int64(skew); step++ {
+		if step <= afterStep {
+			continue
+		}
+		expected, err := Code(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
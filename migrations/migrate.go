@@ -11,16 +11,54 @@ import (
 func main() {
 	db := config.GetDB()
 
+	// Залишки з'явилися пізніше за каталог: товари, що існували до появи колонки,
+	// отримують INITIAL_PRODUCT_STOCK, інакше весь наявний каталог став би
+	// недоступним для замовлень
+	initialStock := config.GetEnvInt("INITIAL_PRODUCT_STOCK", 1000)
+	if initialStock < 0 || initialStock > 1_000_000 {
+		log.Fatalf("INITIAL_PRODUCT_STOCK must be between 0 and 1000000, got %d", initialStock)
+	}
+	backfillStock := db.Migrator().HasTable(&models.Product{}) && !db.Migrator().HasColumn(&models.Product{}, "Stock")
+
 	err := db.AutoMigrate(
 		&models.User{},
 		&models.Product{},
 		&models.UserLike{},
 		&models.Order{},
 		&models.OrderItem{},
+		&models.OrderStatusChange{},
+		&models.RevokedToken{},
+		&models.RefreshToken{},
+		&models.UserToken{},
+		&models.LoginAttempt{},
+		&models.LoginFailureCounter{},
+		&models.RecoveryCode{},
+		&models.UserIdentity{},
+		&models.OIDCLoginState{},
+		&models.APIKey{},
+		&models.DataExport{},
+		&models.Cart{},
+		&models.CartItem{},
 	)
 	if err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
+	if backfillStock {
+		result := db.Unscoped().Model(&models.Product{}).Where("stock = ?", 0).Update("stock", initialStock)
+		if result.Error != nil {
+			log.Fatalf("Failed to backfill product stock: %v", result.Error)
+		}
+		log.Printf("Set stock of %d existing products to %d", result.RowsAffected, initialStock)
+	}
+
+	// До появи статусів замовлень виконані замовлення позначалися як "completed"
+	err = db.Model(&models.Order{}).
+		Where("status = ?", "completed").
+		Update("status", models.OrderStatusDelivered).Error
+	if err != nil {
+		log.Fatalf("Failed to migrate order statuses: %v", err)
+	}
+
 	log.Println("Database migration completed successfully")
 }
@@ -0,0 +1,569 @@
+// Package ann реалізує наближений пошук найближчих сусідів (Approximate
+// Nearest Neighbour) для векторних представлень товарів.
+//
+// Основна структура - HNSW (Hierarchical Navigable Small World): багаторівневий
+// граф, у якому пошук top-k сусідів виконується за час, близький до логарифмічного
+// від кількості векторів, замість повного перебору O(n).
+//
+// Приклад використання:
+//
+//	index, err := ann.Build(vectors, ann.DefaultConfig())
+//	if err != nil {
+//		return err
+//	}
+//	results := index.Search(query, 10, nil)
+package ann
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// Metric визначає функцію відстані між векторами
+type Metric int
+
+const (
+	// Cosine косинусна відстань (1 - косинусна подібність). Вектори нормалізуються при додаванні.
+	Cosine Metric = iota
+	// Euclidean квадрат евклідової відстані
+	Euclidean
+)
+
+// Config параметри побудови та пошуку HNSW-індексу
+type Config struct {
+	// M максимальна кількість зв'язків вузла на рівнях вище нульового (на нульовому - 2*M)
+	M int
+	// EfConstruction розмір списку кандидатів під час вставки
+	EfConstruction int
+	// EfSearch мінімальний розмір списку кандидатів під час пошуку
+	EfSearch int
+	// Metric функція відстані
+	Metric Metric
+	// Seed початкове значення генератора рівнів; однаковий seed дає однаковий граф
+	Seed int64
+}
+
+// DefaultConfig повертає типові параметри індексу
+func DefaultConfig() Config {
+	return Config{
+		M:              16,
+		EfConstruction: 200,
+		EfSearch:       64,
+		Metric:         Cosine,
+		Seed:           42,
+	}
+}
+
+// Result результат пошуку найближчих сусідів
+type Result struct {
+	ID       uint    `json:"id"`
+	Distance float64 `json:"distance"`
+}
+
+var (
+	// ErrDimensionMismatch повертається, якщо розмірність вектора не збігається з розмірністю індексу
+	ErrDimensionMismatch = errors.New("vector dimension mismatch")
+	// ErrZeroVector повертається при спробі додати нульовий вектор до косинусного індексу
+	ErrZeroVector = errors.New("zero vector cannot be indexed with cosine metric")
+)
+
+type node struct {
+	id        uint
+	vector    []float64
+	level     int
+	neighbors [][]uint
+}
+
+// HNSW потокобезпечний індекс наближеного пошуку найближчих сусідів
+type HNSW struct {
+	mu        sync.RWMutex
+	cfg       Config
+	dim       int
+	nodes     map[uint]*node
+	entry     uint
+	hasEntry  bool
+	maxLevel  int
+	levelMult float64
+	rng       *rand.Rand
+}
+
+// NewHNSW створює порожній індекс для векторів розмірності dim
+func NewHNSW(dim int, cfg Config) *HNSW {
+	def := DefaultConfig()
+	if cfg.M < 2 {
+		cfg.M = def.M
+	}
+	if cfg.EfConstruction <= 0 {
+		cfg.EfConstruction = def.EfConstruction
+	}
+	if cfg.EfSearch <= 0 {
+		cfg.EfSearch = def.EfSearch
+	}
+
+	return &HNSW{
+		cfg:       cfg,
+		dim:       dim,
+		nodes:     make(map[uint]*node),
+		levelMult: 1 / math.Log(float64(cfg.M)),
+		rng:       rand.New(rand.NewSource(cfg.Seed)),
+	}
+}
+
+// Build будує індекс з набору векторів. Вектори вставляються в порядку
+// зростання ID, тому при однаковому Config результат відтворюваний.
+func Build(vectors map[uint][]float64, cfg Config) (*HNSW, error) {
+	ids := make([]uint, 0, len(vectors))
+	dim := 0
+	for id, vec := range vectors {
+		ids = append(ids, id)
+		dim = len(vec)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	index := NewHNSW(dim, cfg)
+	for _, id := range ids {
+		if err := index.Add(id, vectors[id]); err != nil {
+			return nil, fmt.Errorf("add vector %d: %w", id, err)
+		}
+	}
+
+	return index, nil
+}
+
+// Dim повертає розмірність векторів індексу
+func (h *HNSW) Dim() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.dim
+}
+
+// Len повертає кількість векторів в індексі
+func (h *HNSW) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.nodes)
+}
+
+// Vector повертає копію збереженого вектора (для косинусної метрики - нормалізованого)
+func (h *HNSW) Vector(id uint) ([]float64, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	n, ok := h.nodes[id]
+	if !ok {
+		return nil, false
+	}
+	vec := make([]float64, len(n.vector))
+	copy(vec, n.vector)
+	return vec, true
+}
+
+// Add додає вектор до індексу. Якщо ID вже існує, вектор замінюється.
+// Розмірність порожнього індексу визначається першим доданим вектором.
+func (h *HNSW) Add(id uint, vector []float64) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Розмірність порожнього індексу фіксується лише після перевірки вектора
+	dim := h.dim
+	if dim == 0 {
+		dim = len(vector)
+	}
+	vec, err := h.prepare(vector, dim)
+	if err != nil {
+		return err
+	}
+	h.dim = dim
+
+	if _, exists := h.nodes[id]; exists {
+		h.remove(id)
+	}
+	h.insert(id, vec)
+	return nil
+}
+
+// Remove видаляє вектор з індексу. Вузли, що посилалися на видалений, отримують
+// замість нього його сусідів, щоб граф залишався зв'язним. Повертає false, якщо ID не знайдено.
+func (h *HNSW) Remove(id uint) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, exists := h.nodes[id]; !exists {
+		return false
+	}
+	h.remove(id)
+	return true
+}
+
+// Search повертає до k найближчих до query векторів у порядку зростання відстані.
+// Якщо accept не nil, у результат потрапляють лише ID, для яких accept повертає true.
+func (h *HNSW) Search(query []float64, k int, accept func(id uint) bool) []Result {
+	if k <= 0 {
+		return nil
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if !h.hasEntry {
+		return nil
+	}
+	q, err := h.prepare(query, h.dim)
+	if err != nil {
+		return nil
+	}
+
+	ep := h.entry
+	for level := h.maxLevel; level > 0; level-- {
+		ep = h.greedyClosest(q, ep, level)
+	}
+
+	ef := h.cfg.EfSearch
+	if k > ef {
+		ef = k
+	}
+	// Фільтр відкидає частину кандидатів, тому розширюємо пошук
+	if accept != nil {
+		ef *= 2
+	}
+
+	candidates := h.searchLayer(q, []uint{ep}, ef, 0)
+
+	results := make([]Result, 0, k)
+	for _, c := range candidates {
+		if accept != nil && !accept(c.id) {
+			continue
+		}
+		results = append(results, Result{ID: c.id, Distance: c.dist})
+		if len(results) == k {
+			break
+		}
+	}
+
+	return results
+}
+
+// prepare перевіряє розмірність dim і, для косинусної метрики, нормалізує копію вектора
+func (h *HNSW) prepare(vector []float64, dim int) ([]float64, error) {
+	if len(vector) != dim || dim == 0 {
+		return nil, ErrDimensionMismatch
+	}
+
+	vec := make([]float64, len(vector))
+	copy(vec, vector)
+
+	if h.cfg.Metric == Cosine {
+		var norm float64
+		for _, v := range vec {
+			norm += v * v
+		}
+		if norm == 0 {
+			return nil, ErrZeroVector
+		}
+		norm = math.Sqrt(norm)
+		for i := range vec {
+			vec[i] /= norm
+		}
+	}
+
+	return vec, nil
+}
+
+func (h *HNSW) distance(a, b []float64) float64 {
+	switch h.cfg.Metric {
+	case Euclidean:
+		var sum float64
+		for i := range a {
+			d := a[i] - b[i]
+			sum += d * d
+		}
+		return sum
+	default:
+		var dot float64
+		for i := range a {
+			dot += a[i] * b[i]
+		}
+		return 1 - dot
+	}
+}
+
+func (h *HNSW) maxNeighbors(level int) int {
+	if level == 0 {
+		return 2 * h.cfg.M
+	}
+	return h.cfg.M
+}
+
+func (h *HNSW) randomLevel() int {
+	return int(math.Floor(-math.Log(1-h.rng.Float64()) * h.levelMult))
+}
+
+func (h *HNSW) insert(id uint, vec []float64) {
+	level := h.randomLevel()
+	n := &node{
+		id:        id,
+		vector:    vec,
+		level:     level,
+		neighbors: make([][]uint, level+1),
+	}
+	h.nodes[id] = n
+
+	if !h.hasEntry {
+		h.entry = id
+		h.hasEntry = true
+		h.maxLevel = level
+		return
+	}
+
+	ep := h.entry
+	for l := h.maxLevel; l > level; l-- {
+		ep = h.greedyClosest(vec, ep, l)
+	}
+
+	entryPoints := []uint{ep}
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		candidates := h.searchLayer(vec, entryPoints, h.cfg.EfConstruction, l)
+
+		selected := selectClosest(candidates, h.maxNeighbors(l), id)
+		n.neighbors[l] = selected
+
+		for _, nbID := range selected {
+			h.link(nbID, id, l)
+		}
+
+		entryPoints = entryPoints[:0]
+		for _, c := range candidates {
+			entryPoints = append(entryPoints, c.id)
+		}
+	}
+
+	if level > h.maxLevel {
+		h.maxLevel = level
+		h.entry = id
+	}
+}
+
+// link додає зв'язок from -> to на рівні level, обрізаючи список до найближчих сусідів
+func (h *HNSW) link(from, to uint, level int) {
+	nb, ok := h.nodes[from]
+	if !ok || level > nb.level {
+		return
+	}
+	for _, existing := range nb.neighbors[level] {
+		if existing == to {
+			return
+		}
+	}
+
+	nb.neighbors[level] = append(nb.neighbors[level], to)
+	if len(nb.neighbors[level]) <= h.maxNeighbors(level) {
+		return
+	}
+
+	nb.neighbors[level] = h.closestTo(nb, nb.neighbors[level], h.maxNeighbors(level))
+}
+
+// closestTo вибирає з ids до limit вузлів, найближчих до n
+func (h *HNSW) closestTo(n *node, ids []uint, limit int) []uint {
+	scored := make([]candidate, 0, len(ids))
+	for _, id := range ids {
+		other, ok := h.nodes[id]
+		if !ok || id == n.id {
+			continue
+		}
+		scored = append(scored, candidate{id: id, dist: h.distance(n.vector, other.vector)})
+	}
+	sort.Slice(scored, func(i, j int) bool { return scored[i].dist < scored[j].dist })
+	return selectClosest(scored, limit, n.id)
+}
+
+func (h *HNSW) remove(id uint) {
+	n := h.nodes[id]
+	delete(h.nodes, id)
+
+	// Зв'язки несиметричні: посилання на вузол можуть мати й вузли, яких
+	// немає серед його сусідів, тому перевіряємо всі вузли
+	for _, other := range h.nodes {
+		top := min(n.level, other.level)
+		for l := 0; l <= top; l++ {
+			if !slices.Contains(other.neighbors[l], id) {
+				continue
+			}
+
+			// Прибираємо зв'язок на видалений вузол і додаємо кандидатів з його сусідів
+			pool := make([]uint, 0, len(other.neighbors[l])+len(n.neighbors[l]))
+			for _, x := range other.neighbors[l] {
+				if x != id {
+					pool = append(pool, x)
+				}
+			}
+			pool = append(pool, n.neighbors[l]...)
+			other.neighbors[l] = h.closestTo(other, dedup(pool), h.maxNeighbors(l))
+		}
+	}
+
+	if h.entry != id {
+		return
+	}
+
+	// Видалено точку входу - обираємо вузол з найвищим рівнем
+	h.hasEntry = false
+	h.maxLevel = 0
+	for nid, other := range h.nodes {
+		if !h.hasEntry || other.level > h.maxLevel || (other.level == h.maxLevel && nid < h.entry) {
+			h.entry = nid
+			h.maxLevel = other.level
+			h.hasEntry = true
+		}
+	}
+}
+
+// greedyClosest жадібно спускається до найближчого вузла на рівні level
+func (h *HNSW) greedyClosest(q []float64, ep uint, level int) uint {
+	current := ep
+	currentDist := h.distance(q, h.nodes[current].vector)
+
+	for changed := true; changed; {
+		changed = false
+		n := h.nodes[current]
+		if level > n.level {
+			break
+		}
+		for _, nbID := range n.neighbors[level] {
+			nb, ok := h.nodes[nbID]
+			if !ok {
+				continue
+			}
+			if d := h.distance(q, nb.vector); d < currentDist {
+				current, currentDist = nbID, d
+				changed = true
+			}
+		}
+	}
+
+	return current
+}
+
+// searchLayer виконує пошук ef найближчих вузлів на рівні level.
+// Повертає кандидатів у порядку зростання відстані.
+func (h *HNSW) searchLayer(q []float64, entryPoints []uint, ef, level int) []candidate {
+	visited := make(map[uint]struct{}, ef*4)
+	toVisit := &minHeap{}
+	found := &maxHeap{}
+
+	for _, ep := range entryPoints {
+		n, ok := h.nodes[ep]
+		if !ok {
+			continue
+		}
+		if _, seen := visited[ep]; seen {
+			continue
+		}
+		visited[ep] = struct{}{}
+		c := candidate{id: ep, dist: h.distance(q, n.vector)}
+		heap.Push(toVisit, c)
+		heap.Push(found, c)
+	}
+
+	for toVisit.Len() > 0 {
+		c := heap.Pop(toVisit).(candidate)
+		if found.Len() >= ef && c.dist > (*found)[0].dist {
+			break
+		}
+
+		n := h.nodes[c.id]
+		if level > n.level {
+			continue
+		}
+		for _, nbID := range n.neighbors[level] {
+			if _, seen := visited[nbID]; seen {
+				continue
+			}
+			visited[nbID] = struct{}{}
+
+			nb, ok := h.nodes[nbID]
+			if !ok {
+				continue
+			}
+			d := h.distance(q, nb.vector)
+			if found.Len() < ef || d < (*found)[0].dist {
+				heap.Push(toVisit, candidate{id: nbID, dist: d})
+				heap.Push(found, candidate{id: nbID, dist: d})
+				if found.Len() > ef {
+					heap.Pop(found)
+				}
+			}
+		}
+	}
+
+	result := make([]candidate, found.Len())
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i] = heap.Pop(found).(candidate)
+	}
+	return result
+}
+
+// selectClosest бере перші limit кандидатів (вже відсортованих), пропускаючи self
+func selectClosest(sorted []candidate, limit int, self uint) []uint {
+	selected := make([]uint, 0, limit)
+	for _, c := range sorted {
+		if c.id == self {
+			continue
+		}
+		selected = append(selected, c.id)
+		if len(selected) == limit {
+			break
+		}
+	}
+	return selected
+}
+
+func dedup(ids []uint) []uint {
+	seen := make(map[uint]struct{}, len(ids))
+	out := ids[:0]
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		out = append(out, id)
+	}
+	return out
+}
+
+type candidate struct {
+	id   uint
+	dist float64
+}
+
+type minHeap []candidate
+
+func (h minHeap) Len() int           { return len(h) }
+func (h minHeap) Less(i, j int) bool { return h[i].dist < h[j].dist }
+func (h minHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *minHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+type maxHeap []candidate
+
+func (h maxHeap) Len() int           { return len(h) }
+func (h maxHeap) Less(i, j int) bool { return h[i].dist > h[j].dist }
+func (h maxHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *maxHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
@@ -0,0 +1,336 @@
+package ann
+
+import (
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// randomVectors генерує n випадкових векторів розмірності dim з ID 1..n
+func randomVectors(n, dim int, seed int64) map[uint][]float64 {
+	rng := rand.New(rand.NewSource(seed))
+	vectors := make(map[uint][]float64, n)
+	for id := 1; id <= n; id++ {
+		vec := make([]float64, dim)
+		for i := range vec {
+			vec[i] = rng.NormFloat64()
+		}
+		vectors[uint(id)] = vec
+	}
+	return vectors
+}
+
+// exactNearest повертає k найближчих до query ID повним перебором
+func exactNearest(h *HNSW, vectors map[uint][]float64, query []float64, k int) []uint {
+	q, err := h.prepare(query, h.dim)
+	if err != nil {
+		panic(err)
+	}
+
+	type scored struct {
+		id   uint
+		dist float64
+	}
+	all := make([]scored, 0, len(vectors))
+	for id := range vectors {
+		vec, _ := h.Vector(id)
+		all = append(all, scored{id, h.distance(q, vec)})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].dist < all[j].dist })
+
+	ids := make([]uint, 0, k)
+	for _, s := range all[:k] {
+		ids = append(ids, s.id)
+	}
+	return ids
+}
+
+func resultIDs(results []Result) []uint {
+	ids := make([]uint, len(results))
+	for i, r := range results {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+func TestSearchRecall(t *testing.T) {
+	const (
+		n       = 2000
+		dim     = 16
+		k       = 10
+		queries = 50
+	)
+
+	for _, metric := range []Metric{Cosine, Euclidean} {
+		cfg := DefaultConfig()
+		cfg.Metric = metric
+		vectors := randomVectors(n, dim, 1)
+
+		index, err := Build(vectors, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if index.Len() != n {
+			t.Fatalf("Len() = %d, want %d", index.Len(), n)
+		}
+
+		queryVectors := randomVectors(queries, dim, 2)
+		var found int
+		for _, query := range queryVectors {
+			want := make(map[uint]bool, k)
+			for _, id := range exactNearest(index, vectors, query, k) {
+				want[id] = true
+			}
+
+			results := index.Search(query, k, nil)
+			if len(results) != k {
+				t.Fatalf("metric %d: got %d results, want %d", metric, len(results), k)
+			}
+			for i := 1; i < len(results); i++ {
+				if results[i].Distance < results[i-1].Distance {
+					t.Fatalf("metric %d: results are not sorted by distance", metric)
+				}
+			}
+			for _, r := range results {
+				if want[r.ID] {
+					found++
+				}
+			}
+		}
+
+		if recall := float64(found) / float64(queries*k); recall < 0.95 {
+			t.Errorf("metric %d: recall@%d = %.3f, want at least 0.95", metric, k, recall)
+		}
+	}
+}
+
+func TestSearchAccept(t *testing.T) {
+	vectors := randomVectors(500, 8, 3)
+	index, err := Build(vectors, DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := index.Search(vectors[1], 10, func(id uint) bool { return id%2 == 0 })
+	if len(results) != 10 {
+		t.Fatalf("got %d results, want 10", len(results))
+	}
+	for _, r := range results {
+		if r.ID%2 != 0 {
+			t.Errorf("rejected ID %d returned", r.ID)
+		}
+	}
+}
+
+func TestAddReplacesAndRemove(t *testing.T) {
+	index := NewHNSW(0, DefaultConfig())
+
+	if err := index.Add(1, []float64{1, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Add(2, []float64{0, 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Add(3, []float64{1, 0, 0}); err != ErrDimensionMismatch {
+		t.Errorf("Add with wrong dimension: err = %v, want %v", err, ErrDimensionMismatch)
+	}
+	if err := index.Add(3, []float64{0, 0}); err != ErrZeroVector {
+		t.Errorf("Add zero vector: err = %v, want %v", err, ErrZeroVector)
+	}
+
+	// Повторне додавання замінює вектор
+	if err := index.Add(1, []float64{0, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if index.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", index.Len())
+	}
+	if vec, _ := index.Vector(1); !reflect.DeepEqual(vec, []float64{0, 1}) {
+		t.Errorf("Vector(1) = %v, want normalized replacement [0 1]", vec)
+	}
+
+	if !index.Remove(1) {
+		t.Error("Remove(1) = false, want true")
+	}
+	if index.Remove(1) {
+		t.Error("second Remove(1) = true, want false")
+	}
+	if got := resultIDs(index.Search([]float64{0, 1}, 5, nil)); !reflect.DeepEqual(got, []uint{2}) {
+		t.Errorf("Search after Remove = %v, want [2]", got)
+	}
+
+	if !index.Remove(2) {
+		t.Error("Remove(2) = false, want true")
+	}
+	if results := index.Search([]float64{0, 1}, 5, nil); len(results) != 0 {
+		t.Errorf("Search on empty index = %v, want none", results)
+	}
+}
+
+func TestRemoveKeepsGraphConnected(t *testing.T) {
+	vectors := randomVectors(1000, 8, 4)
+	index, err := Build(vectors, DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Видаляємо половину вузлів, зокрема точку входу
+	for id := uint(1); id <= 500; id++ {
+		index.Remove(id)
+		delete(vectors, id)
+	}
+
+	for id, vec := range vectors {
+		results := index.Search(vec, 1, nil)
+		if len(results) == 0 || results[0].ID != id {
+			t.Fatalf("vector %d is not reachable after removals: %v", id, resultIDs(results))
+		}
+	}
+}
+
+func TestAddRejectsFirstInvalidVector(t *testing.T) {
+	index := NewHNSW(0, DefaultConfig())
+
+	// Відхилений вектор не фіксує розмірність порожнього індексу
+	if err := index.Add(1, []float64{0, 0, 0}); err != ErrZeroVector {
+		t.Errorf("Add zero vector: err = %v, want %v", err, ErrZeroVector)
+	}
+	if err := index.Add(1, nil); err != ErrDimensionMismatch {
+		t.Errorf("Add empty vector: err = %v, want %v", err, ErrDimensionMismatch)
+	}
+	if index.Dim() != 0 {
+		t.Fatalf("Dim() = %d after rejected vectors, want 0", index.Dim())
+	}
+
+	if err := index.Add(1, []float64{1, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if index.Dim() != 2 || index.Len() != 1 {
+		t.Errorf("Dim/Len = %d/%d, want 2/1", index.Dim(), index.Len())
+	}
+}
+
+func TestRemoveRepairsInboundLinks(t *testing.T) {
+	vectors := randomVectors(500, 8, 7)
+	index, err := Build(vectors, DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Шукаємо вузол, на який посилаються вузли поза його списком сусідів
+	var target uint
+	for id, n := range index.nodes {
+		for _, other := range index.nodes {
+			if slices.Contains(other.neighbors[0], id) && !slices.Contains(n.neighbors[0], other.id) {
+				target = id
+			}
+		}
+		if target != 0 {
+			break
+		}
+	}
+	if target == 0 {
+		t.Fatal("no node with one-way inbound links")
+	}
+
+	index.Remove(target)
+	delete(vectors, target)
+
+	for _, n := range index.nodes {
+		for l, neighbors := range n.neighbors {
+			for _, nb := range neighbors {
+				if _, ok := index.nodes[nb]; !ok {
+					t.Fatalf("node %d links to removed node %d on level %d", n.id, nb, l)
+				}
+			}
+		}
+	}
+	for id, vec := range vectors {
+		results := index.Search(vec, 1, nil)
+		if len(results) == 0 || results[0].ID != id {
+			t.Fatalf("vector %d is not reachable after removal: %v", id, resultIDs(results))
+		}
+	}
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	vectors := randomVectors(300, 8, 5)
+	index, err := Build(vectors, DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	index.Remove(7)
+
+	path := filepath.Join(t.TempDir(), "index.gob")
+	if err := index.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Len() != index.Len() || loaded.Dim() != index.Dim() {
+		t.Fatalf("loaded Len/Dim = %d/%d, want %d/%d", loaded.Len(), loaded.Dim(), index.Len(), index.Dim())
+	}
+	for _, query := range randomVectors(20, 8, 6) {
+		want := index.Search(query, 10, nil)
+		if got := loaded.Search(query, 10, nil); !reflect.DeepEqual(got, want) {
+			t.Fatalf("loaded index search = %v, want %v", resultIDs(got), resultIDs(want))
+		}
+	}
+
+	// Завантажений індекс приймає нові вектори
+	if err := loaded.Add(1000, vectors[1]); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loaded.Vector(1000); !ok {
+		t.Error("vector added after Load is missing")
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.gob")); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+}
+
+// TestSaveConcurrentWithUpdates перевіряє під -race, що Save не читає списки
+// сусідів, які змінюють паралельні Add і Remove
+func TestSaveConcurrentWithUpdates(t *testing.T) {
+	vectors := randomVectors(500, 8, 7)
+	index, err := Build(vectors, DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		extra := randomVectors(200, 8, 8)
+		for id := uint(1); id <= 200; id++ {
+			if err := index.Add(id+1000, extra[id]); err != nil {
+				t.Error(err)
+				return
+			}
+			index.Remove(id)
+		}
+	}()
+
+	for i := 0; i < 10; i++ {
+		path := filepath.Join(dir, "index.gob")
+		if err := index.Save(path); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err != nil {
+			t.Fatalf("snapshot saved during updates cannot be loaded: %v", err)
+		}
+	}
+	wg.Wait()
+}
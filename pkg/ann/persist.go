@@ -0,0 +1,125 @@
+package ann
+
+import (
+	"encoding/gob"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// snapshotVersion версія формату файлу індексу
+const snapshotVersion = 1
+
+type nodeSnapshot struct {
+	ID        uint
+	Vector    []float64
+	Level     int
+	Neighbors [][]uint
+}
+
+type indexSnapshot struct {
+	Version  int
+	Config   Config
+	Dim      int
+	Entry    uint
+	HasEntry bool
+	MaxLevel int
+	Nodes    []nodeSnapshot
+}
+
+// Save зберігає індекс у файл на локальному диску. Запис виконується
+// у тимчасовий файл з подальшим перейменуванням, тому читачі ніколи
+// не побачать частково записаний знімок.
+func (h *HNSW) Save(path string) error {
+	h.mu.RLock()
+	snapshot := indexSnapshot{
+		Version:  snapshotVersion,
+		Config:   h.cfg,
+		Dim:      h.dim,
+		Entry:    h.entry,
+		HasEntry: h.hasEntry,
+		MaxLevel: h.maxLevel,
+		Nodes:    make([]nodeSnapshot, 0, len(h.nodes)),
+	}
+	for _, n := range h.nodes {
+		// Списки сусідів змінюються на місці під час Add і Remove, тому копіюються
+		// під блокуванням; вектор вузла після вставки не змінюється
+		neighbors := make([][]uint, len(n.neighbors))
+		for l, ids := range n.neighbors {
+			neighbors[l] = append([]uint(nil), ids...)
+		}
+		snapshot.Nodes = append(snapshot.Nodes, nodeSnapshot{
+			ID:        n.id,
+			Vector:    n.vector,
+			Level:     n.level,
+			Neighbors: neighbors,
+		})
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(snapshot.Nodes, func(i, j int) bool { return snapshot.Nodes[i].ID < snapshot.Nodes[j].ID })
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(&snapshot); err != nil {
+		tmp.Close()
+		return fmt.Errorf("encode index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load завантажує індекс, збережений методом Save
+func Load(path string) (*HNSW, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var snapshot indexSnapshot
+	if err := gob.NewDecoder(f).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("decode index: %w", err)
+	}
+	if snapshot.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported index version %d", snapshot.Version)
+	}
+
+	h := NewHNSW(snapshot.Dim, snapshot.Config)
+	h.entry = snapshot.Entry
+	h.hasEntry = snapshot.HasEntry
+	h.maxLevel = snapshot.MaxLevel
+	// Генератор рівнів продовжує роботу з новим зсувом, щоб подальші
+	// вставки після завантаження були відтворюваними
+	h.rng = rand.New(rand.NewSource(snapshot.Config.Seed + int64(len(snapshot.Nodes))))
+
+	for _, ns := range snapshot.Nodes {
+		neighbors := ns.Neighbors
+		if len(neighbors) < ns.Level+1 {
+			neighbors = append(neighbors, make([][]uint, ns.Level+1-len(neighbors))...)
+		}
+		h.nodes[ns.ID] = &node{
+			id:        ns.ID,
+			vector:    ns.Vector,
+			level:     ns.Level,
+			neighbors: neighbors,
+		}
+	}
+
+	if h.hasEntry {
+		if _, ok := h.nodes[h.entry]; !ok {
+			return nil, fmt.Errorf("index entry point %d is missing", h.entry)
+		}
+	}
+
+	return h, nil
+}
@@ -0,0 +1,118 @@
+package recommendation
+
+import (
+	"log"
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/pkg/recommendation/snapshot"
+)
+
+// RecommendForCart рекомендує товари, що доповнюють кошик ("разом з цим купують").
+// coPurchases - кількість замовлень, у яких товар купували разом з товарами кошика.
+// Як і в RecommendProductsWithOptions, стратегії застосовуються ланцюжком: спільні
+// покупки та навчена модель, векторна близькість до товарів кошика, товари тих
+// самих категорій, популярні товари. Товари кошика не рекомендуються.
+func RecommendForCart(cartProductIDs []uint, coPurchases map[uint]int, allProducts []*models.Product, limit int, opts Options) ([]*models.Product, []float64) {
+	if limit <= 0 || len(cartProductIDs) == 0 {
+		return nil, nil
+	}
+
+	products := indexProducts(allProducts)
+	cf := newSeedFilter(cartProductIDs, opts.Filter)
+
+	// 1. Товари, які купували разом з товарами кошика, та сусіди й наслідки правил моделі
+	recommendations, scores := getCoPurchaseRecommendations(cartProductIDs, coPurchases, products, limit, opts.Model, cf)
+	log.Println("Count of cart co-purchase records: ", len(recommendations))
+
+	// 2. Товари, найближчі до середнього вектора кошика
+	if len(recommendations) == 0 && opts.Index != nil {
+		if profile := centroid(opts.Index, cartProductIDs); profile != nil {
+			results := opts.Index.Search(profile, limit, func(id uint) bool {
+				product := products[id]
+				return product != nil && cf.accept(product)
+			})
+			recommendations, scores = resolveResults(results, products)
+		}
+		log.Println("Count of cart vector records: ", len(recommendations))
+	}
+
+	// 3. Товари з категорій кошика
+	if len(recommendations) == 0 {
+		recommendations, scores = getCategoryRecommendations(cartProductIDs, products, allProducts, limit, modelPopularity(opts.Model), cf)
+		log.Println("Count of cart category records: ", len(recommendations))
+	}
+
+	// 4. Популярні товари за навченою моделлю
+	if len(recommendations) == 0 && opts.Model != nil {
+		recommendations, scores = rankCandidates(opts.Model.Popularity, products, limit, cf)
+		log.Println("Count of cart popular records: ", len(recommendations))
+	}
+
+	return recommendations, scores
+}
+
+// getCoPurchaseRecommendations оцінює товари за кількістю спільних з кошиком замовлень
+// і, якщо вказана модель, за подібністю та асоціативними правилами товарів кошика
+func getCoPurchaseRecommendations(cartProductIDs []uint, coPurchases map[uint]int, products map[uint]*models.Product, limit int, model *snapshot.Snapshot, cf *candidateFilter) ([]*models.Product, []float64) {
+	candidateScores := make(map[uint]float64, len(coPurchases))
+	for pid, count := range coPurchases {
+		candidateScores[pid] += float64(count)
+	}
+
+	if model != nil {
+		for _, seedID := range cartProductIDs {
+			for _, neighbor := range model.ItemSimilarity[seedID] {
+				candidateScores[neighbor.ProductID] += neighbor.Score
+			}
+			for _, rule := range model.RulesFor(seedID) {
+				candidateScores[rule.Consequent] += rule.Confidence
+			}
+		}
+	}
+
+	return rankCandidates(candidateScores, products, limit, cf)
+}
+
+// getCategoryRecommendations оцінює товари категорій товарів-зразків (кошика або
+// товару, до якого шукаються схожі): вага категорії - кількість зразків у ній;
+// популярність (якщо відома) додає менше одиниці і лише впорядковує товари
+// всередині категорії
+func getCategoryRecommendations(seedIDs []uint, products map[uint]*models.Product, allProducts []*models.Product, limit int, popularity map[uint]float64, cf *candidateFilter) ([]*models.Product, []float64) {
+	categoryWeights := make(map[string]float64)
+	for _, pid := range seedIDs {
+		if product := products[pid]; product != nil {
+			categoryWeights[product.Category]++
+		}
+	}
+
+	if len(categoryWeights) == 0 {
+		return nil, nil
+	}
+
+	productScores := make([]scoredProduct, 0, len(allProducts))
+	for _, product := range allProducts {
+		score := categoryWeights[product.Category]
+		if score <= 0 || !cf.accept(product) {
+			continue
+		}
+		if p := popularity[product.ID]; p > 0 {
+			score += p / (1 + p)
+		}
+		productScores = append(productScores, scoredProduct{product, score})
+	}
+
+	return splitScored(topK(productScores, limit))
+}
+
+// rankCandidates відбирає limit найкращих товарів каталогу з оцінками candidateScores
+func rankCandidates(candidateScores map[uint]float64, products map[uint]*models.Product, limit int, cf *candidateFilter) ([]*models.Product, []float64) {
+	productScores := make([]scoredProduct, 0, len(candidateScores))
+	for pid, score := range candidateScores {
+		product := products[pid]
+		if product == nil || score <= 0 || !cf.accept(product) {
+			continue
+		}
+		productScores = append(productScores, scoredProduct{product, score})
+	}
+
+	return splitScored(topK(productScores, limit))
+}
@@ -2,31 +2,131 @@
 package recommendation
 
 import (
+	"hash/fnv"
 	"log"
-	"math"
 	"math/rand"
 	"product-recommendations-go/internal/models"
+	"product-recommendations-go/pkg/recommendation/snapshot"
 	"sort"
+	"strconv"
 	"time"
 )
 
+// Options налаштовує генерацію рекомендацій
+type Options struct {
+	// Source джерело випадковості для резервної стратегії випадкових рекомендацій.
+	// Якщо не вказане, використовується NewDailySource(userID, time.Now()).
+	Source rand.Source
+
+	// Index індекс векторних представлень товарів. Якщо вказаний, після
+	// колаборативної фільтрації застосовується пошук найближчих сусідів
+	// до векторного профілю користувача.
+	Index VectorIndex
+
+	// Filter обмежує множину товарів-кандидатів (категорія, ціна, виключені ID)
+	Filter models.RecommendationFilter
+
+	// Model навчена офлайн модель. Якщо вказана, використовуються її матриця
+	// подібності товарів, асоціативні правила та глобальна таблиця популярності.
+	Model *snapshot.Snapshot
+
+	// RepurchaseIntervals типові інтервали повторних покупок товарів. Якщо вказані,
+	// на початку видачі пропонуються витратні товари, які користувачу час купити знову.
+	RepurchaseIntervals snapshot.RepurchaseIntervals
+
+	// Now поточний час для стратегії поповнення. Якщо не вказаний, використовується time.Now().
+	Now time.Time
+}
+
+// NewDailySource створює джерело випадковості, детерміноване для пари
+// (користувач, календарний день у UTC). Протягом доби користувач бачить
+// однакову добірку, а тести можуть відтворити результат, передавши фіксовану дату.
+func NewDailySource(userID uint, day time.Time) rand.Source {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(strconv.FormatUint(uint64(userID), 10)))
+	_, _ = h.Write([]byte(day.UTC().Format("2006-01-02")))
+	return rand.NewSource(int64(h.Sum64()))
+}
+
 // RecommendProducts генерує рекомендації на основі гібридного підходу
 func RecommendProducts(userID uint, likes []*models.UserLike, orders []*models.Order, allProducts []*models.Product, limit int) ([]*models.Product, []float64) {
+	return RecommendProductsWithOptions(userID, likes, orders, allProducts, limit, Options{})
+}
+
+// RecommendProductsWithOptions генерує рекомендації на основі гібридного підходу
+// з додатковими налаштуваннями (наприклад, власним джерелом випадковості)
+func RecommendProductsWithOptions(userID uint, likes []*models.UserLike, orders []*models.Order, allProducts []*models.Product, limit int, opts Options) ([]*models.Product, []float64) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	if opts.Source == nil {
+		opts.Source = NewDailySource(userID, opts.Now)
+	}
+
+	// Індекс взаємодій будується один раз і використовується всіма стратегіями
+	idx := newInteractionIndex(userID, likes, orders, allProducts)
+
+	// Фільтр застосовується під час відбору кандидатів кожною стратегією,
+	// тому обмеження limit не зменшує кількість відфільтрованих результатів
+	cf := newCandidateFilter(idx, opts.Filter)
+
+	// 0. Витратні товари, які користувачу час купити знову, займають початок видачі
+	replenishRecs, replenishScores := getReplenishmentRecommendations(idx, orders, replenishmentLimit(limit), opts.RepurchaseIntervals, opts.Now, cf)
+	for _, product := range replenishRecs {
+		// Решта стратегій не повинна повторювати ці товари (актуально з IncludePurchased)
+		cf.excluded[product.ID] = true
+	}
+
+	log.Println("Count of replenishment records: ", len(replenishRecs))
+
+	recommendations, scores := discoverProducts(idx, allProducts, limit-len(replenishRecs), opts, cf)
+	recommendations = append(replenishRecs, recommendations...)
+	scores = append(replenishScores, scores...)
+
+	log.Printf("Final recommendations count: %d, scores count: %d", len(recommendations), len(scores))
+	return recommendations, scores
+}
+
+// discoverProducts застосовує ланцюжок стратегій пошуку нових для користувача товарів:
+// кожна наступна стратегія використовується, лише якщо попередні нічого не знайшли
+func discoverProducts(idx *interactionIndex, allProducts []*models.Product, limit int, opts Options, cf *candidateFilter) ([]*models.Product, []float64) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
 	// Ініціалізуємо рекомендації
 	var recommendations []*models.Product
 	var scores []float64
 
 	// 1. Спочатку спробуємо колаборативну фільтрацію
-	collaborativeRecs, collaborativeScores := getCollaborativeRecommendations(userID, likes, orders, allProducts, limit)
+	collaborativeRecs, collaborativeScores := getCollaborativeRecommendations(idx, limit, cf)
 	recommendations = append(recommendations, collaborativeRecs...)
 	scores = append(scores, collaborativeScores...)
 
 	log.Println("Count of collab records: ", len(recommendations))
 
+	// Якщо завантажена навчена модель, використовуємо подібність товарів і асоціативні правила
+	if len(recommendations) == 0 && opts.Model != nil {
+		modelRecs, modelScores := getModelRecommendations(idx, limit, opts.Model, cf)
+		recommendations = append(recommendations, modelRecs...)
+		scores = append(scores, modelScores...)
+
+		log.Println("Count of model records: ", len(recommendations))
+	}
+
+	// Якщо доступні векторні представлення, шукаємо найближчі товари до профілю користувача
+	if len(recommendations) == 0 && opts.Index != nil {
+		vectorRecs, vectorScores := getVectorRecommendations(idx, limit, opts.Index, cf)
+		recommendations = append(recommendations, vectorRecs...)
+		scores = append(scores, vectorScores...)
+
+		log.Println("Count of vector records: ", len(recommendations))
+	}
+
 	// Якщо колаборативна фільтрація не дала результатів, використовуємо контентну фільтрацію
 	if len(recommendations) == 0 {
 		log.Printf("No collaborative recommendations found, trying content-based recommendations")
-		contentRecs, contentScores := getContentBasedRecommendations(userID, likes, orders, allProducts, limit)
+		contentRecs, contentScores := getContentBasedRecommendations(idx, allProducts, limit, cf)
 		recommendations = append(recommendations, contentRecs...)
 		scores = append(scores, contentScores...)
 	}
@@ -36,7 +136,7 @@ func RecommendProducts(userID uint, likes []*models.UserLike, orders []*models.O
 	// Якщо контентна фільтрація не дала результатів, використовуємо популярні товари
 	if len(recommendations) == 0 {
 		log.Printf("No content-based recommendations found, trying popularity-based recommendations")
-		popularRecs, popularScores := getPopularityBasedRecommendations(userID, likes, orders, allProducts, limit)
+		popularRecs, popularScores := getPopularityBasedRecommendations(idx, limit, modelPopularity(opts.Model), cf)
 		recommendations = append(recommendations, popularRecs...)
 		scores = append(scores, popularScores...)
 	}
@@ -46,171 +146,98 @@ func RecommendProducts(userID uint, likes []*models.UserLike, orders []*models.O
 	// Якщо все ще немає рекомендацій, використовуємо випадкові товари
 	if len(recommendations) == 0 {
 		log.Printf("No popularity-based recommendations found, using random recommendations")
-		randomRecs, randomScores := getRandomRecommendations(userID, likes, orders, allProducts, limit)
+		randomRecs, randomScores := getRandomRecommendations(allProducts, limit, rand.New(opts.Source), cf)
 		recommendations = append(recommendations, randomRecs...)
 		scores = append(scores, randomScores...)
 	}
 
 	log.Println("Count of rand: ", len(recommendations))
 
-	log.Printf("Final recommendations count: %d, scores count: %d", len(recommendations), len(scores))
 	return recommendations, scores
 }
 
 // getCollaborativeRecommendations використовує колаборативну фільтрацію
-func getCollaborativeRecommendations(userID uint, likes []*models.UserLike, orders []*models.Order, allProducts []*models.Product, limit int) ([]*models.Product, []float64) {
-	var recommendations []*models.Product
-	var scores []float64
-
-	// Створення мапи продуктів, які користувач вже лайкнув або купив
-	userProductMap := make(map[uint]bool)
-
-	// Додавання лайкнутих продуктів
-	for _, like := range likes {
-		if like.UserID == userID {
-			userProductMap[like.ProductID] = true
-		}
-	}
-
-	// Додавання куплених продуктів
-	for _, order := range orders {
-		if order.UserID == userID {
-			for _, item := range order.Items {
-				userProductMap[item.ProductID] = true
-			}
-		}
-	}
-
-	// Знаходження подібних користувачів
+func getCollaborativeRecommendations(idx *interactionIndex, limit int, cf *candidateFilter) ([]*models.Product, []float64) {
+	// Знаходження подібних користувачів: кількість спільних товарів, які вони лайкнули
 	userSimilarity := make(map[uint]float64)
-
-	for _, like := range likes {
-		if like.UserID != userID && userProductMap[like.ProductID] {
+	for _, like := range idx.likes {
+		if like.UserID != idx.userID && idx.interacted[like.ProductID] {
 			userSimilarity[like.UserID] += 1.0
 		}
 	}
 
+	if len(userSimilarity) == 0 {
+		return nil, nil
+	}
+
 	// Сортування користувачів за подібністю
 	type UserSim struct {
 		UserID     uint
 		Similarity float64
 	}
 
-	var userSims []UserSim
+	userSims := make([]UserSim, 0, len(userSimilarity))
 	for uid, sim := range userSimilarity {
 		userSims = append(userSims, UserSim{uid, sim})
 	}
 
 	sort.Slice(userSims, func(i, j int) bool {
-		return userSims[i].Similarity > userSims[j].Similarity
+		if userSims[i].Similarity != userSims[j].Similarity {
+			return userSims[i].Similarity > userSims[j].Similarity
+		}
+		return userSims[i].UserID < userSims[j].UserID
 	})
 
-	// Рекомендації на основі подібності
-	recommendationScores := make(map[uint]float64)
-
 	// Обмежуємо кількість подібних користувачів
 	maxUsers := 10
 	if len(userSims) < maxUsers {
 		maxUsers = len(userSims)
 	}
 
-	// Додаємо продукти, які лайкнули схожі користувачі
-	for i := 0; i < maxUsers; i++ {
-		if i >= len(userSims) {
-			break
-		}
-
-		similarUserID := userSims[i].UserID
-		similarityScore := userSims[i].Similarity
-
-		for _, like := range likes {
-			if like.UserID == similarUserID && !userProductMap[like.ProductID] {
-				recommendationScores[like.ProductID] += similarityScore
-			}
-		}
-	}
-
-	// Сортування продуктів за рейтингом рекомендації
-	type ProductScore struct {
-		Product *models.Product
-		Score   float64
+	similarUsers := make(map[uint]float64, maxUsers)
+	for _, us := range userSims[:maxUsers] {
+		similarUsers[us.UserID] = us.Similarity
 	}
 
-	var productScores []ProductScore
-	for pid, score := range recommendationScores {
-		for _, product := range allProducts {
-			if product.ID == pid {
-				productScores = append(productScores, ProductScore{product, score})
-				break
-			}
+	// Додаємо продукти, які лайкнули схожі користувачі
+	recommendationScores := make(map[uint]float64)
+	for _, like := range idx.likes {
+		if similarity, ok := similarUsers[like.UserID]; ok && !cf.excluded[like.ProductID] {
+			recommendationScores[like.ProductID] += similarity
 		}
 	}
 
-	sort.Slice(productScores, func(i, j int) bool {
-		return productScores[i].Score > productScores[j].Score
-	})
-
-	// Додаємо рекомендації з колаборативної фільтрації
-	for _, ps := range productScores {
-		recommendations = append(recommendations, ps.Product)
-		scores = append(scores, ps.Score)
-
-		if len(recommendations) >= limit {
-			break
+	productScores := make([]scoredProduct, 0, len(recommendationScores))
+	for pid, score := range recommendationScores {
+		product := idx.products[pid]
+		if product != nil && cf.accept(product) {
+			productScores = append(productScores, scoredProduct{product, score})
 		}
 	}
 
-	return recommendations, scores
+	return splitScored(topK(productScores, limit))
 }
 
-// getContentBasedRecommendations використовує контентну фільтрацію на основі категорій
-func getContentBasedRecommendations(userID uint, likes []*models.UserLike, orders []*models.Order, allProducts []*models.Product, limit int) ([]*models.Product, []float64) {
-	var recommendations []*models.Product
-	var scores []float64
+// priceAffinityWeight вага відповідності ціни товару ціновому профілю користувача
+const priceAffinityWeight = 1.0
 
-	// Створення мапи продуктів, які користувач вже лайкнув або купив
-	userProductMap := make(map[uint]bool)
+// getContentBasedRecommendations використовує контентну фільтрацію на основі категорій
+// з урахуванням цінового профілю користувача
+func getContentBasedRecommendations(idx *interactionIndex, allProducts []*models.Product, limit int, cf *candidateFilter) ([]*models.Product, []float64) {
 	categoryPreferences := make(map[string]float64)
 
-	// Збираємо ціни лайкнутих продуктів для розрахунку подібності
-	var likedProductPrices []float64
-	var sumLikedPrices float64
-
-	// Додавання лайкнутих продуктів і визначення вподобань за категоріями
-	for _, like := range likes {
-		if like.UserID == userID {
-			userProductMap[like.ProductID] = true
-
-			// Знаходимо продукт, щоб отримати його категорію та ціну
-			for _, product := range allProducts {
-				if product.ID == like.ProductID {
-					categoryPreferences[product.Category] += 1.0
-					likedProductPrices = append(likedProductPrices, product.Price)
-					sumLikedPrices += product.Price
-					break
-				}
-			}
+	addPreference := func(productID uint, weight float64) {
+		if product := idx.products[productID]; product != nil {
+			categoryPreferences[product.Category] += weight
 		}
 	}
 
-	// Додавання куплених продуктів і оновлення переваг категорій
-	for _, order := range orders {
-		if order.UserID == userID {
-			for _, item := range order.Items {
-				userProductMap[item.ProductID] = true
-
-				// Знаходимо продукт, щоб отримати його категорію
-				for _, product := range allProducts {
-					if product.ID == item.ProductID {
-						// Покупки мають більшу вагу, ніж лайки
-						categoryPreferences[product.Category] += 2.0
-						likedProductPrices = append(likedProductPrices, product.Price)
-						sumLikedPrices += product.Price
-						break
-					}
-				}
-			}
-		}
+	// Лайки визначають вподобання за категоріями, покупки мають більшу вагу
+	for _, pid := range idx.userLiked {
+		addPreference(pid, 1.0)
+	}
+	for _, pid := range idx.userPurchased {
+		addPreference(pid, 2.0)
 	}
 
 	// Якщо немає переваг за категоріями, повертаємо пустий список
@@ -218,113 +245,46 @@ func getContentBasedRecommendations(userID uint, likes []*models.UserLike, order
 		return nil, nil
 	}
 
-	// Рахуємо глобальну популярність товарів
-	productPopularity := make(map[uint]int)
-	for _, like := range likes {
-		productPopularity[like.ProductID]++
-	}
+	profile := newPriceProfile(idx)
+	now := time.Now()
 
 	// Оцінюємо продукти на основі переваг категорій
-	type ProductScore struct {
-		Product *models.Product
-		Score   float64
-	}
-
-	var productScores []ProductScore
-
+	productScores := make([]scoredProduct, 0, len(allProducts))
 	for _, product := range allProducts {
-		if userProductMap[product.ID] {
-			continue
-		}
-
 		// Базовий рейтинг на основі категорії
 		score := categoryPreferences[product.Category]
+		if score <= 0 || !cf.accept(product) {
+			continue
+		}
 
-		if score > 0 {
-			// Детальне логування базового рейтингу
-			log.Printf("Product %d (%s): Base category score: %.2f",
-				product.ID, product.Name, score)
-
-			// 1. Зменшуємо вплив новизни
-			daysSinceCreation := time.Since(product.CreatedAt).Hours() / 24
-			recencyBonus := 1.0 / (1.0 + daysSinceCreation/30) * 0.2 // Зменшено вагу
-			score += recencyBonus
-
-			log.Printf("  + Recency bonus: %.2f", recencyBonus)
-
-			// 2. Враховуємо популярність товару (збільшена вага)
-			popularity := float64(productPopularity[product.ID]) * 0.3
-			score += popularity
-
-			log.Printf("  + Popularity bonus: %.2f (%d likes)",
-				popularity, productPopularity[product.ID])
-
-			// 3. Додаємо схожість за ціною
-			if len(likedProductPrices) > 0 {
-				avgLikedPrice := sumLikedPrices / float64(len(likedProductPrices))
-				priceDiff := math.Abs(product.Price - avgLikedPrice)
-				priceSimilarityFactor := math.Max(0, 1.0-priceDiff/avgLikedPrice/2)
-				score += priceSimilarityFactor * 0.3
-
-				log.Printf("  + Price similarity bonus: %.2f (product: %.2f, avg liked: %.2f)",
-					priceSimilarityFactor*0.3, product.Price, avgLikedPrice)
-			}
+		// 1. Зменшуємо вплив новизни
+		daysSinceCreation := now.Sub(product.CreatedAt).Hours() / 24
+		score += 1.0 / (1.0 + daysSinceCreation/30) * 0.2
 
-			log.Printf("  = Final score: %.2f", score)
+		// 2. Враховуємо популярність товару (збільшена вага)
+		score += float64(idx.likeCounts[product.ID]) * 0.3
 
-			productScores = append(productScores, ProductScore{product, score})
+		// 3. Додаємо відповідність ціни звичному для користувача рівню цін
+		if profile != nil {
+			score += profile.affinity(product.Category, product.Price) * priceAffinityWeight
 		}
-	}
-
-	// Сортуємо за рейтингом (більший - вищий)
-	sort.Slice(productScores, func(i, j int) bool {
-		return productScores[i].Score > productScores[j].Score
-	})
-
-	// Обмежуємо кількість рекомендацій
-	maxRecommendations := limit
-	if len(productScores) < maxRecommendations {
-		maxRecommendations = len(productScores)
-	}
 
-	// Формуємо фінальний список рекомендацій
-	for i := 0; i < maxRecommendations; i++ {
-		recommendations = append(recommendations, productScores[i].Product)
-		scores = append(scores, productScores[i].Score)
+		productScores = append(productScores, scoredProduct{product, score})
 	}
 
-	return recommendations, scores
+	return splitScored(topK(productScores, limit))
 }
 
 // getPopularityBasedRecommendations використовує популярність товарів
-func getPopularityBasedRecommendations(userID uint, likes []*models.UserLike, orders []*models.Order, allProducts []*models.Product, limit int) ([]*models.Product, []float64) {
-	var recommendations []*models.Product
-	var scores []float64
-
-	// Створення мапи продуктів, які користувач вже лайкнув або купив
-	userProductMap := make(map[uint]bool)
-
-	// Додавання лайкнутих продуктів
-	for _, like := range likes {
-		if like.UserID == userID {
-			userProductMap[like.ProductID] = true
-		}
-	}
-
-	// Додавання куплених продуктів
-	for _, order := range orders {
-		if order.UserID == userID {
-			for _, item := range order.Items {
-				userProductMap[item.ProductID] = true
-			}
-		}
-	}
-
+// Якщо передана таблиця popularity (з навченої моделі), вона використовується
+// замість підрахунку лайків.
+func getPopularityBasedRecommendations(idx *interactionIndex, limit int, popularity map[uint]float64, cf *candidateFilter) ([]*models.Product, []float64) {
 	// Підрахунок популярності товарів
-	popularity := make(map[uint]int)
-
-	for _, like := range likes {
-		popularity[like.ProductID]++
+	if len(popularity) == 0 {
+		popularity = make(map[uint]float64, len(idx.likeCounts))
+		for pid, count := range idx.likeCounts {
+			popularity[pid] = float64(count)
+		}
 	}
 
 	// Якщо немає лайків взагалі, повертаємо пустий список
@@ -332,73 +292,37 @@ func getPopularityBasedRecommendations(userID uint, likes []*models.UserLike, or
 		return nil, nil
 	}
 
-	type PopularProduct struct {
-		Product *models.Product
-		Count   int
-	}
-
-	var popularProducts []PopularProduct
+	// Відбираємо популярні товари (окрім тих, що вже лайкав користувач)
+	popularProducts := make([]scoredProduct, 0, len(popularity))
 	for pid, count := range popularity {
-		for _, product := range allProducts {
-			if product.ID == pid {
-				popularProducts = append(popularProducts, PopularProduct{product, count})
-				break
-			}
+		product := idx.products[pid]
+		if product != nil && cf.accept(product) {
+			popularProducts = append(popularProducts, scoredProduct{product, count})
 		}
 	}
 
-	// Сортуємо за популярністю
-	sort.Slice(popularProducts, func(i, j int) bool {
-		return popularProducts[i].Count > popularProducts[j].Count
-	})
-
-	// Додаємо популярні товари до рекомендацій (окрім тих, що вже лайкав користувач)
-	for _, pp := range popularProducts {
-		if !userProductMap[pp.Product.ID] {
-			recommendations = append(recommendations, pp.Product)
-			scores = append(scores, float64(pp.Count))
-		}
+	return splitScored(topK(popularProducts, limit))
+}
 
-		// Обмежуємо кількість рекомендацій
-		if len(recommendations) >= limit {
-			break
-		}
+// modelPopularity повертає таблицю популярності моделі або nil, якщо модель не завантажена
+func modelPopularity(model *snapshot.Snapshot) map[uint]float64 {
+	if model == nil {
+		return nil
 	}
-
-	return recommendations, scores
+	return model.Popularity
 }
 
 // getRandomRecommendations генерує випадкові рекомендації
-func getRandomRecommendations(userID uint, likes []*models.UserLike, orders []*models.Order, allProducts []*models.Product, count int) ([]*models.Product, []float64) {
-	var recommendations []*models.Product
-	var scores []float64
-
-	// Створення мапи продуктів, які користувач вже лайкнув або купив
-	userProductMap := make(map[uint]bool)
-
-	// Додавання лайкнутих продуктів
-	for _, like := range likes {
-		if like.UserID == userID {
-			userProductMap[like.ProductID] = true
-		}
-	}
-
-	// Додавання куплених продуктів
-	for _, order := range orders {
-		if order.UserID == userID {
-			for _, item := range order.Items {
-				userProductMap[item.ProductID] = true
-			}
-		}
-	}
-
-	// Ініціалізуємо випадковий генератор
-	rand.NewSource(time.Now().UnixNano())
-
-	// Перемішуємо всі товари
+func getRandomRecommendations(allProducts []*models.Product, count int, rng *rand.Rand, cf *candidateFilter) ([]*models.Product, []float64) {
+	// Перемішуємо всі товари. Спершу впорядковуємо їх за ID, бо порядок
+	// вибірки з бази не гарантований, а результат має залежати лише від seed
 	shuffledProducts := make([]*models.Product, len(allProducts))
 	copy(shuffledProducts, allProducts)
-	rand.Shuffle(len(shuffledProducts), func(i, j int) {
+	byID := func(i, j int) bool { return shuffledProducts[i].ID < shuffledProducts[j].ID }
+	if !sort.SliceIsSorted(shuffledProducts, byID) {
+		sort.Slice(shuffledProducts, byID)
+	}
+	rng.Shuffle(len(shuffledProducts), func(i, j int) {
 		shuffledProducts[i], shuffledProducts[j] = shuffledProducts[j], shuffledProducts[i]
 	})
 
@@ -407,10 +331,15 @@ func getRandomRecommendations(userID uint, likes []*models.UserLike, orders []*m
 	if len(shuffledProducts) < maxRandomProducts {
 		maxRandomProducts = len(shuffledProducts)
 	}
+	if maxRandomProducts <= 0 {
+		return nil, nil
+	}
 
+	recommendations := make([]*models.Product, 0, maxRandomProducts)
+	scores := make([]float64, 0, maxRandomProducts)
 	for i := 0; i < len(shuffledProducts) && len(recommendations) < maxRandomProducts; i++ {
-		// Пропускаємо товари, які користувач уже лайкав/купував
-		if !userProductMap[shuffledProducts[i].ID] {
+		// Пропускаємо товари, які користувач уже лайкав/купував або які не проходять фільтр
+		if cf.accept(shuffledProducts[i]) {
 			recommendations = append(recommendations, shuffledProducts[i])
 			// Додаємо фіксований низький рейтинг для випадкових рекомендацій
 			scores = append(scores, 0.1)
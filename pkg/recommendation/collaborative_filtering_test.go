@@ -0,0 +1,122 @@
+package recommendation
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"product-recommendations-go/internal/models"
+)
+
+// testCatalog створює n товарів у трьох категоріях з ID 1..n
+func testCatalog(n int) []*models.Product {
+	products := make([]*models.Product, n)
+	for i := range products {
+		id := uint(i + 1)
+		products[i] = &models.Product{
+			ID:       id,
+			Name:     fmt.Sprintf("Product %d", id),
+			Price:    float64(10 * id),
+			Category: fmt.Sprintf("category-%d", id%3),
+		}
+	}
+	return products
+}
+
+// reversed повертає товари у зворотному порядку, як могла б повернути їх база
+func reversed(products []*models.Product) []*models.Product {
+	result := make([]*models.Product, len(products))
+	for i, p := range products {
+		result[len(products)-1-i] = p
+	}
+	return result
+}
+
+func productIDs(products []*models.Product) []uint {
+	ids := make([]uint, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+func TestRecommendProductsDeterministicFallbacks(t *testing.T) {
+	const userID = 1
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	products := testCatalog(30)
+
+	// Інші користувачі лайкали товари 5 (тричі), 7 (двічі) і 9 (один раз);
+	// користувач userID ще ні з чим не взаємодіяв
+	popularLikes := []*models.UserLike{
+		{UserID: 2, ProductID: 5}, {UserID: 3, ProductID: 5}, {UserID: 4, ProductID: 5},
+		{UserID: 2, ProductID: 7}, {UserID: 3, ProductID: 7},
+		{UserID: 4, ProductID: 9},
+	}
+
+	tests := []struct {
+		name  string
+		likes []*models.UserLike
+		opts  func() Options
+		want  []uint
+	}{
+		{
+			name:  "random fallback with daily source",
+			likes: nil,
+			opts:  func() Options { return Options{Now: now} },
+		},
+		{
+			name:  "random fallback with explicit source",
+			likes: nil,
+			opts:  func() Options { return Options{Source: rand.NewSource(42), Now: now} },
+		},
+		{
+			name:  "popularity fallback",
+			likes: popularLikes,
+			opts:  func() Options { return Options{Now: now} },
+			want:  []uint{5, 7, 9},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, firstScores := RecommendProductsWithOptions(userID, tt.likes, nil, products, 5, tt.opts())
+			// Порядок товарів, отриманих з бази, не впливає на результат
+			second, secondScores := RecommendProductsWithOptions(userID, tt.likes, nil, reversed(products), 5, tt.opts())
+
+			if len(first) == 0 {
+				t.Fatal("expected recommendations, got none")
+			}
+			if !reflect.DeepEqual(productIDs(first), productIDs(second)) {
+				t.Errorf("same seed and Now gave different products: %v vs %v", productIDs(first), productIDs(second))
+			}
+			if !reflect.DeepEqual(firstScores, secondScores) {
+				t.Errorf("same seed and Now gave different scores: %v vs %v", firstScores, secondScores)
+			}
+			if tt.want != nil && !reflect.DeepEqual(productIDs(first), tt.want) {
+				t.Errorf("got %v, want %v", productIDs(first), tt.want)
+			}
+		})
+	}
+}
+
+func TestRecommendProductsRandomFallbackChangesDaily(t *testing.T) {
+	products := testCatalog(30)
+	day := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+
+	morning, _ := RecommendProductsWithOptions(1, nil, nil, products, 5, Options{Now: day})
+	evening, _ := RecommendProductsWithOptions(1, nil, nil, products, 5, Options{Now: day.Add(12 * time.Hour)})
+	nextDay, _ := RecommendProductsWithOptions(1, nil, nil, products, 5, Options{Now: day.Add(24 * time.Hour)})
+	otherUser, _ := RecommendProductsWithOptions(2, nil, nil, products, 5, Options{Now: day})
+
+	if !reflect.DeepEqual(productIDs(morning), productIDs(evening)) {
+		t.Errorf("selection changed within a day: %v vs %v", productIDs(morning), productIDs(evening))
+	}
+	if reflect.DeepEqual(productIDs(morning), productIDs(nextDay)) {
+		t.Errorf("selection did not change on the next day: %v", productIDs(morning))
+	}
+	if reflect.DeepEqual(productIDs(morning), productIDs(otherUser)) {
+		t.Errorf("different users got the same selection: %v", productIDs(morning))
+	}
+}
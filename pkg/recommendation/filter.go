@@ -0,0 +1,89 @@
+package recommendation
+
+import "product-recommendations-go/internal/models"
+
+// candidateFilter визначає, які товари можуть потрапити до рекомендацій користувача
+type candidateFilter struct {
+	filter   models.RecommendationFilter
+	excluded map[uint]bool
+	// explicit товари, явно виключені параметром ExcludeIDs
+	explicit map[uint]bool
+}
+
+// newCandidateFilter будує фільтр кандидатів: виключає лайкнуті товари, куплені товари
+// (якщо не встановлено IncludePurchased) та явно передані ExcludeIDs
+func newCandidateFilter(idx *interactionIndex, filter models.RecommendationFilter) *candidateFilter {
+	excluded := make(map[uint]bool, len(idx.interacted)+len(filter.ExcludeIDs))
+
+	for _, pid := range idx.userLiked {
+		excluded[pid] = true
+	}
+
+	if !filter.IncludePurchased {
+		for _, pid := range idx.userPurchased {
+			excluded[pid] = true
+		}
+	}
+
+	explicit := make(map[uint]bool, len(filter.ExcludeIDs))
+	for _, id := range filter.ExcludeIDs {
+		excluded[id] = true
+		explicit[id] = true
+	}
+
+	return &candidateFilter{
+		filter:   filter,
+		excluded: excluded,
+		explicit: explicit,
+	}
+}
+
+// newSeedFilter будує фільтр кандидатів для рекомендацій до набору товарів (кошика
+// або товару, до якого шукаються схожі): виключає самі товари seedIDs та явно
+// передані ExcludeIDs
+func newSeedFilter(seedIDs []uint, filter models.RecommendationFilter) *candidateFilter {
+	excluded := make(map[uint]bool, len(seedIDs)+len(filter.ExcludeIDs))
+	for _, pid := range seedIDs {
+		excluded[pid] = true
+	}
+
+	explicit := make(map[uint]bool, len(filter.ExcludeIDs))
+	for _, id := range filter.ExcludeIDs {
+		excluded[id] = true
+		explicit[id] = true
+	}
+
+	return &candidateFilter{
+		filter:   filter,
+		excluded: excluded,
+		explicit: explicit,
+	}
+}
+
+// accept перевіряє, чи може товар бути рекомендований
+func (f *candidateFilter) accept(product *models.Product) bool {
+	return !f.excluded[product.ID] && f.matches(product)
+}
+
+// acceptRepeat перевіряє, чи може товар, з яким користувач уже взаємодіяв, бути
+// рекомендований повторно: враховуються лише параметри запиту
+func (f *candidateFilter) acceptRepeat(product *models.Product) bool {
+	return !f.explicit[product.ID] && f.matches(product)
+}
+
+// matches перевіряє категорію, ціновий діапазон і, за потреби, наявність на складі
+func (f *candidateFilter) matches(product *models.Product) bool {
+	if f.filter.Category != "" && product.Category != f.filter.Category {
+		return false
+	}
+	if f.filter.MinPrice != nil && product.Price < *f.filter.MinPrice {
+		return false
+	}
+	if f.filter.MaxPrice != nil && product.Price > *f.filter.MaxPrice {
+		return false
+	}
+	if f.filter.InStockOnly && product.Stock <= 0 {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package recommendation
+
+import "product-recommendations-go/internal/models"
+
+// interactionIndex попередньо згруповані дані для одного виклику RecommendProductsWithOptions.
+// Будується за один прохід по лайках, замовленнях і каталогу (O(n)) і спільно
+// використовується всіма стратегіями, замість вкладених циклів по allProducts.
+// Індекс не створює структур на кожного користувача, щоб кількість алокацій
+// не залежала від кількості користувачів.
+type interactionIndex struct {
+	userID uint
+
+	// products каталог товарів за ID
+	products map[uint]*models.Product
+
+	// likes усі лайки; стратегії, яким потрібні лайки інших користувачів, проходять їх лінійно
+	likes []*models.UserLike
+	// likeCounts кількість лайків кожного товару
+	likeCounts map[uint]int
+
+	// userLiked товари, лайкнуті цільовим користувачем
+	userLiked []uint
+	// userPurchased товари з замовлень цільового користувача (кожна позиція замовлення окремо)
+	userPurchased []uint
+	// userItems позиції замовлень цільового користувача з фактично сплаченою ціною
+	userItems []models.OrderItem
+	// interacted товари, які цільовий користувач лайкнув або купив
+	interacted map[uint]bool
+}
+
+// newInteractionIndex будує індекс взаємодій для цільового користувача
+func newInteractionIndex(userID uint, likes []*models.UserLike, orders []*models.Order, allProducts []*models.Product) *interactionIndex {
+	idx := &interactionIndex{
+		userID:     userID,
+		products:   indexProducts(allProducts),
+		likes:      likes,
+		likeCounts: make(map[uint]int, len(allProducts)),
+		interacted: make(map[uint]bool),
+	}
+
+	for _, like := range likes {
+		idx.likeCounts[like.ProductID]++
+
+		if like.UserID == userID {
+			idx.userLiked = append(idx.userLiked, like.ProductID)
+			idx.interacted[like.ProductID] = true
+		}
+	}
+
+	for _, order := range orders {
+		if order.UserID != userID {
+			continue
+		}
+		for _, item := range order.Items {
+			idx.userPurchased = append(idx.userPurchased, item.ProductID)
+			idx.userItems = append(idx.userItems, item)
+			idx.interacted[item.ProductID] = true
+		}
+	}
+
+	return idx
+}
@@ -0,0 +1,52 @@
+package recommendation
+
+import (
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/pkg/recommendation/snapshot"
+)
+
+// getModelRecommendations використовує навчену офлайн модель: товари, подібні до
+// тих, з якими взаємодіяв користувач (item-based фільтрація), та наслідки
+// асоціативних правил для куплених товарів
+func getModelRecommendations(idx *interactionIndex, limit int, model *snapshot.Snapshot, cf *candidateFilter) ([]*models.Product, []float64) {
+	// Товари користувача з вагою взаємодії
+	seeds := make(map[uint]float64, len(idx.interacted))
+	purchased := make(map[uint]bool, len(idx.userPurchased))
+
+	for _, pid := range idx.userLiked {
+		if seeds[pid] < 1.0 {
+			seeds[pid] = 1.0
+		}
+	}
+	for _, pid := range idx.userPurchased {
+		seeds[pid] = 2.0
+		purchased[pid] = true
+	}
+
+	if len(seeds) == 0 {
+		return nil, nil
+	}
+
+	candidateScores := make(map[uint]float64)
+	for seedID, weight := range seeds {
+		for _, neighbor := range model.ItemSimilarity[seedID] {
+			candidateScores[neighbor.ProductID] += weight * neighbor.Score
+		}
+	}
+	for seedID := range purchased {
+		for _, rule := range model.RulesFor(seedID) {
+			candidateScores[rule.Consequent] += rule.Confidence
+		}
+	}
+
+	productScores := make([]scoredProduct, 0, len(candidateScores))
+	for pid, score := range candidateScores {
+		product := idx.products[pid]
+		if product == nil || !cf.accept(product) {
+			continue
+		}
+		productScores = append(productScores, scoredProduct{product, score})
+	}
+
+	return splitScored(topK(productScores, limit))
+}
@@ -0,0 +1,128 @@
+package recommendation
+
+import (
+	"math"
+	"sort"
+)
+
+// Параметри цінового профілю
+const (
+	// minCategoryPriceObservations мінімальна кількість покупок у категорії, за якої
+	// використовується медіана категорії замість загальної
+	minCategoryPriceObservations = 2
+	// minPriceSpread нижня межа розкиду (у логарифмічній шкалі), щоб профіль з
+	// кількох однакових цін не відкидав усі інші товари
+	minPriceSpread = 0.5
+	// iqrToSigma відношення міжквартильного розмаху до стандартного відхилення
+	// нормального розподілу
+	iqrToSigma = 1.349
+)
+
+// pricePoint ціна з вагою (кількістю куплених одиниць)
+type pricePoint struct {
+	price  float64
+	weight float64
+}
+
+// priceProfile профіль цінової чутливості користувача, побудований за цінами,
+// які він фактично заплатив (OrderItem.Price). Якщо покупок немає, використовуються
+// каталожні ціни лайкнутих товарів.
+type priceProfile struct {
+	// P25, Median, P75 квартилі розподілу цін покупок
+	P25, Median, P75 float64
+	// Spread розкид цін у логарифмічній шкалі (оцінка стандартного відхилення за IQR)
+	Spread float64
+	// CategoryMedian медіанна ціна покупок у кожній категорії
+	CategoryMedian map[string]float64
+}
+
+// newPriceProfile будує ціновий профіль цільового користувача; повертає nil,
+// якщо в історії немає жодної ціни
+func newPriceProfile(idx *interactionIndex) *priceProfile {
+	var all []pricePoint
+	byCategory := make(map[string][]pricePoint)
+
+	add := func(productID uint, price, weight float64) {
+		if price <= 0 || weight <= 0 {
+			return
+		}
+		point := pricePoint{price, weight}
+		all = append(all, point)
+		if product := idx.products[productID]; product != nil {
+			byCategory[product.Category] = append(byCategory[product.Category], point)
+		}
+	}
+
+	for _, item := range idx.userItems {
+		add(item.ProductID, item.Price, float64(item.Quantity))
+	}
+	if len(all) == 0 {
+		for _, pid := range idx.userLiked {
+			if product := idx.products[pid]; product != nil {
+				add(pid, product.Price, 1)
+			}
+		}
+	}
+	if len(all) == 0 {
+		return nil
+	}
+
+	sortPoints(all)
+	profile := &priceProfile{
+		P25:            weightedQuantile(all, 0.25),
+		Median:         weightedQuantile(all, 0.5),
+		P75:            weightedQuantile(all, 0.75),
+		CategoryMedian: make(map[string]float64, len(byCategory)),
+	}
+	profile.Spread = math.Max(minPriceSpread, math.Log(profile.P75/profile.P25)/iqrToSigma)
+
+	for category, points := range byCategory {
+		if len(points) < minCategoryPriceObservations {
+			continue
+		}
+		sortPoints(points)
+		profile.CategoryMedian[category] = weightedQuantile(points, 0.5)
+	}
+
+	return profile
+}
+
+// affinity оцінює від 0 до 1, наскільки ціна товару відповідає звичному для
+// користувача рівню цін у цій категорії (або загалом, якщо в категорії мало покупок).
+// Ціни порівнюються в логарифмічній шкалі, тому відхилення вдвічі дешевше і вдвічі
+// дорожче важать однаково.
+func (p *priceProfile) affinity(category string, price float64) float64 {
+	if price <= 0 {
+		return 0
+	}
+
+	reference, ok := p.CategoryMedian[category]
+	if !ok {
+		reference = p.Median
+	}
+
+	z := math.Log(price/reference) / p.Spread
+	return math.Exp(-z * z / 2)
+}
+
+func sortPoints(points []pricePoint) {
+	sort.Slice(points, func(i, j int) bool { return points[i].price < points[j].price })
+}
+
+// weightedQuantile повертає квантиль q зваженого розподілу, відсортованого за ціною
+func weightedQuantile(points []pricePoint, q float64) float64 {
+	var total float64
+	for _, p := range points {
+		total += p.weight
+	}
+
+	target := q * total
+	var cumulative float64
+	for _, p := range points {
+		cumulative += p.weight
+		if cumulative >= target {
+			return p.price
+		}
+	}
+	return points[len(points)-1].price
+}
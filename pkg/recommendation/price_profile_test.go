@@ -0,0 +1,130 @@
+package recommendation
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"product-recommendations-go/internal/models"
+)
+
+func TestPriceProfile(t *testing.T) {
+	products := testCatalog(6)
+	purchase := func(items ...models.OrderItem) []*models.Order {
+		return []*models.Order{{UserID: 1, Items: items}}
+	}
+	item := func(productID uint, price float64, quantity int) models.OrderItem {
+		return models.OrderItem{ProductID: productID, Price: price, Quantity: quantity}
+	}
+
+	tests := []struct {
+		name   string
+		likes  []*models.UserLike
+		orders []*models.Order
+		// want nil - профіль не будується
+		want *priceProfile
+	}{
+		{
+			name: "empty history",
+		},
+		{
+			name:   "only other users",
+			likes:  []*models.UserLike{{UserID: 2, ProductID: 1}},
+			orders: []*models.Order{{UserID: 2, Items: []models.OrderItem{item(1, 10, 1)}}},
+		},
+		{
+			name:   "single purchase",
+			orders: purchase(item(1, 25, 1)),
+			want: &priceProfile{
+				P25: 25, Median: 25, P75: 25,
+				Spread:         minPriceSpread,
+				CategoryMedian: map[string]float64{},
+			},
+		},
+		{
+			name:   "same price everywhere",
+			orders: purchase(item(1, 40, 1), item(4, 40, 2), item(2, 40, 1)),
+			want: &priceProfile{
+				P25: 40, Median: 40, P75: 40,
+				Spread:         minPriceSpread,
+				CategoryMedian: map[string]float64{"category-1": 40},
+			},
+		},
+		{
+			// Враховується сплачена ціна, а не поточна каталожна, з вагою кількості
+			name:   "weighted by quantity",
+			orders: purchase(item(1, 10, 3), item(2, 80, 1)),
+			want: &priceProfile{
+				P25: 10, Median: 10, P75: 10,
+				Spread:         minPriceSpread,
+				CategoryMedian: map[string]float64{},
+			},
+		},
+		{
+			name:   "zero and negative prices are ignored",
+			orders: purchase(item(1, 0, 1), item(4, -5, 1), item(2, 20, 1), item(5, 20, 0)),
+			want: &priceProfile{
+				P25: 20, Median: 20, P75: 20,
+				Spread:         minPriceSpread,
+				CategoryMedian: map[string]float64{},
+			},
+		},
+		{
+			name:   "only zero and negative prices",
+			orders: purchase(item(1, 0, 1), item(4, -5, 2)),
+		},
+		{
+			// Без придатних покупок використовуються каталожні ціни лайкнутих товарів
+			name:   "likes when purchases have no price",
+			likes:  []*models.UserLike{{UserID: 1, ProductID: 3}, {UserID: 1, ProductID: 6}},
+			orders: purchase(item(1, 0, 1)),
+			want: &priceProfile{
+				P25: 30, Median: 30, P75: 60,
+				Spread:         math.Log(2) / iqrToSigma,
+				CategoryMedian: map[string]float64{"category-0": 30},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newPriceProfile(newInteractionIndex(1, tt.likes, tt.orders, products))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriceAffinity(t *testing.T) {
+	profile := &priceProfile{
+		Median:         20,
+		Spread:         minPriceSpread,
+		CategoryMedian: map[string]float64{"shoes": 100},
+	}
+	// Ціна вдвічі вища чи нижча за звичну відхиляється на ln 2 / Spread сигм
+	twice := math.Exp(-math.Pow(math.Log(2)/minPriceSpread, 2) / 2)
+
+	tests := []struct {
+		name     string
+		category string
+		price    float64
+		want     float64
+	}{
+		{name: "usual price", category: "books", price: 20, want: 1},
+		{name: "twice the usual price", category: "books", price: 40, want: twice},
+		{name: "half the usual price", category: "books", price: 10, want: twice},
+		{name: "category median", category: "shoes", price: 100, want: 1},
+		{name: "overall median in a known category", category: "shoes", price: 20, want: math.Exp(-math.Pow(math.Log(5)/minPriceSpread, 2) / 2)},
+		{name: "zero price", category: "books", price: 0, want: 0},
+		{name: "negative price", category: "books", price: -20, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := profile.affinity(tt.category, tt.price); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("affinity(%q, %v) = %v, want %v", tt.category, tt.price, got, tt.want)
+			}
+		})
+	}
+}
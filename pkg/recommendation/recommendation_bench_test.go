@@ -0,0 +1,347 @@
+package recommendation
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/pkg/ann"
+	"product-recommendations-go/pkg/recommendation/snapshot"
+	"product-recommendations-go/pkg/recommendation/synthetic"
+)
+
+// Запуск: go test ./pkg/recommendation -run '^$' -bench . -benchmem
+// Профіль алокацій: додати -memprofile mem.out і переглянути через go tool pprof.
+
+const benchLimit = 10
+
+var benchSizes = []int{1_000, 10_000, 100_000}
+
+type benchFixture struct {
+	data   *synthetic.Dataset
+	userID uint
+	model  *snapshot.Snapshot
+	index  *ann.HNSW
+
+	// Дані для стратегії поповнення: витратний каталог та інтервали, за якими
+	// кожен куплений користувачем товар пора купити знову
+	consumables []*models.Product
+	intervals   snapshot.RepurchaseIntervals
+	now         time.Time
+
+	// cart товари кошика; coPurchases кількість спільних з ними замовлень
+	cart        []uint
+	coPurchases map[uint]int
+}
+
+var (
+	fixturesMu sync.Mutex
+	fixtures   = map[int]*benchFixture{}
+)
+
+func TestMain(m *testing.M) {
+	// Стратегії логують кожен крок ланцюжка; у бенчмарках це лише шум
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// fixture повертає набір даних розміру size (товарів і користувачів), згенерований один раз
+func fixture(b *testing.B, size int) *benchFixture {
+	b.Helper()
+
+	fixturesMu.Lock()
+	defer fixturesMu.Unlock()
+
+	if f, ok := fixtures[size]; ok {
+		return f
+	}
+
+	data := synthetic.Generate(synthetic.DefaultConfig(size, size))
+	f := &benchFixture{data: data, userID: mostActiveUser(data)}
+	f.prepareReplenishment()
+	f.prepareCart()
+	fixtures[size] = f
+	return f
+}
+
+// prepareReplenishment робить каталог витратним і підбирає інтервали так, щоб
+// від останньої покупки кожного товару користувача минув рівно один інтервал.
+// Решта товарів отримує інтервал, як у навченій таблиці.
+func (f *benchFixture) prepareReplenishment() {
+	f.consumables = make([]*models.Product, len(f.data.Products))
+	f.intervals = make(snapshot.RepurchaseIntervals, len(f.data.Products))
+	for i, product := range f.data.Products {
+		consumable := *product
+		consumable.IsConsumable = true
+		f.consumables[i] = &consumable
+		f.intervals[product.ID] = 30
+	}
+
+	lastPurchase := make(map[uint]time.Time)
+	for _, order := range f.data.Orders {
+		if order.UserID != f.userID {
+			continue
+		}
+		for _, item := range order.Items {
+			if order.CreatedAt.After(lastPurchase[item.ProductID]) {
+				lastPurchase[item.ProductID] = order.CreatedAt
+			}
+		}
+		if order.CreatedAt.After(f.now) {
+			f.now = order.CreatedAt
+		}
+	}
+	f.now = f.now.Add(24 * time.Hour)
+	for id, purchasedAt := range lastPurchase {
+		f.intervals[id] = f.now.Sub(purchasedAt).Hours() / 24
+	}
+}
+
+// prepareCart кладе в кошик товари найбільшого замовлення користувача і рахує
+// спільні покупки, як це робить репозиторій замовлень
+func (f *benchFixture) prepareCart() {
+	var largest *models.Order
+	for _, order := range f.data.Orders {
+		if order.UserID == f.userID && (largest == nil || len(order.Items) > len(largest.Items)) {
+			largest = order
+		}
+	}
+	inCart := make(map[uint]bool)
+	for _, item := range largest.Items {
+		if !inCart[item.ProductID] {
+			inCart[item.ProductID] = true
+			f.cart = append(f.cart, item.ProductID)
+		}
+	}
+
+	f.coPurchases = make(map[uint]int)
+	for _, order := range f.data.Orders {
+		withCart := false
+		for _, item := range order.Items {
+			withCart = withCart || inCart[item.ProductID]
+		}
+		if !withCart {
+			continue
+		}
+		for _, item := range order.Items {
+			if !inCart[item.ProductID] {
+				f.coPurchases[item.ProductID]++
+			}
+		}
+	}
+}
+
+// withModel доповнює набір даних навченою моделлю та ANN індексом її векторів
+func withModel(b *testing.B, f *benchFixture) *benchFixture {
+	b.Helper()
+
+	fixturesMu.Lock()
+	defer fixturesMu.Unlock()
+
+	if f.model != nil {
+		return f
+	}
+
+	cfg := snapshot.DefaultTrainConfig()
+	cfg.Epochs = 5
+	f.model = snapshot.Train(f.data.Likes, f.data.Orders, cfg)
+
+	indexCfg := ann.DefaultConfig()
+	indexCfg.EfConstruction = 64
+	index, err := ann.Build(f.model.ItemFactors, indexCfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	f.index = index
+	return f
+}
+
+// mostActiveUser вибирає користувача з найбільшою кількістю взаємодій,
+// щоб кожна стратегія мала непорожній профіль
+func mostActiveUser(data *synthetic.Dataset) uint {
+	counts := make(map[uint]int)
+	for _, like := range data.Likes {
+		counts[like.UserID]++
+	}
+	for _, order := range data.Orders {
+		counts[order.UserID] += len(order.Items)
+	}
+
+	var best uint
+	for id, n := range counts {
+		if n > counts[best] || (n == counts[best] && id < best) {
+			best = id
+		}
+	}
+	return best
+}
+
+// benchStrategy запускає стратегію для кожного розміру набору даних.
+// Побудова індексу взаємодій і фільтра входить у виміряний час, бо
+// виконується на кожен запит.
+func benchStrategy(b *testing.B, needsModel bool, run func(f *benchFixture, idx *interactionIndex, cf *candidateFilter) []*models.Product) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("n=%d", size), func(b *testing.B) {
+			f := fixture(b, size)
+			if needsModel {
+				f = withModel(b, f)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				idx := newInteractionIndex(f.userID, f.data.Likes, f.data.Orders, f.data.Products)
+				cf := newCandidateFilter(idx, models.RecommendationFilter{})
+				if recs := run(f, idx, cf); len(recs) == 0 {
+					b.Fatal("no recommendations")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkInteractionIndex(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("n=%d", size), func(b *testing.B) {
+			f := fixture(b, size)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				newInteractionIndex(f.userID, f.data.Likes, f.data.Orders, f.data.Products)
+			}
+		})
+	}
+}
+
+func BenchmarkCollaborative(b *testing.B) {
+	benchStrategy(b, false, func(f *benchFixture, idx *interactionIndex, cf *candidateFilter) []*models.Product {
+		recs, _ := getCollaborativeRecommendations(idx, benchLimit, cf)
+		return recs
+	})
+}
+
+func BenchmarkModel(b *testing.B) {
+	benchStrategy(b, true, func(f *benchFixture, idx *interactionIndex, cf *candidateFilter) []*models.Product {
+		recs, _ := getModelRecommendations(idx, benchLimit, f.model, cf)
+		return recs
+	})
+}
+
+func BenchmarkVector(b *testing.B) {
+	benchStrategy(b, true, func(f *benchFixture, idx *interactionIndex, cf *candidateFilter) []*models.Product {
+		recs, _ := getVectorRecommendations(idx, benchLimit, f.index, cf)
+		return recs
+	})
+}
+
+func BenchmarkContentBased(b *testing.B) {
+	benchStrategy(b, false, func(f *benchFixture, idx *interactionIndex, cf *candidateFilter) []*models.Product {
+		recs, _ := getContentBasedRecommendations(idx, f.data.Products, benchLimit, cf)
+		return recs
+	})
+}
+
+func BenchmarkPopularity(b *testing.B) {
+	benchStrategy(b, false, func(f *benchFixture, idx *interactionIndex, cf *candidateFilter) []*models.Product {
+		recs, _ := getPopularityBasedRecommendations(idx, benchLimit, nil, cf)
+		return recs
+	})
+}
+
+func BenchmarkRandom(b *testing.B) {
+	benchStrategy(b, false, func(f *benchFixture, idx *interactionIndex, cf *candidateFilter) []*models.Product {
+		recs, _ := getRandomRecommendations(f.data.Products, benchLimit, rand.New(rand.NewSource(1)), cf)
+		return recs
+	})
+}
+
+func BenchmarkReplenishment(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("n=%d", size), func(b *testing.B) {
+			f := fixture(b, size)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				idx := newInteractionIndex(f.userID, f.data.Likes, f.data.Orders, f.consumables)
+				cf := newCandidateFilter(idx, models.RecommendationFilter{})
+				recs, _ := getReplenishmentRecommendations(idx, f.data.Orders, benchLimit, f.intervals, f.now, cf)
+				if len(recs) == 0 {
+					b.Fatal("no recommendations")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRecommendForCart(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("n=%d", size), func(b *testing.B) {
+			f := withModel(b, fixture(b, size))
+			opts := Options{Model: f.model, Index: f.index}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if recs, _ := RecommendForCart(f.cart, f.coPurchases, f.data.Products, benchLimit, opts); len(recs) == 0 {
+					b.Fatal("no recommendations")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSimilarProducts(b *testing.B) {
+	for _, size := range benchSizes {
+		f := fixture(b, size)
+		productID := f.cart[0]
+
+		// Пошук у векторному індексі та резервний пошук у категорії товару
+		variants := []struct {
+			name       string
+			needsIndex bool
+		}{
+			{name: "index", needsIndex: true},
+			{name: "category"},
+		}
+		for _, v := range variants {
+			b.Run(fmt.Sprintf("%s/n=%d", v.name, size), func(b *testing.B) {
+				var opts Options
+				if v.needsIndex {
+					f := withModel(b, f)
+					opts = Options{Model: f.model, Index: f.index}
+				}
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if recs, _ := SimilarProducts(productID, f.data.Products, benchLimit, opts); len(recs) == 0 {
+						b.Fatal("no recommendations")
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkHybrid(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("n=%d", size), func(b *testing.B) {
+			f := fixture(b, size)
+			opts := Options{Source: rand.NewSource(1)}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				RecommendProductsWithOptions(f.userID, f.data.Likes, f.data.Orders, f.data.Products, benchLimit, opts)
+			}
+		})
+	}
+}
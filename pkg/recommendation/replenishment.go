@@ -0,0 +1,69 @@
+package recommendation
+
+import (
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/pkg/recommendation/snapshot"
+	"time"
+)
+
+// Межі "вікна поповнення" у частках типового інтервалу повторної покупки
+const (
+	// replenishmentDueRatio товар пропонується, коли минуло щонайменше 80% інтервалу
+	replenishmentDueRatio = 0.8
+	// replenishmentMaxRatio після трьох пропущених інтервалів вважаємо, що користувач
+	// більше не купує цей товар
+	replenishmentMaxRatio = 3.0
+)
+
+// replenishmentLimit кількість місць у видачі для поповнення запасів: не більше
+// половини, щоб залишалося місце для нових товарів
+func replenishmentLimit(limit int) int {
+	if limit <= 1 {
+		return limit
+	}
+	return limit / 2
+}
+
+// getReplenishmentRecommendations пропонує витратні товари, які користувач уже купував
+// і яким настав час повторної покупки. На відміну від інших стратегій, придбані
+// та лайкнуті товари не виключаються; враховуються лише параметри запиту.
+// Оцінка - частка типового інтервалу, що минула від останньої покупки.
+func getReplenishmentRecommendations(idx *interactionIndex, orders []*models.Order, limit int, intervals snapshot.RepurchaseIntervals, now time.Time, cf *candidateFilter) ([]*models.Product, []float64) {
+	if limit <= 0 || len(intervals) == 0 {
+		return nil, nil
+	}
+
+	// Остання покупка кожного товару користувача
+	lastPurchase := make(map[uint]time.Time, len(idx.userPurchased))
+	for _, order := range orders {
+		if order.UserID != idx.userID {
+			continue
+		}
+		for _, item := range order.Items {
+			if order.CreatedAt.After(lastPurchase[item.ProductID]) {
+				lastPurchase[item.ProductID] = order.CreatedAt
+			}
+		}
+	}
+
+	candidates := make([]scoredProduct, 0, len(lastPurchase))
+	for pid, purchasedAt := range lastPurchase {
+		product := idx.products[pid]
+		if product == nil || !product.IsConsumable || !cf.acceptRepeat(product) {
+			continue
+		}
+
+		intervalDays := intervals[pid]
+		if intervalDays <= 0 {
+			continue
+		}
+
+		ratio := now.Sub(purchasedAt).Hours() / 24 / intervalDays
+		if ratio < replenishmentDueRatio || ratio > replenishmentMaxRatio {
+			continue
+		}
+		candidates = append(candidates, scoredProduct{product, ratio})
+	}
+
+	return splitScored(topK(candidates, limit))
+}
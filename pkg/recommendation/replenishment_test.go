@@ -0,0 +1,142 @@
+package recommendation
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/pkg/recommendation/snapshot"
+)
+
+// replenishmentFixture повертає каталог, інтервали та замовлення, у яких користувачу 1
+// час повторно купити товари 8 (минуло 120% інтервалу) та 1 (90%)
+func replenishmentFixture(now time.Time) ([]*models.Product, snapshot.RepurchaseIntervals, []*models.Order) {
+	products := testCatalog(10)
+	for _, p := range products {
+		p.IsConsumable = p.ID != 6
+	}
+
+	intervals := snapshot.RepurchaseIntervals{1: 10, 2: 10, 3: 10, 4: 10, 6: 10, 7: 10, 8: 10}
+
+	daysAgo := func(days float64) time.Time {
+		return now.Add(-time.Duration(days * float64(24*time.Hour)))
+	}
+	order := func(userID uint, createdAt time.Time, productIDs ...uint) *models.Order {
+		o := &models.Order{UserID: userID, CreatedAt: createdAt}
+		for _, id := range productIDs {
+			o.Items = append(o.Items, models.OrderItem{ProductID: id, Quantity: 1})
+		}
+		return o
+	}
+	orders := []*models.Order{
+		order(1, daysAgo(9), 1),     // 0.9 інтервалу - час купувати
+		order(1, daysAgo(5), 2),     // 0.5 - ще рано
+		order(1, daysAgo(40), 3),    // 4.0 - користувач більше не купує товар
+		order(1, daysAgo(25), 4),    // давня покупка товару 4...
+		order(1, daysAgo(2), 4),     // ...але остання була щойно
+		order(1, daysAgo(15), 5, 6), // 5 без інтервалу, 6 не витратний
+		order(1, daysAgo(12), 8),    // 1.2 - час купувати
+		order(2, daysAgo(10), 7),    // покупка іншого користувача
+	}
+	return products, intervals, orders
+}
+
+func TestReplenishmentRecommendations(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	products, intervals, orders := replenishmentFixture(now)
+
+	tests := []struct {
+		name       string
+		limit      int
+		intervals  snapshot.RepurchaseIntervals
+		filter     models.RecommendationFilter
+		want       []uint
+		wantScores []float64
+	}{
+		{
+			name:       "due products ranked by elapsed share of interval",
+			limit:      5,
+			intervals:  intervals,
+			want:       []uint{8, 1},
+			wantScores: []float64{1.2, 0.9},
+		},
+		{
+			name:      "limit",
+			limit:     1,
+			intervals: intervals,
+			want:      []uint{8},
+		},
+		{
+			name:      "category filter",
+			limit:     5,
+			intervals: intervals,
+			filter:    models.RecommendationFilter{Category: "category-1"},
+			want:      []uint{1},
+		},
+		{
+			name:      "explicit exclusion",
+			limit:     5,
+			intervals: intervals,
+			filter:    models.RecommendationFilter{ExcludeIDs: []uint{8}},
+			want:      []uint{1},
+		},
+		{
+			name:      "no intervals",
+			limit:     5,
+			intervals: nil,
+			want:      []uint{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := newInteractionIndex(1, nil, orders, products)
+			cf := newCandidateFilter(idx, tt.filter)
+
+			got, scores := getReplenishmentRecommendations(idx, orders, tt.limit, tt.intervals, now, cf)
+			if ids := productIDs(got); !reflect.DeepEqual(ids, tt.want) {
+				t.Fatalf("got %v, want %v", ids, tt.want)
+			}
+			for i, want := range tt.wantScores {
+				if math.Abs(scores[i]-want) > 1e-9 {
+					t.Errorf("score of product %d = %v, want %v", got[i].ID, scores[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRecommendProductsPutsReplenishmentFirst(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	products, intervals, orders := replenishmentFixture(now)
+
+	opts := Options{
+		RepurchaseIntervals: intervals,
+		Now:                 now,
+		Filter:              models.RecommendationFilter{IncludePurchased: true},
+	}
+	got, scores := RecommendProductsWithOptions(1, nil, orders, products, 6, opts)
+	if len(got) != 6 || len(scores) != 6 {
+		t.Fatalf("got %d products and %d scores, want 6", len(got), len(scores))
+	}
+
+	// Поповнення займає не більше половини видачі і йде першим
+	if ids := productIDs(got[:2]); !reflect.DeepEqual(ids, []uint{8, 1}) {
+		t.Errorf("replenishment = %v, want [8 1]", ids)
+	}
+	// Решта стратегій не повторює товари поповнення
+	for _, p := range got[2:] {
+		if p.ID == 8 || p.ID == 1 {
+			t.Errorf("product %d repeated after replenishment: %v", p.ID, productIDs(got))
+		}
+	}
+
+	// Без інтервалів поповнення вимкнене
+	opts.RepurchaseIntervals = nil
+	got, _ = RecommendProductsWithOptions(1, nil, orders, products, 6, opts)
+	if len(got) >= 2 && reflect.DeepEqual(productIDs(got[:2]), []uint{8, 1}) {
+		t.Errorf("replenishment without intervals: %v", productIDs(got))
+	}
+}
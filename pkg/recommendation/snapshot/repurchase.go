@@ -0,0 +1,100 @@
+package snapshot
+
+import (
+	"product-recommendations-go/internal/models"
+	"sort"
+	"time"
+)
+
+// RepurchaseIntervals типовий інтервал між повторними покупками товару одним
+// користувачем, у днях
+type RepurchaseIntervals map[uint]float64
+
+// Параметри навчання інтервалів повторних покупок
+const (
+	// minRepurchaseObservations мінімальна кількість спостережених повторних покупок товару,
+	// за якої інтервал вважається надійним
+	minRepurchaseObservations = 3
+	// minRepurchaseGap покупки, зроблені ближче одна до одної, вважаються однією
+	// (наприклад, доповнення до щойно оформленого замовлення)
+	minRepurchaseGap = 24 * time.Hour
+)
+
+// LearnRepurchaseIntervals обчислює для кожного товару медіанний інтервал між
+// послідовними покупками одним користувачем за Order.CreatedAt усіх користувачів
+func LearnRepurchaseIntervals(orders []*models.Order) RepurchaseIntervals {
+	type key struct {
+		userID, productID uint
+	}
+
+	purchases := make(map[key][]time.Time)
+	for _, order := range orders {
+		for _, item := range order.Items {
+			k := key{order.UserID, item.ProductID}
+			purchases[k] = append(purchases[k], order.CreatedAt)
+		}
+	}
+
+	learner := NewRepurchaseLearner()
+	for k, times := range purchases {
+		sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+		for _, t := range times {
+			learner.Observe(k.userID, k.productID, t)
+		}
+	}
+
+	return learner.Intervals()
+}
+
+// RepurchaseLearner навчає інтервали повторних покупок на потоці покупок, не
+// тримаючи їх у пам'яті. Покупки однієї пари користувач-товар мають надходити
+// поспіль у порядку зростання часу, як у результаті ORDER BY user_id, product_id, created_at.
+type RepurchaseLearner struct {
+	gaps map[uint][]float64
+
+	userID, productID uint
+	last              time.Time
+	started           bool
+}
+
+// NewRepurchaseLearner створює порожній RepurchaseLearner
+func NewRepurchaseLearner() *RepurchaseLearner {
+	return &RepurchaseLearner{gaps: make(map[uint][]float64)}
+}
+
+// Observe враховує покупку товару productID користувачем userID у момент at
+func (l *RepurchaseLearner) Observe(userID, productID uint, at time.Time) {
+	if !l.started || userID != l.userID || productID != l.productID {
+		l.userID, l.productID, l.last, l.started = userID, productID, at, true
+		return
+	}
+
+	gap := at.Sub(l.last)
+	if gap < minRepurchaseGap {
+		return
+	}
+	l.gaps[productID] = append(l.gaps[productID], gap.Hours()/24)
+	l.last = at
+}
+
+// Intervals повертає інтервали товарів з достатньою кількістю спостережень
+func (l *RepurchaseLearner) Intervals() RepurchaseIntervals {
+	intervals := make(RepurchaseIntervals, len(l.gaps))
+	for productID, values := range l.gaps {
+		if len(values) < minRepurchaseObservations {
+			continue
+		}
+		intervals[productID] = median(values)
+	}
+
+	return intervals
+}
+
+func median(values []float64) float64 {
+	sort.Float64s(values)
+	mid := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[mid-1] + values[mid]) / 2
+	}
+	return values[mid]
+}
@@ -0,0 +1,169 @@
+// Package snapshot описує знімок навченої рекомендаційної моделі та його
+// серіалізацію у версіонований JSON-формат на локальному диску.
+//
+// Знімок містить артефакти, які дорого обчислювати на кожному екземплярі API:
+//   - матрицю подібності товарів (найближчі сусіди кожного товару)
+//   - вектори матричної факторизації товарів і користувачів
+//   - таблицю популярності товарів
+//   - асоціативні правила "купили A - купують B"
+//   - типові інтервали повторних покупок витратних товарів
+//
+// Модель навчається офлайн (див. Train), перевіряється і розгортається без
+// повторного навчання: сервер API завантажує знімок під час запуску.
+package snapshot
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FormatVersion поточна версія формату файлу знімка
+const FormatVersion = 1
+
+// Neighbor подібний товар та оцінка подібності
+type Neighbor struct {
+	ProductID uint    `json:"product_id"`
+	Score     float64 `json:"score"`
+}
+
+// AssociationRule асоціативне правило Antecedent => Consequent
+type AssociationRule struct {
+	Antecedent uint    `json:"antecedent"`
+	Consequent uint    `json:"consequent"`
+	Support    float64 `json:"support"`
+	Confidence float64 `json:"confidence"`
+	Lift       float64 `json:"lift"`
+}
+
+// Snapshot знімок навченої моделі
+type Snapshot struct {
+	FormatVersion    int                 `json:"format_version"`
+	ModelVersion     string              `json:"model_version"`
+	CreatedAt        time.Time           `json:"created_at"`
+	ItemSimilarity   map[uint][]Neighbor `json:"item_similarity"`
+	ItemFactors      map[uint][]float64  `json:"item_factors"`
+	UserFactors      map[uint][]float64  `json:"user_factors"`
+	Popularity       map[uint]float64    `json:"popularity"`
+	AssociationRules []AssociationRule   `json:"association_rules"`
+	// RepurchaseIntervals відсутні у знімках, створених до появи стратегії поповнення
+	RepurchaseIntervals RepurchaseIntervals `json:"repurchase_intervals,omitempty"`
+
+	rulesOnce  sync.Once
+	rulesIndex map[uint][]AssociationRule
+}
+
+// ErrUnsupportedVersion повертається для знімків несумісної версії формату
+var ErrUnsupportedVersion = errors.New("unsupported snapshot format version")
+
+// RulesFor повертає асоціативні правила з передумовою productID.
+// Індекс правил будується один раз при першому зверненні.
+func (s *Snapshot) RulesFor(productID uint) []AssociationRule {
+	s.rulesOnce.Do(func() {
+		s.rulesIndex = make(map[uint][]AssociationRule)
+		for _, rule := range s.AssociationRules {
+			s.rulesIndex[rule.Antecedent] = append(s.rulesIndex[rule.Antecedent], rule)
+		}
+	})
+	return s.rulesIndex[productID]
+}
+
+// Validate перевіряє цілісність знімка
+func (s *Snapshot) Validate() error {
+	if s.FormatVersion != FormatVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedVersion, s.FormatVersion)
+	}
+
+	dim := -1
+	for id, vec := range s.ItemFactors {
+		if dim == -1 {
+			dim = len(vec)
+		}
+		if len(vec) != dim {
+			return fmt.Errorf("item %d has %d factors, expected %d", id, len(vec), dim)
+		}
+	}
+	for id, vec := range s.UserFactors {
+		if dim != -1 && len(vec) != dim {
+			return fmt.Errorf("user %d has %d factors, expected %d", id, len(vec), dim)
+		}
+	}
+
+	return nil
+}
+
+// Save записує знімок у файл. Якщо шлях закінчується на ".gz", файл стискається.
+// Запис атомарний: дані пишуться у тимчасовий файл, який потім перейменовується.
+func Save(path string, s *Snapshot) error {
+	if s.FormatVersion == 0 {
+		s.FormatVersion = FormatVersion
+	}
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := encode(tmp, s, strings.HasSuffix(path, ".gz")); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load читає та перевіряє знімок з файлу
+func Load(path string) (*Snapshot, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	var s Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("decode snapshot: %w", err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func encode(w io.Writer, s *Snapshot, compress bool) error {
+	if !compress {
+		return json.NewEncoder(w).Encode(s)
+	}
+
+	gz := gzip.NewWriter(w)
+	if err := json.NewEncoder(gz).Encode(s); err != nil {
+		gz.Close()
+		return err
+	}
+	return gz.Close()
+}
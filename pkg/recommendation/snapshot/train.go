@@ -0,0 +1,315 @@
+package snapshot
+
+import (
+	"math"
+	"math/rand"
+	"product-recommendations-go/internal/models"
+	"sort"
+	"time"
+)
+
+// TrainConfig параметри навчання моделі
+type TrainConfig struct {
+	// ModelVersion мітка версії моделі, що зберігається у знімку
+	ModelVersion string
+	// Neighbors кількість подібних товарів, що зберігається для кожного товару
+	Neighbors int
+	// Factors розмірність векторів факторизації (0 - факторизація не виконується)
+	Factors int
+	// Epochs кількість епох стохастичного градієнтного спуску
+	Epochs int
+	// LearningRate крок градієнтного спуску
+	LearningRate float64
+	// Regularization коефіцієнт L2-регуляризації
+	Regularization float64
+	// MinSupport мінімальна підтримка асоціативного правила (частка замовлень)
+	MinSupport float64
+	// MinConfidence мінімальна достовірність асоціативного правила
+	MinConfidence float64
+	// Seed початкове значення генератора для відтворюваного навчання
+	Seed int64
+}
+
+// DefaultTrainConfig повертає типові параметри навчання
+func DefaultTrainConfig() TrainConfig {
+	return TrainConfig{
+		Neighbors:      20,
+		Factors:        16,
+		Epochs:         20,
+		LearningRate:   0.05,
+		Regularization: 0.01,
+		MinSupport:     0.001,
+		MinConfidence:  0.1,
+		Seed:           1,
+	}
+}
+
+// Вага взаємодій: покупка свідчить про сильніший інтерес, ніж лайк
+const (
+	likeWeight     = 1.0
+	purchaseWeight = 2.0
+)
+
+// Train навчає модель на лайках і замовленнях усіх користувачів
+func Train(likes []*models.UserLike, orders []*models.Order, cfg TrainConfig) *Snapshot {
+	interactions := buildInteractions(likes, orders)
+
+	s := &Snapshot{
+		FormatVersion:    FormatVersion,
+		ModelVersion:     cfg.ModelVersion,
+		CreatedAt:        time.Now().UTC(),
+		ItemSimilarity:   itemSimilarity(interactions, cfg.Neighbors),
+		Popularity:       popularity(likes, orders),
+		AssociationRules: associationRules(orders, cfg.MinSupport, cfg.MinConfidence),
+
+		RepurchaseIntervals: LearnRepurchaseIntervals(orders),
+	}
+
+	if cfg.Factors > 0 {
+		s.UserFactors, s.ItemFactors = factorize(interactions, cfg)
+	}
+
+	return s
+}
+
+// buildInteractions будує матрицю взаємодій користувач -> товар -> вага
+func buildInteractions(likes []*models.UserLike, orders []*models.Order) map[uint]map[uint]float64 {
+	interactions := make(map[uint]map[uint]float64)
+	add := func(userID, productID uint, weight float64) {
+		if interactions[userID] == nil {
+			interactions[userID] = make(map[uint]float64)
+		}
+		// Беремо максимальну вагу, щоб повторні покупки не домінували
+		if weight > interactions[userID][productID] {
+			interactions[userID][productID] = weight
+		}
+	}
+
+	for _, like := range likes {
+		add(like.UserID, like.ProductID, likeWeight)
+	}
+	for _, order := range orders {
+		for _, item := range order.Items {
+			add(order.UserID, item.ProductID, purchaseWeight)
+		}
+	}
+
+	return interactions
+}
+
+// itemSimilarity обчислює косинусну подібність товарів за векторами взаємодій
+// користувачів і зберігає для кожного товару topN найподібніших
+func itemSimilarity(interactions map[uint]map[uint]float64, topN int) map[uint][]Neighbor {
+	if topN <= 0 {
+		return map[uint][]Neighbor{}
+	}
+
+	norms := make(map[uint]float64)
+	dots := make(map[uint]map[uint]float64)
+
+	for _, items := range interactions {
+		ids := make([]uint, 0, len(items))
+		for id, w := range items {
+			ids = append(ids, id)
+			norms[id] += w * w
+		}
+		for i, a := range ids {
+			for _, b := range ids[i+1:] {
+				w := items[a] * items[b]
+				if dots[a] == nil {
+					dots[a] = make(map[uint]float64)
+				}
+				if dots[b] == nil {
+					dots[b] = make(map[uint]float64)
+				}
+				dots[a][b] += w
+				dots[b][a] += w
+			}
+		}
+	}
+
+	result := make(map[uint][]Neighbor, len(dots))
+	for a, row := range dots {
+		neighbors := make([]Neighbor, 0, len(row))
+		for b, dot := range row {
+			neighbors = append(neighbors, Neighbor{
+				ProductID: b,
+				Score:     dot / (math.Sqrt(norms[a]) * math.Sqrt(norms[b])),
+			})
+		}
+		sort.Slice(neighbors, func(i, j int) bool {
+			if neighbors[i].Score != neighbors[j].Score {
+				return neighbors[i].Score > neighbors[j].Score
+			}
+			return neighbors[i].ProductID < neighbors[j].ProductID
+		})
+		if len(neighbors) > topN {
+			neighbors = neighbors[:topN]
+		}
+		result[a] = neighbors
+	}
+
+	return result
+}
+
+// popularity рахує глобальну популярність: лайки плюс куплені одиниці товару
+func popularity(likes []*models.UserLike, orders []*models.Order) map[uint]float64 {
+	result := make(map[uint]float64)
+	for _, like := range likes {
+		result[like.ProductID] += likeWeight
+	}
+	for _, order := range orders {
+		for _, item := range order.Items {
+			result[item.ProductID] += purchaseWeight * float64(item.Quantity)
+		}
+	}
+	return result
+}
+
+// associationRules знаходить правила A => B для пар товарів, що купуються разом
+func associationRules(orders []*models.Order, minSupport, minConfidence float64) []AssociationRule {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	itemCounts := make(map[uint]int)
+	pairCounts := make(map[[2]uint]int)
+
+	for _, order := range orders {
+		basket := make(map[uint]bool, len(order.Items))
+		for _, item := range order.Items {
+			basket[item.ProductID] = true
+		}
+
+		ids := make([]uint, 0, len(basket))
+		for id := range basket {
+			ids = append(ids, id)
+			itemCounts[id]++
+		}
+		for i, a := range ids {
+			for _, b := range ids[i+1:] {
+				pairCounts[[2]uint{a, b}]++
+				pairCounts[[2]uint{b, a}]++
+			}
+		}
+	}
+
+	total := float64(len(orders))
+	var rules []AssociationRule
+	for pair, count := range pairCounts {
+		support := float64(count) / total
+		if support < minSupport {
+			continue
+		}
+		confidence := float64(count) / float64(itemCounts[pair[0]])
+		if confidence < minConfidence {
+			continue
+		}
+		rules = append(rules, AssociationRule{
+			Antecedent: pair[0],
+			Consequent: pair[1],
+			Support:    support,
+			Confidence: confidence,
+			Lift:       confidence / (float64(itemCounts[pair[1]]) / total),
+		})
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Antecedent != rules[j].Antecedent {
+			return rules[i].Antecedent < rules[j].Antecedent
+		}
+		if rules[i].Confidence != rules[j].Confidence {
+			return rules[i].Confidence > rules[j].Confidence
+		}
+		return rules[i].Consequent < rules[j].Consequent
+	})
+
+	return rules
+}
+
+// factorize навчає вектори користувачів і товарів стохастичним градієнтним спуском
+// на неявному зворотному зв'язку: спостережені взаємодії мають ціль 1, а для
+// кожної з них вибирається випадковий товар без взаємодії з ціллю 0
+func factorize(interactions map[uint]map[uint]float64, cfg TrainConfig) (map[uint][]float64, map[uint][]float64) {
+	type sample struct {
+		user, item uint
+	}
+
+	rng := rand.New(rand.NewSource(cfg.Seed))
+
+	userIDs := make([]uint, 0, len(interactions))
+	itemSet := make(map[uint]bool)
+	for userID, items := range interactions {
+		userIDs = append(userIDs, userID)
+		for itemID := range items {
+			itemSet[itemID] = true
+		}
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+
+	itemIDs := make([]uint, 0, len(itemSet))
+	for itemID := range itemSet {
+		itemIDs = append(itemIDs, itemID)
+	}
+	sort.Slice(itemIDs, func(i, j int) bool { return itemIDs[i] < itemIDs[j] })
+
+	if len(userIDs) == 0 || len(itemIDs) == 0 {
+		return map[uint][]float64{}, map[uint][]float64{}
+	}
+
+	initVector := func() []float64 {
+		vec := make([]float64, cfg.Factors)
+		for i := range vec {
+			vec[i] = rng.NormFloat64() * 0.1
+		}
+		return vec
+	}
+
+	userFactors := make(map[uint][]float64, len(userIDs))
+	for _, id := range userIDs {
+		userFactors[id] = initVector()
+	}
+	itemFactors := make(map[uint][]float64, len(itemIDs))
+	for _, id := range itemIDs {
+		itemFactors[id] = initVector()
+	}
+
+	var samples []sample
+	for _, userID := range userIDs {
+		items := make([]uint, 0, len(interactions[userID]))
+		for itemID := range interactions[userID] {
+			items = append(items, itemID)
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i] < items[j] })
+		for _, itemID := range items {
+			samples = append(samples, sample{userID, itemID})
+		}
+	}
+
+	update := func(u, v []float64, target float64) {
+		var prediction float64
+		for k := range u {
+			prediction += u[k] * v[k]
+		}
+		err := target - prediction
+		for k := range u {
+			uk, vk := u[k], v[k]
+			u[k] += cfg.LearningRate * (err*vk - cfg.Regularization*uk)
+			v[k] += cfg.LearningRate * (err*uk - cfg.Regularization*vk)
+		}
+	}
+
+	for epoch := 0; epoch < cfg.Epochs; epoch++ {
+		rng.Shuffle(len(samples), func(i, j int) { samples[i], samples[j] = samples[j], samples[i] })
+		for _, s := range samples {
+			update(userFactors[s.user], itemFactors[s.item], 1)
+
+			negative := itemIDs[rng.Intn(len(itemIDs))]
+			if _, seen := interactions[s.user][negative]; !seen {
+				update(userFactors[s.user], itemFactors[negative], 0)
+			}
+		}
+	}
+
+	return userFactors, itemFactors
+}
@@ -0,0 +1,146 @@
+// Package synthetic генерує синтетичні набори даних (каталог, лайки, замовлення)
+// для бенчмарків і профілювання рекомендаційних стратегій.
+//
+// Популярність товарів розподілена за законом Ціпфа: невелика кількість товарів
+// збирає більшість взаємодій, як і в реальному магазині. Генерація детермінована
+// для заданого Seed.
+package synthetic
+
+import (
+	"fmt"
+	"math/rand"
+	"product-recommendations-go/internal/models"
+	"time"
+)
+
+// Config параметри синтетичного набору даних
+type Config struct {
+	// Products кількість товарів у каталозі
+	Products int
+	// Users кількість користувачів
+	Users int
+	// Categories кількість категорій товарів
+	Categories int
+	// LikesPerUser середня кількість лайків на користувача
+	LikesPerUser int
+	// OrdersPerUser середня кількість замовлень на користувача
+	OrdersPerUser int
+	// ItemsPerOrder максимальна кількість позицій у замовленні
+	ItemsPerOrder int
+	// Skew параметр розподілу Ціпфа (> 1); більше значення - сильніша концентрація популярності
+	Skew float64
+	// Seed початкове значення генератора
+	Seed int64
+}
+
+// DefaultConfig повертає конфігурацію з products товарами та users користувачами
+func DefaultConfig(products, users int) Config {
+	return Config{
+		Products:      products,
+		Users:         users,
+		Categories:    20,
+		LikesPerUser:  8,
+		OrdersPerUser: 2,
+		ItemsPerOrder: 4,
+		Skew:          1.1,
+		Seed:          1,
+	}
+}
+
+// Dataset синтетичний набір даних
+type Dataset struct {
+	Products []*models.Product
+	Likes    []*models.UserLike
+	Orders   []*models.Order
+}
+
+// Generate створює набір даних за конфігурацією. ID товарів і користувачів
+// починаються з 1, товари впорядковані за ID.
+func Generate(cfg Config) *Dataset {
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	categories := cfg.Categories
+	if categories <= 0 {
+		categories = 1
+	}
+
+	products := make([]*models.Product, cfg.Products)
+	for i := range products {
+		id := uint(i + 1)
+		products[i] = &models.Product{
+			ID:        id,
+			Name:      fmt.Sprintf("Product %d", id),
+			Price:     float64(5+rng.Intn(995)) + 0.99,
+			Category:  fmt.Sprintf("category-%d", rng.Intn(categories)),
+			CreatedAt: base.Add(time.Duration(rng.Intn(365*24)) * time.Hour),
+		}
+	}
+
+	ds := &Dataset{Products: products}
+	if cfg.Products == 0 || cfg.Users == 0 {
+		return ds
+	}
+
+	// Ранг популярності не збігається з ID, щоб популярні товари були розкидані по каталогу
+	rank := rng.Perm(cfg.Products)
+	zipf := rand.NewZipf(rng, cfg.Skew, 1, uint64(cfg.Products-1))
+	pick := func() *models.Product {
+		return products[rank[zipf.Uint64()]]
+	}
+
+	ds.Likes = make([]*models.UserLike, 0, cfg.Users*cfg.LikesPerUser)
+	ds.Orders = make([]*models.Order, 0, cfg.Users*cfg.OrdersPerUser)
+
+	var likeID, orderID, itemID uint
+	for u := 1; u <= cfg.Users; u++ {
+		userID := uint(u)
+
+		// Унікальний індекс (user_id, product_id) не допускає повторних лайків
+		liked := make(map[uint]bool, cfg.LikesPerUser)
+		for n := rng.Intn(2*cfg.LikesPerUser + 1); n > 0; n-- {
+			product := pick()
+			if liked[product.ID] {
+				continue
+			}
+			liked[product.ID] = true
+			likeID++
+			ds.Likes = append(ds.Likes, &models.UserLike{
+				ID:        likeID,
+				UserID:    userID,
+				ProductID: product.ID,
+				CreatedAt: base.Add(time.Duration(rng.Intn(365*24)) * time.Hour),
+			})
+		}
+
+		for n := rng.Intn(2*cfg.OrdersPerUser + 1); n > 0; n-- {
+			orderID++
+			order := &models.Order{
+				ID:        orderID,
+				UserID:    userID,
+				Status:    models.OrderStatusDelivered,
+				CreatedAt: base.Add(time.Duration(rng.Intn(365*24)) * time.Hour),
+			}
+
+			items := 1 + rng.Intn(max(cfg.ItemsPerOrder, 1))
+			order.Items = make([]models.OrderItem, 0, items)
+			for i := 0; i < items; i++ {
+				product := pick()
+				quantity := 1 + rng.Intn(3)
+				itemID++
+				order.Items = append(order.Items, models.OrderItem{
+					ID:        itemID,
+					OrderID:   orderID,
+					ProductID: product.ID,
+					Quantity:  quantity,
+					Price:     product.Price,
+				})
+				order.Total += product.Price * float64(quantity)
+			}
+
+			ds.Orders = append(ds.Orders, order)
+		}
+	}
+
+	return ds
+}
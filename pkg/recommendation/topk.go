@@ -0,0 +1,77 @@
+package recommendation
+
+import (
+	"container/heap"
+	"product-recommendations-go/internal/models"
+	"sort"
+)
+
+// scoredProduct товар-кандидат з оцінкою
+type scoredProduct struct {
+	Product *models.Product
+	Score   float64
+}
+
+// better визначає порядок кандидатів: більша оцінка вище, при рівності - менший ID,
+// щоб результат не залежав від порядку обходу мап
+func better(a, b scoredProduct) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.Product.ID < b.Product.ID
+}
+
+// topK вибирає до k найкращих кандидатів у порядку спадання оцінки.
+// Використовує купу розміру k, тому працює за O(n log k) замість повного сортування.
+func topK(candidates []scoredProduct, k int) []scoredProduct {
+	if k <= 0 {
+		return nil
+	}
+	if len(candidates) <= k {
+		sort.Slice(candidates, func(i, j int) bool { return better(candidates[i], candidates[j]) })
+		return candidates
+	}
+
+	h := make(worstFirst, 0, k)
+	for _, c := range candidates {
+		if len(h) < k {
+			heap.Push(&h, c)
+			continue
+		}
+		if better(c, h[0]) {
+			h[0] = c
+			heap.Fix(&h, 0)
+		}
+	}
+
+	result := make([]scoredProduct, len(h))
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i] = heap.Pop(&h).(scoredProduct)
+	}
+	return result
+}
+
+// splitScored розділяє кандидатів на товари та оцінки
+func splitScored(candidates []scoredProduct) ([]*models.Product, []float64) {
+	products := make([]*models.Product, len(candidates))
+	scores := make([]float64, len(candidates))
+	for i, c := range candidates {
+		products[i] = c.Product
+		scores[i] = c.Score
+	}
+	return products, scores
+}
+
+// worstFirst купа, у вершині якої найгірший з відібраних кандидатів
+type worstFirst []scoredProduct
+
+func (h worstFirst) Len() int           { return len(h) }
+func (h worstFirst) Less(i, j int) bool { return better(h[j], h[i]) }
+func (h worstFirst) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *worstFirst) Push(x any)        { *h = append(*h, x.(scoredProduct)) }
+func (h *worstFirst) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
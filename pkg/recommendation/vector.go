@@ -0,0 +1,119 @@
+package recommendation
+
+import (
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/pkg/ann"
+)
+
+// VectorIndex індекс векторних представлень товарів (ембедингів), у якому
+// виконується пошук найближчих сусідів. Реалізується, зокрема, ann.HNSW.
+type VectorIndex interface {
+	// Search повертає до k найближчих до query товарів, для яких accept повертає true
+	Search(query []float64, k int, accept func(id uint) bool) []ann.Result
+	// Vector повертає вектор товару, якщо він присутній в індексі
+	Vector(id uint) ([]float64, bool)
+}
+
+// SimilarProducts повертає до limit товарів, схожих на productID, разом з оцінками.
+// Якщо вказаний opts.Index і товар має в ньому вектор, це найближчі товари у
+// векторному просторі (оцінка - 1 - відстань); інакше - товари тієї самої категорії,
+// впорядковані за популярністю моделі. Враховується opts.Filter; товари, відсутні
+// в allProducts (наприклад, видалені), пропускаються.
+func SimilarProducts(productID uint, allProducts []*models.Product, limit int, opts Options) ([]*models.Product, []float64) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	products := indexProducts(allProducts)
+	cf := newSeedFilter([]uint{productID}, opts.Filter)
+
+	if opts.Index != nil {
+		if query, ok := opts.Index.Vector(productID); ok {
+			results := opts.Index.Search(query, limit, func(id uint) bool {
+				product := products[id]
+				return product != nil && cf.accept(product)
+			})
+			if len(results) > 0 {
+				return resolveResults(results, products)
+			}
+		}
+	}
+
+	return getCategoryRecommendations([]uint{productID}, products, allProducts, limit, modelPopularity(opts.Model), cf)
+}
+
+// getVectorRecommendations формує персоналізовані рекомендації за векторним профілем
+// користувача - середнім векторів товарів, які він лайкнув або купив
+func getVectorRecommendations(idx *interactionIndex, limit int, index VectorIndex, cf *candidateFilter) ([]*models.Product, []float64) {
+	seeds := make([]uint, 0, len(idx.userLiked)+len(idx.userPurchased))
+	seeds = append(seeds, idx.userLiked...)
+	seeds = append(seeds, idx.userPurchased...)
+
+	// Жоден з товарів користувача не має вектора
+	profile := centroid(index, seeds)
+	if profile == nil {
+		return nil, nil
+	}
+
+	results := index.Search(profile, limit, func(id uint) bool {
+		product := idx.products[id]
+		return product != nil && cf.accept(product)
+	})
+
+	return resolveResults(results, idx.products)
+}
+
+// centroid повертає середнє векторів товарів productIDs (кожен товар враховується
+// один раз) або nil, якщо жоден з них не має вектора в індексі
+func centroid(index VectorIndex, productIDs []uint) []float64 {
+	seen := make(map[uint]bool, len(productIDs))
+	var profile []float64
+	var count int
+
+	for _, productID := range productIDs {
+		if seen[productID] {
+			continue
+		}
+		seen[productID] = true
+
+		vec, ok := index.Vector(productID)
+		if !ok {
+			continue
+		}
+		if profile == nil {
+			profile = make([]float64, len(vec))
+		}
+		for i, v := range vec {
+			profile[i] += v
+		}
+		count++
+	}
+
+	if count == 0 {
+		return nil
+	}
+	for i := range profile {
+		profile[i] /= float64(count)
+	}
+	return profile
+}
+
+// indexProducts будує мапу ID -> товар для пошуку за O(1)
+func indexProducts(allProducts []*models.Product) map[uint]*models.Product {
+	productIndex := make(map[uint]*models.Product, len(allProducts))
+	for _, product := range allProducts {
+		productIndex[product.ID] = product
+	}
+	return productIndex
+}
+
+// resolveResults перетворює результати пошуку в індексі на товари з оцінками
+func resolveResults(results []ann.Result, productIndex map[uint]*models.Product) ([]*models.Product, []float64) {
+	products := make([]*models.Product, 0, len(results))
+	scores := make([]float64, 0, len(results))
+	for _, r := range results {
+		products = append(products, productIndex[r.ID])
+		scores = append(scores, 1-r.Distance)
+	}
+	return products, scores
+}
@@ -0,0 +1,76 @@
+package recommendation
+
+import (
+	"reflect"
+	"testing"
+
+	"product-recommendations-go/internal/models"
+	"product-recommendations-go/pkg/ann"
+)
+
+func TestSimilarProducts(t *testing.T) {
+	// Категорії: 1, 4, 7 -> category-1; 2, 5 -> category-2; 3, 6 -> category-0.
+	// Товар 7 не має вектора в індексі.
+	catalog := append(testCatalog(6), &models.Product{ID: 7, Category: "category-1"})
+	vectors := map[uint][]float64{
+		1: {1, 0},
+		2: {0.9, 0.1},
+		3: {0.7, 0.7},
+		4: {0, 1},
+		5: {-1, 0},
+		6: {0.95, 0.05},
+	}
+	index, err := ann.Build(vectors, ann.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var noIndex VectorIndex
+
+	tests := []struct {
+		name  string
+		index VectorIndex
+		id    uint
+		opts  Options
+		want  []uint
+	}{
+		{
+			name:  "nearest vectors",
+			index: index,
+			id:    1,
+			want:  []uint{6, 2, 3},
+		},
+		{
+			name:  "filter applies to vector results",
+			index: index,
+			id:    1,
+			opts:  Options{Filter: models.RecommendationFilter{ExcludeIDs: []uint{6}}},
+			want:  []uint{2, 3, 4},
+		},
+		{
+			name:  "same category without index",
+			index: noIndex,
+			id:    1,
+			want:  []uint{4, 7},
+		},
+		{
+			name:  "same category when product has no vector",
+			index: index,
+			id:    7,
+			want:  []uint{1, 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.Index = tt.index
+			got, scores := SimilarProducts(tt.id, catalog, 3, opts)
+			if len(got) != len(scores) {
+				t.Fatalf("got %d products and %d scores", len(got), len(scores))
+			}
+			if ids := productIDs(got); !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("got %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
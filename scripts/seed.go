@@ -27,6 +27,7 @@ func main() {
 		{Email: "user1@example.com", Password: hashPassword("password1")},
 		{Email: "user2@example.com", Password: hashPassword("password2")},
 		{Email: "user3@example.com", Password: hashPassword("password3")},
+		{Email: "admin@example.com", Password: hashPassword("admin123"), Role: models.RoleAdmin},
 	}
 
 	for _, user := range users {
@@ -38,11 +39,14 @@ func main() {
 
 	for i := 1; i <= 50; i++ {
 		product := models.Product{
-			Name:        fmt.Sprintf("Product %d", i),
-			Description: fmt.Sprintf("Description for product %d", i),
-			Price:       float64(i*10) + 0.99,
-			Category:    categories[i%len(categories)],
-			ImageURL:    fmt.Sprintf("https://example.com/images/product%d.jpg", i),
+			Name:         fmt.Sprintf("Product %d", i),
+			Description:  fmt.Sprintf("Description for product %d", i),
+			Price:        float64(i*10) + 0.99,
+			Category:     categories[i%len(categories)],
+			ImageURL:     fmt.Sprintf("https://example.com/images/product%d.jpg", i),
+			IsConsumable: categories[i%len(categories)] == "Home",
+			// Кожен десятий товар відсутній на складі
+			Stock: (i % 10) * 5,
 		}
 		db.Create(&product)
 	}
@@ -80,7 +84,7 @@ func main() {
 	// Створення замовлень
 	order1 := models.Order{
 		UserID:    user1.ID,
-		Status:    "completed",
+		Status:    models.OrderStatusDelivered,
 		Total:     159.97,
 		CreatedAt: time.Now().Add(-24 * time.Hour),
 	}